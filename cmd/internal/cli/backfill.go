@@ -0,0 +1,118 @@
+package cli
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/cmd/internal/cli/internal/duration"
+	"metrics-scrapper/cmd/internal/cli/internal/timestamp"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/manager"
+)
+
+//go:embed data/backfill_desc.md
+var backfillCmdDesc string
+
+const (
+	fromFlag   = "from"
+	toFlag     = "to"
+	stepFlag   = "step"
+	windowFlag = "window"
+)
+
+func newBackfillCmd() *cobra.Command {
+	backfillCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:     "backfill",
+		Short:   "Compute and import historical rolling-window metrics",
+		Long:    backfillCmdDesc,
+		Args:    cobra.NoArgs,
+		PreRunE: requireScraping,
+	}
+
+	var (
+		from, to timestamp.Timestamp
+		step     = duration.New(7 * 24 * time.Hour)
+		window   = duration.New(30 * 24 * time.Hour)
+	)
+
+	backfillCmd.Flags().Var(&from, fromFlag, "first point, \"YYYY-MM-DD\" or \"YYYY-MM-DD HH:MM:SS\"")
+	backfillCmd.Flags().Var(&to, toFlag, "last point, \"YYYY-MM-DD\" or \"YYYY-MM-DD HH:MM:SS\". Defaults to now")
+	backfillCmd.Flags().Var(&step, stepFlag, "distance between points, e.g. 1d, 1w or 12h")
+	backfillCmd.Flags().Var(&window, windowFlag, "how far back from each point PRs are taken into account")
+	backfillCmd.Flags().Bool(dryRunFlag, false, "print metrics to stdout instead of pushing them to VictoriaMetrics")
+
+	backfillCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		opts := manager.BackfillOptions{
+			From:   from.Time(),
+			To:     to.Time(),
+			Step:   step.Duration(),
+			Window: window.Duration(),
+		}
+
+		if opts.From.IsZero() {
+			return fmt.Errorf("--%s is required", fromFlag)
+		}
+
+		if opts.To.IsZero() {
+			opts.To = time.Now()
+		}
+
+		if !opts.From.Before(opts.To) {
+			return fmt.Errorf("--%s must be before --%s", fromFlag, toFlag)
+		}
+
+		if opts.Step <= 0 || opts.Window <= 0 {
+			return fmt.Errorf("--%s and --%s must be positive", stepFlag, windowFlag)
+		}
+
+		// Points closer than the deduplication window would replace each
+		// other in VictoriaMetrics.
+		if align := time.Duration(cfg.Export.AlignMinutes) * time.Minute; opts.Step < align {
+			return fmt.Errorf("--%s must be at least export.align_minutes (%v)", stepFlag, align)
+		}
+
+		cmd.SilenceUsage = true
+
+		return backfill(cmd, opts)
+	}
+
+	return backfillCmd
+}
+
+// Entry point of BackfillCmd (i.e. `metrics-scraper backfill`).
+func backfill(cmd *cobra.Command, opts manager.BackfillOptions) error {
+	// A PR list cut at max_pages would silently leave the oldest PRs out of
+	// every point, so backfill always lists the whole history.
+	cfg.MaxPages = config.Unlimited
+	for i := range cfg.Repositories {
+		cfg.Repositories[i].MaxPages = config.Unlimited
+	}
+
+	client, err := github.NewService(cfg)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := cmd.Flags().GetBool(dryRunFlag)
+	if err != nil {
+		return err
+	}
+
+	exporter, err := newExporter(dryRun)
+	if err != nil {
+		return err
+	}
+
+	metricManager := manager.NewMetricManager(exporter, client)
+
+	report, err := metricManager.Backfill(cmd.Context(), cfg, opts)
+
+	report.Print(os.Stdout)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/manager"
+)
+
+//go:embed data/backtest_desc.md
+var backtestCmdDesc string
+
+const holdoutFlag = "holdout"
+
+func newBacktestCmd() *cobra.Command {
+	backtestCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:     "backtest",
+		Short:   "Compare the merge-time model with the 70/30 heuristic on recent PRs",
+		Long:    backtestCmdDesc,
+		Args:    cobra.NoArgs,
+		PreRunE: requireScraping,
+	}
+
+	var (
+		repoKey string
+		holdout float64
+	)
+
+	backtestCmd.Flags().StringVar(&repoKey, repoFlag, "", "repository as owner/repo (defaults to every configured one)")
+	backtestCmd.Flags().Float64Var(&holdout, holdoutFlag, 0.3, "share of the newest PRs held out for scoring")
+
+	backtestCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if holdout <= 0 || holdout >= 1 {
+			return fmt.Errorf("--%s must be between 0 and 1, got %g", holdoutFlag, holdout)
+		}
+
+		repos := cfg.Repositories
+
+		if repoKey != "" {
+			repo, err := selectRepository(repoKey)
+			if err != nil {
+				return err
+			}
+
+			repos = []config.RepoConfig{repo}
+		}
+
+		cmd.SilenceUsage = true
+
+		client, err := github.NewService(cfg)
+		if err != nil {
+			return err
+		}
+
+		metricManager := manager.NewMetricManager(nil, client)
+
+		for _, repo := range repos {
+			result, err := metricManager.Backtest(cmd.Context(), cfg, repo, holdout)
+			if err != nil {
+				return fmt.Errorf("%s: %w", repo.Key(), err)
+			}
+
+			result.Print(os.Stdout)
+		}
+
+		return nil
+	}
+
+	return backtestCmd
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/internal/httpcache"
+)
+
+//go:embed data/cache_desc.md
+var cacheCmdDesc string
+
+const pruneAllFlag = "all"
+
+func newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "cache",
+		Short: "Manage the GitHub response cache",
+		Long:  cacheCmdDesc,
+		Args:  cobra.NoArgs,
+	}
+
+	statsCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "stats",
+		Short: "Show cache size and entry count",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cacheStats()
+		},
+	}
+
+	pruneCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "prune",
+		Short: "Remove expired entries and shrink the cache to its size limit",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, err := cmd.Flags().GetBool(pruneAllFlag)
+			if err != nil {
+				return err
+			}
+
+			return cachePrune(all)
+		},
+	}
+
+	pruneCmd.Flags().Bool(pruneAllFlag, false, "remove every entry")
+
+	cacheCmd.AddCommand(statsCmd, pruneCmd)
+
+	return cacheCmd
+}
+
+func cacheStats() error {
+	cache, err := httpcache.FromConfig(cfg.Cache)
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Directory: %s\n", stats.Dir)
+	fmt.Printf("Enabled:   %t\n", cfg.Cache.Enabled)
+	fmt.Printf("Entries:   %d (%d expired)\n", stats.Entries, stats.Expired)
+	limit := "unlimited"
+	if stats.MaxSize > 0 {
+		limit = formatBytes(stats.MaxSize)
+	}
+
+	fmt.Printf("Size:      %s / %s\n", formatBytes(stats.TotalSize), limit)
+
+	if stats.Entries > 0 {
+		fmt.Printf("Oldest:    %s\n", stats.Oldest.Format(time.DateTime))
+		fmt.Printf("Newest:    %s\n", stats.Newest.Format(time.DateTime))
+	}
+
+	return nil
+}
+
+func cachePrune(all bool) error {
+	cache, err := httpcache.FromConfig(cfg.Cache)
+	if err != nil {
+		return err
+	}
+
+	result, err := cache.Prune(all)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d entries, freed %s\n", result.Removed, formatBytes(result.FreedBytes))
+
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
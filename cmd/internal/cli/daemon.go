@@ -0,0 +1,58 @@
+package cli
+
+import (
+	_ "embed"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/internal/daemon"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/manager"
+)
+
+//go:embed data/daemon_desc.md
+var daemonCmdDesc string
+
+func newDaemonCmd() *cobra.Command {
+	daemonCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:     "daemon",
+		Short:   "Scrape and push repositories on a schedule",
+		Long:    daemonCmdDesc,
+		Args:    cobra.NoArgs,
+		PreRunE: requireScraping,
+	}
+
+	daemonCmd.Flags().String(listenFlag, "", "address to listen on (defaults to daemon.listen from the config)")
+
+	daemonCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		listen, err := cmd.Flags().GetString(listenFlag)
+		if err != nil {
+			return err
+		}
+
+		if listen == "" {
+			listen = cfg.Daemon.Listen
+		}
+
+		exporter, err := newExporter(false)
+		if err != nil {
+			return err
+		}
+
+		client, err := github.NewService(cfg)
+		if err != nil {
+			return err
+		}
+
+		d, err := daemon.New(cfg, manager.NewMetricManager(exporter, client))
+		if err != nil {
+			return err
+		}
+
+		return d.Run(cmd.Context(), listen)
+	}
+
+	return daemonCmd
+}
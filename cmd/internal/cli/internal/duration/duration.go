@@ -0,0 +1,71 @@
+package duration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUninitialized = errors.New("duration uninitialized")
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Duration is a flag value accepting everything time.ParseDuration does plus
+// whole days and weeks, e.g. "30d" or "1w".
+type Duration struct{ value time.Duration }
+
+func New(d time.Duration) Duration {
+	return Duration{value: d}
+}
+
+func (d *Duration) Type() string {
+	return "duration"
+}
+
+func (d *Duration) Set(s string) error {
+	if d == nil {
+		return ErrUninitialized
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": week} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return fmt.Errorf("parsing duration %q: %w", s, err)
+			}
+
+			d.value = time.Duration(count) * unit
+
+			return nil
+		}
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("parsing duration: %w", err)
+	}
+
+	d.value = parsed
+
+	return nil
+}
+
+func (d *Duration) String() string {
+	switch {
+	case d.value != 0 && d.value%week == 0:
+		return fmt.Sprintf("%dw", d.value/week)
+	case d.value != 0 && d.value%day == 0:
+		return fmt.Sprintf("%dd", d.value/day)
+	}
+
+	return d.value.String()
+}
+
+func (d *Duration) Duration() time.Duration {
+	return d.value
+}
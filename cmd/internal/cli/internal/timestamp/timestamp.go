@@ -23,7 +23,11 @@ func (t *Timestamp) Set(s string) error {
 
 	parsed, err := time.Parse(Layout, s)
 	if err != nil {
-		return fmt.Errorf("parsing timestamp: %w", err)
+		// A bare date means midnight.
+		var dateErr error
+		if parsed, dateErr = time.Parse(time.DateOnly, s); dateErr != nil {
+			return fmt.Errorf("parsing timestamp: %w", err)
+		}
 	}
 
 	t.value = parsed
@@ -32,5 +36,14 @@ func (t *Timestamp) Set(s string) error {
 }
 
 func (t *Timestamp) String() string {
+	if t.value.IsZero() {
+		return ""
+	}
+
 	return t.value.Format(Layout)
 }
+
+// Time returns the parsed value, or the zero time if the flag was not set.
+func (t *Timestamp) Time() time.Time {
+	return t.value
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/manager"
+)
+
+//go:embed data/predict_desc.md
+var predictCmdDesc string
+
+const (
+	repoFlag    = "repo"
+	prFlag      = "pr"
+	retrainFlag = "retrain"
+)
+
+func newPredictCmd() *cobra.Command {
+	predictCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:     "predict",
+		Short:   "Predict the time to merge of a single PR",
+		Long:    predictCmdDesc,
+		Args:    cobra.NoArgs,
+		PreRunE: requireScraping,
+	}
+
+	var (
+		repoKey string
+		number  int
+		retrain bool
+	)
+
+	predictCmd.Flags().StringVar(&repoKey, repoFlag, "", "repository as owner/repo (defaults to the only configured one)")
+	predictCmd.Flags().IntVar(&number, prFlag, 0, "number of the PR")
+	predictCmd.Flags().BoolVar(&retrain, retrainFlag, false, "retrain the model on the current history before predicting")
+
+	predictCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if number <= 0 {
+			return fmt.Errorf("--%s is required", prFlag)
+		}
+
+		repo, err := selectRepository(repoKey)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		client, err := github.NewService(cfg)
+		if err != nil {
+			return err
+		}
+
+		// Predictions are printed, nothing is pushed.
+		metricManager := manager.NewMetricManager(nil, client)
+
+		return metricManager.Predict(cmd.Context(), cfg, repo, number, retrain)
+	}
+
+	return predictCmd
+}
+
+// selectRepository returns the settings of the owner/repo given with --repo,
+// or of the only configured repository when it is empty.
+func selectRepository(repoKey string) (config.RepoConfig, error) {
+	if repoKey == "" {
+		if len(cfg.Repositories) != 1 {
+			return config.RepoConfig{}, fmt.Errorf("--%s is required with %d configured repositories",
+				repoFlag, len(cfg.Repositories))
+		}
+
+		return cfg.Repositories[0], nil
+	}
+
+	owner, repo, ok := strings.Cut(repoKey, "/")
+	if !ok || owner == "" || repo == "" {
+		return config.RepoConfig{}, fmt.Errorf("--%s must be owner/repo, got %q", repoFlag, repoKey)
+	}
+
+	return cfg.Repository(owner, repo), nil
+}
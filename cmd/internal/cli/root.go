@@ -2,6 +2,7 @@ package cli
 
 import (
 	_ "embed"
+	"fmt"
 	"log/slog"
 
 	"github.com/spf13/cobra"
@@ -14,13 +15,90 @@ const AppTitle = "Metrics Scraper"
 //go:embed data/root_desc.md
 var rootCmdDesc string
 
+const (
+	configFlag = "config"
+	recordFlag = "record"
+	replayFlag = "replay"
+
+	vmModeFlag      = "vm-mode"
+	vmURLFlag       = "vm-url"
+	vmInsertURLFlag = "vm-insert-url"
+	vmSelectURLFlag = "vm-select-url"
+	vmTenantFlag    = "vm-tenant"
+	vmProtocolFlag  = "vm-protocol"
+	vmWriteURLFlag  = "vm-remote-write-url"
+)
+
 var (
 	logger *slog.Logger
 	cfg    *config.Config
 )
 
-func setupConfig(_ *cobra.Command, _ []string) error {
-	cfg = config.LoadConfig()
+func setupConfig(cmd *cobra.Command, _ []string) error {
+	configPath, err := cmd.Flags().GetString(configFlag)
+	if err != nil {
+		return fmt.Errorf("reading --%s flag: %w", configFlag, err)
+	}
+
+	cfg, err = config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if cfg.RecordDir, err = cmd.Flags().GetString(recordFlag); err != nil {
+		return fmt.Errorf("reading --%s flag: %w", recordFlag, err)
+	}
+
+	if cfg.ReplayDir, err = cmd.Flags().GetString(replayFlag); err != nil {
+		return fmt.Errorf("reading --%s flag: %w", replayFlag, err)
+	}
+
+	if err := applyVMFlags(cmd, &cfg.VictoriaMetrics); err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	return nil
+}
+
+// requireScraping is the PreRunE of the commands that scrape GitHub. The
+// others, such as cache and spool, only need the settings they use.
+func requireScraping(*cobra.Command, []string) error {
+	if err := cfg.ValidateScraping(); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	return nil
+}
+
+// applyVMFlags overrides the VictoriaMetrics settings with the flags set on
+// the command line.
+func applyVMFlags(cmd *cobra.Command, vm *config.VictoriaMetricsConfig) error {
+	flags := map[string]*string{
+		vmModeFlag:      &vm.Mode,
+		vmURLFlag:       &vm.URL,
+		vmInsertURLFlag: &vm.InsertURL,
+		vmSelectURLFlag: &vm.SelectURL,
+		vmTenantFlag:    &vm.Tenant,
+		vmProtocolFlag:  &vm.Protocol,
+		vmWriteURLFlag:  &vm.RemoteWriteURL,
+	}
+
+	for name, value := range flags {
+		if !cmd.Flags().Changed(name) {
+			continue
+		}
+
+		v, err := cmd.Flags().GetString(name)
+		if err != nil {
+			return fmt.Errorf("reading --%s flag: %w", name, err)
+		}
+
+		*value = v
+	}
 
 	return nil
 }
@@ -38,8 +116,68 @@ func NewRootCmd() (*cobra.Command, error) {
 		PersistentPreRunE: setupConfig,
 	}
 
+	rootCmd.PersistentFlags().String(
+		configFlag,
+		"",
+		"path to a YAML or JSON config file (defaults to $CONFIG_PATH)",
+	)
+	rootCmd.PersistentFlags().String(
+		recordFlag,
+		"",
+		"directory to record every GitHub request/response pair to",
+	)
+	rootCmd.PersistentFlags().String(
+		replayFlag,
+		"",
+		"directory with recorded GitHub responses to serve instead of the network",
+	)
+	rootCmd.MarkFlagsMutuallyExclusive(recordFlag, replayFlag)
+
+	rootCmd.PersistentFlags().String(
+		vmModeFlag,
+		"",
+		"VictoriaMetrics deployment: \"single\" (--vm-url) or \"cluster\" (--vm-insert-url, --vm-select-url, --vm-tenant)",
+	)
+	rootCmd.PersistentFlags().String(
+		vmURLFlag,
+		"",
+		"single-node VictoriaMetrics URL",
+	)
+	rootCmd.PersistentFlags().String(
+		vmInsertURLFlag,
+		"",
+		"vminsert URL of a VictoriaMetrics cluster",
+	)
+	rootCmd.PersistentFlags().String(
+		vmSelectURLFlag,
+		"",
+		"vmselect URL of a VictoriaMetrics cluster",
+	)
+	rootCmd.PersistentFlags().String(
+		vmTenantFlag,
+		"",
+		"VictoriaMetrics cluster tenant, accountID or accountID:projectID",
+	)
+	rootCmd.PersistentFlags().String(
+		vmProtocolFlag,
+		"",
+		"push protocol: \"import\" (VictoriaMetrics JSON lines) or \"remote_write\" (Prometheus remote-write)",
+	)
+	rootCmd.PersistentFlags().String(
+		vmWriteURLFlag,
+		"",
+		"remote-write URL, e.g. a Prometheus, Mimir or Thanos receive endpoint (defaults to /api/v1/write of --vm-url)",
+	)
+
 	rootCmd.AddCommand(
 		newRunCmd(),
+		newServeCmd(),
+		newDaemonCmd(),
+		newBackfillCmd(),
+		newPredictCmd(),
+		newBacktestCmd(),
+		newCacheCmd(),
+		newSpoolCmd(),
 	)
 
 	return rootCmd, nil
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetupConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  string
+		flag string
+		want string
+	}{
+		{name: "default", want: "http://ms-victoria-metrics:8428"},
+		{name: "config file", file: "http://file", want: "http://file"},
+		{name: "environment over the file", file: "http://file", env: "http://env", want: "http://env"},
+		{name: "flag over the environment", file: "http://file", env: "http://env", flag: "http://flag", want: "http://flag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_PATH", "")
+			t.Setenv("GITHUB_TOKEN", "")
+			t.Setenv("VM_URL", tt.env)
+
+			var args []string
+
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				contents := "repositories: [{owner: o, repo: a}]\nvictoria_metrics: {url: " + tt.file + "}\n"
+
+				if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				args = append(args, "--"+configFlag, path)
+			}
+
+			if tt.flag != "" {
+				args = append(args, "--"+vmURLFlag, tt.flag)
+			}
+
+			rootCmd, err := NewRootCmd()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := rootCmd.ParseFlags(args); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := setupConfig(rootCmd, nil); err != nil {
+				t.Fatalf("setupConfig: %v", err)
+			}
+
+			if got := cfg.VictoriaMetrics.URL; got != tt.want {
+				t.Errorf("VictoriaMetrics URL %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,65 +2,190 @@ package cli
 
 import (
 	_ "embed"
+	"errors"
 	manager "metrics-scrapper/internal/manager"
+	"metrics-scrapper/internal/spool"
 	"metrics-scrapper/internal/vmdb"
-	"net/http"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/exp/slog"
 
+	"metrics-scrapper/cmd/internal/cli/internal/timestamp"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
 	"metrics-scrapper/internal/github"
 )
 
 //go:embed data/run_desc.md
 var runCmdDesc string
 
-const scrapeThresholdFlag = "scrape-threshold"
+const (
+	scrapeThresholdFlag = "scrape-threshold"
+	dryRunFlag          = "dry-run"
+	failFastFlag        = "fail-fast"
+	reportFlag          = "report"
+)
 
 func newRunCmd() *cobra.Command {
 	runCmd := &cobra.Command{ //nolint:exhaustruct
-		Use:   "run",
-		Short: "Run metrics-scraper",
-		Long:  runCmdDesc,
-		Args:  cobra.NoArgs,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return run(cmd)
-		},
+		Use:     "run",
+		Short:   "Run metrics-scraper",
+		Long:    runCmdDesc,
+		Args:    cobra.NoArgs,
+		PreRunE: requireScraping,
 	}
 
-	// var timestampFlag timestamp.Timestamp
+	var timestampFlag timestamp.Timestamp
+
+	runCmd.PersistentFlags().Var(
+		&timestampFlag,
+		scrapeThresholdFlag,
+		"timestamp in format \"YYYY-MM-DD HH:MM:SS\". PRs updated earlier then this timestamp will not be scraped. "+
+			"Defaults to the last successful execution stored in VictoriaMetrics",
+	)
 
-	// runCmd.PersistentFlags().Var(
-	// 	&timestampFlag,
-	// 	scrapeThresholdFlag,
-	// 	"timestamp in format \"YYYY-MM-DD HH:MM:SS\". PRs updated earlier then this timestamp will not be scraped",
-	// )
+	runCmd.Flags().Bool(
+		dryRunFlag,
+		false,
+		"print metrics to stdout instead of pushing them to VictoriaMetrics. Combined with --replay runs fully offline",
+	)
+
+	runCmd.Flags().Bool(
+		failFastFlag,
+		false,
+		"stop at the first repository that fails. By default every repository is processed "+
+			"and the run fails after all of them if any failed",
+	)
+
+	runCmd.Flags().String(
+		reportFlag,
+		"",
+		"write the run summary as JSON to this file",
+	)
+
+	runCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// Failures past this point are reported in the run summary, the
+		// usage text would only bury it.
+		cmd.SilenceUsage = true
+
+		return run(cmd, timestampFlag.Time())
+	}
 
 	return runCmd
 }
 
 // Entry point of RunCmd (i.e. `metrics-scraper run`).
-func run(_ *cobra.Command) error {
-	var vmdbURLProvider vmdb.URLProvider
+func run(cmd *cobra.Command, scrapeThreshold time.Time) error {
+	client, err := github.NewService(cfg)
+	if err != nil {
+		return err
+	}
 
-	vmdbURLProvider = &vmdb.SingleNodeURL{
-		VMURL: "http://ms-victoria-metrics:8428",
+	dryRun, err := cmd.Flags().GetBool(dryRunFlag)
+	if err != nil {
+		return err
 	}
 
-	logger := slog.Default()
+	exporter, err := newExporter(dryRun)
+	if err != nil {
+		return err
+	}
+
+	failFast, err := cmd.Flags().GetBool(failFastFlag)
+	if err != nil {
+		return err
+	}
 
-	client := github.NewClient(cfg)
+	reportPath, err := cmd.Flags().GetString(reportFlag)
+	if err != nil {
+		return err
+	}
 
-	exporter := vmdb.NewVMDBExporter(
-		&http.Client{}, //nolint:exhaustruct
-		vmdbURLProvider,
-		logger,
-		"1y",
-	)
+	metricManager := manager.NewMetricManager(exporter, client)
+
+	report, err := metricManager.ScrapeAndPush(cmd.Context(), cfg, manager.RunOptions{
+		ScrapeThreshold: scrapeThreshold,
+		FailFast:        failFast,
+		SkipTotals:      dryRun,
+	})
 
-	manager := manager.NewMetricManager(exporter, client)
+	// Repositories are processed concurrently, so their analyses are printed
+	// once all of them are done.
+	for _, repo := range report.Repositories {
+		if result := repo.Result; result != nil {
+			analyzer.PrintAnalysisResults(result.Owner, result.Repo, result.Analysis, result.Metrics)
+		}
+	}
+
+	report.Print(os.Stdout)
 
-	err := manager.ScrapeAndPush(cfg)
+	if reportPath != "" {
+		if writeErr := report.WriteJSON(reportPath); writeErr != nil {
+			return errors.Join(err, writeErr)
+		}
+	}
 
 	return err
 }
+
+// newExporter returns the exporter for the configured VictoriaMetrics (or
+// remote-write) endpoint behind the spool, or one printing metrics to stdout
+// for dry runs.
+func newExporter(dryRun bool) (manager.VMDBExporter, error) {
+	if dryRun {
+		return vmdb.NewDryRunExporter(os.Stdout), nil
+	}
+
+	target, err := newTargetExporter()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Spool.Enabled {
+		return target, nil
+	}
+
+	return spool.FromConfig(target, cfg.Spool, cfg.VictoriaMetrics.Import.ChunkSizeMB<<20)
+}
+
+// newTargetExporter returns the exporter that sends metrics to the
+// configured endpoint directly.
+func newTargetExporter() (manager.VMDBExporter, error) {
+	logger := slog.Default()
+
+	vmClient, err := vmdb.NewHTTPClient(cfg.VictoriaMetrics)
+	if err != nil {
+		return nil, err
+	}
+
+	urlProvider := vmdb.NewURLProvider(cfg.VictoriaMetrics)
+
+	if cfg.VictoriaMetrics.Protocol == config.ProtocolRemoteWrite {
+		writeURL := cfg.VictoriaMetrics.RemoteWriteURL
+		if writeURL == "" {
+			writeURL = urlProvider.RemoteWrite()
+		}
+
+		return vmdb.NewRemoteWriteExporter(
+			vmClient,
+			urlProvider,
+			writeURL,
+			logger,
+			cfg.VictoriaMetrics.LastExecLookback,
+		), nil
+	}
+
+	return vmdb.NewVMDBExporter(
+		vmClient,
+		urlProvider,
+		logger,
+		cfg.VictoriaMetrics.LastExecLookback,
+		vmdb.ImportOptions{
+			ChunkSize:   cfg.VictoriaMetrics.Import.ChunkSizeMB << 20,
+			Gzip:        cfg.VictoriaMetrics.Import.Gzip,
+			Concurrency: cfg.VictoriaMetrics.Import.Concurrency,
+		},
+	), nil
+}
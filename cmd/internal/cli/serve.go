@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/internal/exposition"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/manager"
+	"metrics-scrapper/internal/vmdb"
+)
+
+//go:embed data/serve_desc.md
+var serveCmdDesc string
+
+const (
+	listenFlag          = "listen"
+	refreshIntervalFlag = "refresh-interval"
+)
+
+// shutdownTimeout bounds how long in-flight HTTP requests may take to finish
+// once the process is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+func newServeCmd() *cobra.Command {
+	serveCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:     "serve",
+		Short:   "Expose the latest analysis on a /metrics endpoint",
+		Long:    serveCmdDesc,
+		Args:    cobra.NoArgs,
+		PreRunE: requireScraping,
+	}
+
+	serveCmd.Flags().String(listenFlag, ":9101", "address to listen on")
+	serveCmd.Flags().Duration(refreshIntervalFlag, 15*time.Minute, "how often to refresh the analysis")
+
+	serveCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		listen, err := cmd.Flags().GetString(listenFlag)
+		if err != nil {
+			return err
+		}
+
+		interval, err := cmd.Flags().GetDuration(refreshIntervalFlag)
+		if err != nil {
+			return err
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("--%s must be positive, got %v", refreshIntervalFlag, interval)
+		}
+
+		return serve(cmd.Context(), listen, interval)
+	}
+
+	return serveCmd
+}
+
+// Entry point of ServeCmd (i.e. `metrics-scraper serve`).
+func serve(ctx context.Context, listen string, interval time.Duration) error {
+	client, err := github.NewService(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Every refresh fetches the configured window from scratch, since the
+	// discarding exporter never reports a previous execution, and adds it to
+	// the saved totals.
+	metricManager := manager.NewMetricManager(vmdb.NewDryRunExporter(io.Discard), client)
+	store := exposition.NewStore(cfg.Labels, cfg.Export.MergeWithinDays)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", store)
+
+	server := &http.Server{ //nolint:exhaustruct
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		fmt.Printf("Serving metrics on %s/metrics\n", listen)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	go refreshLoop(ctx, metricManager, store, interval)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func refreshLoop(ctx context.Context, metricManager *manager.MetricManager, store *exposition.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := metricManager.ScrapeAndPush(ctx, cfg, manager.RunOptions{}) //nolint:exhaustruct
+		if ctx.Err() != nil {
+			return
+		}
+
+		report.Print(os.Stdout)
+		store.Update(report)
+
+		if err != nil {
+			fmt.Printf("Refresh failed: %v\n", err)
+		}
+
+		fmt.Printf("Next refresh at %s\n", time.Now().Add(interval).Format(time.DateTime))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"metrics-scrapper/internal/spool"
+	"metrics-scrapper/internal/vmdb"
+)
+
+//go:embed data/spool_desc.md
+var spoolCmdDesc string
+
+func newSpoolCmd() *cobra.Command {
+	spoolCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "spool",
+		Short: "Manage the spool of undelivered metric pushes",
+		Long:  spoolCmdDesc,
+		Args:  cobra.NoArgs,
+	}
+
+	inspectCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "inspect",
+		Short: "List pending batches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return spoolInspect()
+		},
+	}
+
+	flushCmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "flush",
+		Short: "Deliver pending batches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			return spoolFlush(cmd.Context())
+		},
+	}
+
+	spoolCmd.AddCommand(inspectCmd, flushCmd)
+
+	return spoolCmd
+}
+
+func openSpool() (*spool.Spool, error) {
+	target, err := newTargetExporter()
+	if err != nil {
+		return nil, err
+	}
+
+	return spool.FromConfig(target, cfg.Spool, cfg.VictoriaMetrics.Import.ChunkSizeMB<<20)
+}
+
+func spoolInspect() error {
+	s, err := openSpool()
+	if err != nil {
+		return err
+	}
+
+	batches, err := s.Batches()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, batch := range batches {
+		total += batch.Size
+	}
+
+	limit := "unlimited"
+	if s.MaxSize() > 0 {
+		limit = formatBytes(s.MaxSize())
+	}
+
+	fmt.Printf("Directory: %s\n", s.Dir())
+	fmt.Printf("Enabled:   %t\n", cfg.Spool.Enabled)
+	fmt.Printf("Batches:   %d\n", len(batches))
+	fmt.Printf("Size:      %s / %s\n", formatBytes(total), limit)
+
+	if len(batches) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "BATCH\tCREATED\tSIZE\tSERIES\tSAMPLES")
+
+	for _, batch := range batches {
+		series, samples := "?", "?"
+
+		if data, err := os.ReadFile(batch.Path); err == nil {
+			if metrics, err := vmdb.ParseJSON(data); err == nil {
+				count := 0
+				for _, metric := range metrics.Data {
+					count += len(metric.Timestamps)
+				}
+
+				series, samples = fmt.Sprint(len(metrics.Data)), fmt.Sprint(count)
+			}
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			batch.Seq, batch.CreatedAt.Format(time.DateTime), formatBytes(batch.Size), series, samples)
+	}
+
+	return tw.Flush() //nolint:wrapcheck
+}
+
+func spoolFlush(ctx context.Context) error {
+	s, err := openSpool()
+	if err != nil {
+		return err
+	}
+
+	delivered, pending, err := s.Flush(ctx)
+
+	fmt.Printf("Delivered %d batches, %d pending\n", delivered, pending)
+
+	return err
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"metrics-scrapper/cmd/internal/cli"
 )
@@ -12,7 +16,10 @@ func main() {
 		log.Fatalf("failed to create root cmd: %s", err.Error())
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		log.Fatalf("failed to start: %s", err.Error())
 	}
 }
@@ -5,8 +5,12 @@ go 1.24.0
 toolchain go1.24.9
 
 require (
+	github.com/golang/snappy v1.0.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/cobra v1.10.1
 	golang.org/x/exp v0.0.0-20251023183803-a4bb9ffd2546
+	google.golang.org/protobuf v1.36.9
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
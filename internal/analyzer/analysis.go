@@ -4,79 +4,188 @@ import (
 	"time"
 )
 
-func AnalyzeData(metrics []PRMetrics) AnalysisResult {
-	result := AnalysisResult{
-		PRMetrics: metrics,
-		TotalPRs:  len(metrics),
+// Accumulator collects PR metrics into counters and sketches, so it takes the
+// same memory for ten PRs and for the full history of a large repository.
+// Accumulators of different repositories, time windows or runs merge into the
+// same totals as accumulating all their PRs at once.
+type Accumulator struct {
+	TotalPRs  int `json:"total_prs"`
+	MergedPRs int `json:"merged_prs"`
+	ClosedPRs int `json:"closed_prs"`
+
+	Lifetimes Sketch `json:"lifetimes"`
+	// Only reviewed PRs have a time to first review.
+	TimesToFirstReview Sketch `json:"times_to_first_review"`
+	// Lifetimes of merged PRs, used for the prediction.
+	MergedLifetimes Sketch `json:"merged_lifetimes"`
+	// Lifetimes of PRs closed without a merge and ages of open PRs, which
+	// together with MergedLifetimes make the MergeCurve.
+	ClosedLifetimes Sketch `json:"closed_lifetimes"`
+	OpenLifetimes   Sketch `json:"open_lifetimes"`
+
+	// Business accumulates the working-time durations of PRs that have them.
+	Business *Accumulator `json:"business,omitempty"`
+}
+
+func (a *Accumulator) Add(m PRMetrics) {
+	a.add(m, m.TotalLifetime, m.TimeToFirstReview, m.TimeToFirstReview > 0)
+
+	if m.Business != nil {
+		if a.Business == nil {
+			a.Business = &Accumulator{} //nolint:exhaustruct
+		}
+
+		// A review outside working hours takes no working time but still
+		// counts as a review.
+		a.Business.add(m, m.Business.TotalLifetime, m.Business.TimeToFirstReview, !m.FirstReviewTime.IsZero())
 	}
+}
 
-	var totalLifetime time.Duration
-	var totalTimeToFirstReview time.Duration
-	var lifetimes []time.Duration
-	var reviewTimes []time.Duration
+func (a *Accumulator) add(m PRMetrics, lifetime, timeToFirstReview time.Duration, reviewed bool) {
+	a.TotalPRs++
+
+	switch {
+	case m.IsMerged:
+		a.MergedPRs++
+		a.MergedLifetimes.Add(lifetime)
+	case m.State == "closed":
+		a.ClosedPRs++
+		a.ClosedLifetimes.Add(lifetime)
+	default:
+		a.OpenLifetimes.Add(lifetime)
+	}
 
-	mergedCount := 0
-	closedCount := 0
+	a.Lifetimes.Add(lifetime)
 
-	// Собираем данные только по смерженным PR для прогноза
-	var mergedLifetimes []time.Duration
+	if reviewed {
+		a.TimesToFirstReview.Add(timeToFirstReview)
+	}
+}
 
-	for _, m := range metrics {
-		if m.IsMerged {
-			mergedCount++
-			mergedLifetimes = append(mergedLifetimes, m.TotalLifetime)
-		} else if m.State == "closed" {
-			closedCount++
-		}
+func (a *Accumulator) Merge(other *Accumulator) {
+	a.TotalPRs += other.TotalPRs
+	a.MergedPRs += other.MergedPRs
+	a.ClosedPRs += other.ClosedPRs
 
-		totalLifetime += m.TotalLifetime
-		lifetimes = append(lifetimes, m.TotalLifetime)
+	a.Lifetimes.Merge(&other.Lifetimes)
+	a.TimesToFirstReview.Merge(&other.TimesToFirstReview)
+	a.MergedLifetimes.Merge(&other.MergedLifetimes)
+	a.ClosedLifetimes.Merge(&other.ClosedLifetimes)
+	a.OpenLifetimes.Merge(&other.OpenLifetimes)
 
-		if m.TimeToFirstReview > 0 {
-			totalTimeToFirstReview += m.TimeToFirstReview
-			reviewTimes = append(reviewTimes, m.TimeToFirstReview)
+	if other.Business != nil {
+		if a.Business == nil {
+			a.Business = &Accumulator{} //nolint:exhaustruct
 		}
+
+		a.Business.Merge(other.Business)
+	}
+}
+
+// Result derives the analysis from the accumulated values. The result shares
+// the sketches of a, so a must not be changed afterwards.
+func (a *Accumulator) Result() AnalysisResult {
+	result := AnalysisResult{ //nolint:exhaustruct
+		TotalPRs:           a.TotalPRs,
+		MergedPRs:          a.MergedPRs,
+		ClosedPRs:          a.ClosedPRs,
+		Lifetimes:          a.Lifetimes,
+		TimesToFirstReview: a.TimesToFirstReview,
+		MergedLifetimes:    a.MergedLifetimes,
+		ClosedLifetimes:    a.ClosedLifetimes,
+		OpenLifetimes:      a.OpenLifetimes,
+		MergeCurve:         newMergeCurve(&a.MergedLifetimes, &a.ClosedLifetimes, &a.OpenLifetimes),
 	}
 
-	result.MergedPRs = mergedCount
-	result.ClosedPRs = closedCount
+	result.MedianTimeToMerge = result.MergeCurve.MedianTimeToMerge()
+	result.LifetimePercentiles = a.Lifetimes.Percentiles()
+	result.TimeToFirstReviewPercentiles = a.TimesToFirstReview.Percentiles()
 
 	if result.TotalPRs > 0 {
 		result.MergeRate = float64(result.MergedPRs) / float64(result.TotalPRs) * 100
-		result.AverageLifetime = totalLifetime / time.Duration(result.TotalPRs)
+		result.AverageLifetime = a.Lifetimes.Mean()
 
-		if len(reviewTimes) > 0 {
-			result.AverageTimeToFirstReview = totalTimeToFirstReview / time.Duration(len(reviewTimes))
-			result.MedianTimeToFirstReview = calculateMedianDuration(reviewTimes)
+		if a.TimesToFirstReview.Count() > 0 {
+			result.AverageTimeToFirstReview = a.TimesToFirstReview.Mean()
+			result.MedianTimeToFirstReview = result.TimeToFirstReviewPercentiles.P50
 		}
 
-		if len(lifetimes) > 0 {
-			result.MedianLifetime = calculateMedianDuration(lifetimes)
-		}
+		result.MedianLifetime = result.LifetimePercentiles.P50
 
 		// Расчет прогнозного времени до мерджа
-		if len(mergedLifetimes) > 0 {
-			result.PredictedTimeToMerge = calculatePredictedMergeTime(mergedLifetimes, reviewTimes)
+		if a.MergedLifetimes.Count() > 0 {
+			result.HeuristicTimeToMerge = calculatePredictedMergeTime(&a.MergedLifetimes, &a.TimesToFirstReview)
 		}
 	}
 
+	if a.Business != nil {
+		business := a.Business.Result()
+		result.Business = &business
+	}
+
 	return result
 }
 
+// Accumulator returns the totals behind r, so that results computed
+// separately can be merged.
+func (r AnalysisResult) Accumulator() Accumulator {
+	totals := Accumulator{
+		TotalPRs:           r.TotalPRs,
+		MergedPRs:          r.MergedPRs,
+		ClosedPRs:          r.ClosedPRs,
+		Lifetimes:          r.Lifetimes,
+		TimesToFirstReview: r.TimesToFirstReview,
+		MergedLifetimes:    r.MergedLifetimes,
+		ClosedLifetimes:    r.ClosedLifetimes,
+		OpenLifetimes:      r.OpenLifetimes,
+		Business:           nil,
+	}
+
+	if r.Business != nil {
+		business := r.Business.Accumulator()
+		totals.Business = &business
+	}
+
+	return totals
+}
+
+// MergeResults returns the analysis of the PRs of all results together,
+// without going back to the PRs.
+func MergeResults(results ...AnalysisResult) AnalysisResult {
+	var merged Accumulator
+
+	for _, result := range results {
+		totals := result.Accumulator()
+		merged.Merge(&totals)
+	}
+
+	return merged.Result()
+}
+
+func AnalyzeData(metrics []PRMetrics) AnalysisResult {
+	var accumulator Accumulator
+
+	for _, m := range metrics {
+		accumulator.Add(m)
+	}
+
+	return accumulator.Result()
+}
+
 // calculatePredictedMergeTime рассчитывает прогнозное время до мерджа
 // Используем взвешенную формулу: 70% медианного времени + 30% времени до первого ревью
-func calculatePredictedMergeTime(mergedLifetimes, reviewTimes []time.Duration) time.Duration {
-	if len(mergedLifetimes) == 0 {
+func calculatePredictedMergeTime(mergedLifetimes, reviewTimes *Sketch) time.Duration {
+	if mergedLifetimes.Count() == 0 {
 		return 0
 	}
 
 	// Медиана времени жизни смерженных PR
-	medianMergeTime := calculateMedianDuration(mergedLifetimes)
+	medianMergeTime := mergedLifetimes.Quantile(0.5)
 
 	// Медиана времени до первого ревью (если есть данные)
 	var medianReviewTime time.Duration
-	if len(reviewTimes) > 0 {
-		medianReviewTime = calculateMedianDuration(reviewTimes)
+	if reviewTimes.Count() > 0 {
+		medianReviewTime = reviewTimes.Quantile(0.5)
 	} else {
 		// Если нет данных по ревью, используем 20% от общего времени как оценку
 		medianReviewTime = medianMergeTime / 5
@@ -88,26 +197,3 @@ func calculatePredictedMergeTime(mergedLifetimes, reviewTimes []time.Duration) t
 
 	return predicted
 }
-
-func calculateMedianDuration(durations []time.Duration) time.Duration {
-	if len(durations) == 0 {
-		return 0
-	}
-
-	sorted := make([]time.Duration, len(durations))
-	copy(sorted, durations)
-
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
-
-	mid := len(sorted) / 2
-	if len(sorted)%2 == 0 {
-		return (sorted[mid-1] + sorted[mid]) / 2
-	}
-	return sorted[mid]
-}
@@ -1,33 +1,128 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"metrics-scrapper/internal/github"
 	"sort"
+	"sync"
 	"time"
 )
 
-func CollectPRMetrics(client *github.Client, owner, repo string, prs []github.PullRequest) ([]PRMetrics, error) {
-	var metrics []PRMetrics
+// CollectPRMetrics fetches the details of prs with up to opts.Concurrency
+// requests in flight, further bounded by the shared opts.Limiter. Metrics are
+// returned in the order of prs; PRs whose details could not be fetched are
+// left out and reported in the returned PRErrors. The error is non-nil only
+// when ctx is cancelled.
+func CollectPRMetrics(
+	ctx context.Context,
+	client github.GitHubService,
+	owner, repo string,
+	prs []github.PullRequest,
+	opts CollectOptions,
+) ([]PRMetrics, []PRError, error) {
+	type outcome struct {
+		metrics PRMetrics
+		err     error
+	}
 
-	for i, pr := range prs {
-		fmt.Printf("PR processing #%d (%d/%d)\n", pr.Number, i+1, len(prs))
+	outcomes := make([]outcome, len(prs))
+	jobs := make(chan int)
 
-		prMetrics, err := collectMetricsForPR(client, owner, repo, pr)
-		if err != nil {
-			log.Printf("Error when getting metrics for PR #%d: %v", pr.Number, err)
+	var wg sync.WaitGroup
+
+	for range max(opts.Concurrency, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if err := opts.Limiter.acquire(ctx); err != nil {
+					outcomes[i].err = err
+					continue
+				}
+
+				pr := prs[i]
+				fmt.Printf("%s/%s: PR processing #%d (%d/%d)\n", owner, repo, pr.Number, i+1, len(prs))
+
+				outcomes[i].metrics, outcomes[i].err = collectMetricsForPR(ctx, client, owner, repo, pr, opts.Sizes)
+
+				opts.Limiter.release()
+			}
+		}()
+	}
+
+	for i := range prs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	var (
+		metrics []PRMetrics
+		errs    []PRError
+	)
+
+	for i, o := range outcomes {
+		if o.err != nil {
+			errs = append(errs, PRError{PRNumber: prs[i].Number, Err: o.err})
 			continue
 		}
 
-		metrics = append(metrics, prMetrics)
-		time.Sleep(500 * time.Millisecond) // delay between requests
+		metrics = append(metrics, o.metrics)
 	}
 
+	return metrics, errs, nil
+}
+
+func collectMetricsForPR(
+	ctx context.Context,
+	client github.GitHubService,
+	owner, repo string,
+	pr github.PullRequest,
+	sizes bool,
+) (PRMetrics, error) {
+	if sizer, ok := client.(github.PullRequestSizer); ok && sizes {
+		full, err := sizer.GetPullRequest(ctx, owner, repo, pr.Number)
+		if err != nil {
+			return PullRequestMetrics(owner, repo, pr), fmt.Errorf("size: %w", err)
+		}
+
+		pr = full
+	}
+
+	metrics := PullRequestMetrics(owner, repo, pr)
+
+	reviews, err := client.GetReviews(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return metrics, fmt.Errorf("reviews: %w", err)
+	}
+
+	comments, err := client.GetComments(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return metrics, fmt.Errorf("comments: %w", err)
+	}
+
+	processReviews(&metrics, reviews, pr.User.Login)
+
+	metrics.CommentsCount = len(comments)
+
 	return metrics, nil
 }
 
-func collectMetricsForPR(client *github.Client, owner, repo string, pr github.PullRequest) (PRMetrics, error) {
+// PullRequestMetrics returns the metrics known from the PR listing alone,
+// without its reviews and comments.
+func PullRequestMetrics(owner, repo string, pr github.PullRequest) PRMetrics {
 	metrics := PRMetrics{
 		Repository: fmt.Sprintf("%s/%s", owner, repo),
 
@@ -36,6 +131,10 @@ func collectMetricsForPR(client *github.Client, owner, repo string, pr github.Pu
 		State:     pr.State,
 		CreatedAt: pr.CreatedAt,
 		IsMerged:  pr.MergedAt != nil,
+
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		ChangedFiles: pr.ChangedFiles,
 	}
 
 	if pr.ClosedAt != nil {
@@ -45,23 +144,13 @@ func collectMetricsForPR(client *github.Client, owner, repo string, pr github.Pu
 		metrics.MergedAt = *pr.MergedAt
 	}
 
-	reviews, err := client.GetReviews(owner, repo, pr.Number)
-	if err != nil {
-		return metrics, fmt.Errorf("reviews: %v", err)
-	}
-
-	comments, err := client.GetComments(owner, repo, pr.Number)
-	if err != nil {
-		return metrics, fmt.Errorf("comments: %v", err)
+	for _, label := range pr.Labels {
+		metrics.Labels = append(metrics.Labels, label.Name)
 	}
 
-	processReviews(&metrics, reviews, pr.User.Login)
-
 	calculateLifetime(&metrics, pr)
 
-	metrics.CommentsCount = len(comments)
-
-	return metrics, nil
+	return metrics
 }
 
 func processReviews(metrics *PRMetrics, reviews []github.Review, author string) {
@@ -122,8 +211,12 @@ func ComparativeAnalysis(results map[string]RepositoryResult) ComparativeAnalyse
 	totalMerged := 0
 	var mergeRates []float64
 	var performances []RepoPerformance
+	var overall Accumulator
 
 	for repoKey, result := range results {
+		totals := result.Analysis.Accumulator()
+		overall.Merge(&totals)
+
 		totalPRs += result.Analysis.TotalPRs
 		totalMerged += result.Analysis.MergedPRs
 		mergeRates = append(mergeRates, result.Analysis.MergeRate)
@@ -140,6 +233,7 @@ func ComparativeAnalysis(results map[string]RepositoryResult) ComparativeAnalyse
 		return performances[i].MergeRate > performances[j].MergeRate
 	})
 
+	comparative.Summary.Overall = overall.Result()
 	comparative.Summary.TotalPRs = totalPRs
 	comparative.Summary.TotalMergedPRs = totalMerged
 	if len(results) > 0 {
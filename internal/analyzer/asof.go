@@ -0,0 +1,49 @@
+package analyzer
+
+import "time"
+
+// AsOf returns the PRs as they looked at the moment at, for rebuilding past
+// analyses from today's data. Only PRs that existed at that moment and were
+// still open at some point within the preceding window are kept. Merges,
+// closes and reviews that happened after at are undone, and PRs still open
+// at that moment get their lifetime censored at at.
+//
+// Comment counts and reviewer lists are not timestamped and are kept as is.
+func AsOf(metrics []PRMetrics, at time.Time, window time.Duration) []PRMetrics {
+	windowStart := at.Add(-window)
+
+	var snapshot []PRMetrics
+
+	for _, m := range metrics {
+		if m.CreatedAt.After(at) {
+			continue
+		}
+
+		closedAt := m.ClosedAt
+		if m.IsMerged {
+			closedAt = m.MergedAt
+		}
+
+		if !closedAt.IsZero() && !closedAt.After(windowStart) {
+			continue
+		}
+
+		if closedAt.IsZero() || closedAt.After(at) {
+			m.State = "open"
+			m.IsMerged = false
+			m.ClosedAt = time.Time{}
+			m.MergedAt = time.Time{}
+			m.TotalLifetime = at.Sub(m.CreatedAt)
+		}
+
+		if m.FirstReviewTime.After(at) {
+			m.FirstReviewTime = time.Time{}
+			m.TimeToFirstReview = 0
+			m.Reviewers = nil
+		}
+
+		snapshot = append(snapshot, m)
+	}
+
+	return snapshot
+}
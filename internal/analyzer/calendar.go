@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Calendar is a working-time model: durations measured with it count only the
+// working hours of working days in its time zone, so a PR opened on Friday
+// evening and reviewed on Monday morning waited minutes, not days.
+type Calendar struct {
+	location *time.Location
+	// start and end are the working hours in minutes since midnight.
+	start, end int
+	weekdays   [7]bool
+	holidays   map[date]bool
+}
+
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) date {
+	year, month, day := t.Date()
+	return date{year: year, month: month, day: day}
+}
+
+// NewCalendar returns a calendar working from start to end after midnight on
+// weekdays, except holidays. Only the dates of holidays matter.
+func NewCalendar(location *time.Location, start, end time.Duration, weekdays []time.Weekday, holidays []time.Time) *Calendar {
+	c := &Calendar{
+		location: location,
+		start:    int(start / time.Minute),
+		end:      int(end / time.Minute),
+		weekdays: [7]bool{},
+		holidays: make(map[date]bool, len(holidays)),
+	}
+
+	for _, weekday := range weekdays {
+		c.weekdays[weekday] = true
+	}
+
+	for _, holiday := range holidays {
+		c.holidays[dateOf(holiday)] = true
+	}
+
+	return c
+}
+
+// Between returns the working time from from to to.
+func (c *Calendar) Between(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+
+	from, to = from.In(c.location), to.In(c.location)
+
+	var total time.Duration
+
+	year, month, day := from.Date()
+
+	// Days are built from their date rather than by adding 24h, so working
+	// hours stay put across DST changes.
+	for i := 0; ; i++ {
+		midnight := time.Date(year, month, day+i, 0, 0, 0, 0, c.location)
+		if !midnight.Before(to) {
+			break
+		}
+
+		if !c.weekdays[midnight.Weekday()] || c.holidays[dateOf(midnight)] {
+			continue
+		}
+
+		y, m, d := midnight.Date()
+		start := time.Date(y, m, d, 0, c.start, 0, 0, c.location)
+		end := time.Date(y, m, d, 0, c.end, 0, 0, c.location)
+
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+
+	return total
+}
+
+// Apply sets the working-time durations of metrics from their timestamps.
+// Lifetimes end where TotalLifetime does, which for open PRs is the moment
+// they were collected or the AsOf point. A nil calendar leaves metrics as is.
+func (c *Calendar) Apply(metrics []PRMetrics) {
+	if c == nil {
+		return
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+
+		business := &BusinessTime{ //nolint:exhaustruct
+			TotalLifetime: c.Between(m.CreatedAt, m.CreatedAt.Add(m.TotalLifetime)),
+		}
+
+		if !m.FirstReviewTime.IsZero() {
+			business.TimeToFirstReview = c.Between(m.CreatedAt, m.FirstReviewTime)
+		}
+
+		m.Business = business
+	}
+}
+
+// LoadHolidays reads a holiday list: one YYYY-MM-DD date per line, optionally
+// followed by a description. Empty lines and lines starting with # are
+// skipped.
+func LoadHolidays(path string) ([]time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadingHolidays, err)
+	}
+	defer file.Close()
+
+	var holidays []time.Time
+
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		holiday, err := time.Parse(time.DateOnly, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s:%d: %w", ErrLoadingHolidays, path, line, err)
+		}
+
+		holidays = append(holidays, holiday)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadingHolidays, err)
+	}
+
+	return holidays, nil
+}
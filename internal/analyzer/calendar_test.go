@@ -0,0 +1,216 @@
+package analyzer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return location
+}
+
+func TestCalendarBetween(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	tokyo := loadLocation(t, "Asia/Tokyo")
+
+	workdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	everyDay := append([]time.Weekday{time.Sunday, time.Saturday}, workdays...)
+
+	// January 1, 2024 is a Monday.
+	newYear := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	office := NewCalendar(berlin, 9*time.Hour, 18*time.Hour, workdays, []time.Time{newYear})
+	allDay := NewCalendar(berlin, 0, 24*time.Hour, everyDay, nil)
+	japan := NewCalendar(tokyo, 9*time.Hour, 18*time.Hour, workdays, nil)
+
+	at := func(location *time.Location, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, location)
+	}
+
+	tests := []struct {
+		name     string
+		calendar *Calendar
+		from, to time.Time
+		want     time.Duration
+	}{
+		{
+			name:     "within working hours",
+			calendar: office,
+			from:     at(berlin, time.January, 2, 10, 0),
+			to:       at(berlin, time.January, 2, 12, 30),
+			want:     2*time.Hour + 30*time.Minute,
+		},
+		{
+			name:     "outside working hours on both ends",
+			calendar: office,
+			from:     at(berlin, time.January, 2, 7, 0),
+			to:       at(berlin, time.January, 2, 20, 0),
+			want:     9 * time.Hour,
+		},
+		{
+			name:     "evening to the next morning",
+			calendar: office,
+			from:     at(berlin, time.January, 2, 19, 0),
+			to:       at(berlin, time.January, 3, 8, 0),
+			want:     0,
+		},
+		{
+			name:     "friday evening to monday morning",
+			calendar: office,
+			from:     at(berlin, time.January, 5, 17, 0),
+			to:       at(berlin, time.January, 8, 10, 0),
+			want:     2 * time.Hour,
+		},
+		{
+			name:     "weekend only",
+			calendar: office,
+			from:     at(berlin, time.January, 6, 10, 0),
+			to:       at(berlin, time.January, 7, 17, 0),
+			want:     0,
+		},
+		{
+			name:     "whole week",
+			calendar: office,
+			from:     at(berlin, time.January, 8, 0, 0),
+			to:       at(berlin, time.January, 15, 0, 0),
+			want:     45 * time.Hour,
+		},
+		{
+			name:     "holiday is skipped",
+			calendar: office,
+			from:     time.Date(2023, time.December, 29, 17, 0, 0, 0, berlin),
+			to:       at(berlin, time.January, 2, 10, 0),
+			want:     2 * time.Hour,
+		},
+		{
+			name:     "holiday only",
+			calendar: office,
+			from:     at(berlin, time.January, 1, 9, 0),
+			to:       at(berlin, time.January, 1, 18, 0),
+			want:     0,
+		},
+		{
+			name:     "end before start",
+			calendar: office,
+			from:     at(berlin, time.January, 3, 12, 0),
+			to:       at(berlin, time.January, 2, 12, 0),
+			want:     0,
+		},
+		{
+			name:     "empty interval",
+			calendar: office,
+			from:     at(berlin, time.January, 2, 12, 0),
+			to:       at(berlin, time.January, 2, 12, 0),
+			want:     0,
+		},
+		{
+			name:     "times in another zone are converted",
+			calendar: office,
+			from:     at(time.UTC, time.January, 2, 7, 0),
+			to:       at(time.UTC, time.January, 2, 9, 0),
+			want:     time.Hour,
+		},
+		{
+			name:     "working day in the calendar zone, evening in UTC",
+			calendar: japan,
+			from:     at(time.UTC, time.January, 2, 0, 0),
+			to:       at(time.UTC, time.January, 2, 12, 0),
+			want:     9 * time.Hour,
+		},
+		{
+			name:     "calendar weekend is a UTC weekday",
+			calendar: japan,
+			// Friday 18:00 to Monday 9:00 in Tokyo.
+			from: at(time.UTC, time.January, 5, 9, 0),
+			to:   at(time.UTC, time.January, 8, 0, 0),
+			want: 0,
+		},
+		{
+			name:     "working hours stay put across the switch to summer time",
+			calendar: office,
+			from:     at(berlin, time.March, 25, 9, 0),
+			to:       at(berlin, time.April, 1, 9, 0),
+			want:     45 * time.Hour,
+		},
+		{
+			name:     "the day of the switch to summer time is an hour short",
+			calendar: allDay,
+			from:     at(berlin, time.March, 31, 0, 0),
+			to:       at(berlin, time.April, 1, 0, 0),
+			want:     23 * time.Hour,
+		},
+		{
+			name:     "the day of the switch back is an hour long",
+			calendar: allDay,
+			from:     at(berlin, time.October, 27, 0, 0),
+			to:       at(berlin, time.October, 28, 0, 0),
+			want:     25 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.calendar.Between(tt.from, tt.to); got != tt.want {
+				t.Errorf("Between(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadHolidays(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     []time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "dates with descriptions, comments and blank lines",
+			contents: "# 2024\n2024-01-01 New Year\n\n  2024-05-01\n# 2024-12-25\n",
+			want: []time.Time{
+				time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "empty file", contents: "", want: nil},
+		{name: "malformed date", contents: "2024-01-01\n01.05.2024\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "holidays.txt")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadHolidays(path)
+			if tt.wantErr {
+				if !errors.Is(err, ErrLoadingHolidays) {
+					t.Errorf("LoadHolidays: got %v, %v, want %v", got, err, ErrLoadingHolidays)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("LoadHolidays: %v", err)
+			}
+
+			if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+)
+
+// CollectOptions controls how CollectPRMetrics fetches PR details.
+type CollectOptions struct {
+	// Concurrency is the number of workers for a single repository.
+	Concurrency int
+	// Limiter bounds in-flight PRs across all repositories processed at
+	// the same time. Nil means no global bound.
+	Limiter Limiter
+	// Sizes fetches every PR on its own when the backend lists PRs without
+	// their size (see github.PullRequestSizer). It costs a request per PR
+	// and is only needed by the merge-time model.
+	Sizes bool
+}
+
+// Limiter is a counting semaphore shared between concurrent collections.
+type Limiter chan struct{}
+
+func NewLimiter(size int) Limiter {
+	return make(Limiter, size)
+}
+
+func (l Limiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l Limiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// PRError describes a PR whose details could not be fetched.
+type PRError struct {
+	PRNumber int
+	Err      error
+}
+
+func (e PRError) Error() string {
+	return fmt.Sprintf("PR #%d: %v", e.PRNumber, e.Err)
+}
+
+func (e PRError) Unwrap() error {
+	return e.Err
+}
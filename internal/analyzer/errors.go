@@ -0,0 +1,9 @@
+package analyzer
+
+import "errors"
+
+var (
+	ErrDecodingSketch     = errors.New("decoding sketch")
+	ErrIncompatibleSketch = errors.New("incompatible sketch")
+	ErrLoadingHolidays    = errors.New("loading holidays")
+)
@@ -18,6 +18,22 @@ type PRMetrics struct {
 	Reviewers         []string
 	CommentsCount     int
 	IsMerged          bool
+
+	// Size and labels of the PR, used by the merge-time model.
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	Labels       []string
+
+	// Business holds the durations in working time when the repository has a
+	// calendar, see Calendar.Apply.
+	Business *BusinessTime
+}
+
+// BusinessTime holds durations of a PR counted in working time only.
+type BusinessTime struct {
+	TotalLifetime     time.Duration
+	TimeToFirstReview time.Duration
 }
 
 type RepositoryResult struct {
@@ -37,8 +53,30 @@ type AnalysisResult struct {
 	AverageTimeToFirstReview time.Duration
 	MedianLifetime           time.Duration
 	MedianTimeToFirstReview  time.Duration
-	PredictedTimeToMerge     time.Duration
-	PRMetrics                []PRMetrics
+	// HeuristicTimeToMerge is the legacy 70/30 estimate, see
+	// calculatePredictedMergeTime. It ignores open PRs and is kept for
+	// existing dashboards; MedianTimeToMerge is the canonical estimate.
+	HeuristicTimeToMerge time.Duration
+
+	LifetimePercentiles          Percentiles
+	TimeToFirstReviewPercentiles Percentiles
+
+	// Sketches of the distributions behind the percentiles. Only reviewed PRs
+	// have a time to first review.
+	Lifetimes          Sketch
+	TimesToFirstReview Sketch
+	MergedLifetimes    Sketch
+	ClosedLifetimes    Sketch
+	OpenLifetimes      Sketch
+
+	// MergeCurve predicts merges from all PRs, open ones included, and
+	// MedianTimeToMerge is its median with the confidence interval.
+	MergeCurve        *MergeCurve `json:"-"`
+	MedianTimeToMerge DurationEstimate
+
+	// Business is the same analysis in working time, nil unless the PRs have
+	// working-time durations.
+	Business *AnalysisResult
 }
 
 type AuthorStats struct {
@@ -72,8 +110,10 @@ type SummaryStats struct {
 	TotalPRs          int
 	TotalMergedPRs    int
 	AvgMergeRate      float64
-	BestPerforming    []RepoPerformance
-	WorstPerforming   []RepoPerformance
+	// Overall is the analysis of the PRs of all repositories together.
+	Overall         AnalysisResult
+	BestPerforming  []RepoPerformance
+	WorstPerforming []RepoPerformance
 }
 
 type RepoPerformance struct {
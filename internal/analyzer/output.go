@@ -9,7 +9,7 @@ import (
 	"time"
 )
 
-func PrintAnalysisResults(owner, repo string, result AnalysisResult) {
+func PrintAnalysisResults(owner, repo string, result AnalysisResult, metrics []PRMetrics) {
 	fmt.Printf("\n--- Results for %s/%s ---\n", owner, repo)
 	fmt.Printf("Total number of PR: %d\n", result.TotalPRs)
 	fmt.Printf("Successfully merged: %d (%.1f%%)\n", result.MergedPRs, result.MergeRate)
@@ -19,11 +19,16 @@ func PrintAnalysisResults(owner, repo string, result AnalysisResult) {
 	fmt.Printf("Average time to the first response: %v\n", result.AverageTimeToFirstReview.Round(time.Hour))
 	fmt.Printf("Median time to the first response: %v\n", result.MedianTimeToFirstReview.Round(time.Hour))
 
+	if business := result.Business; business != nil {
+		fmt.Printf("Median PR lifetime in working hours: %v\n", business.MedianLifetime.Round(time.Minute))
+		fmt.Printf("Median time to the first response in working hours: %v\n", business.MedianTimeToFirstReview.Round(time.Minute))
+	}
+
 	authorStats := make(map[string]int)
 	reviewerStats := make(map[string]int)
 	authorReviewerPairs := make(map[string]map[string]int)
 
-	for _, m := range result.PRMetrics {
+	for _, m := range metrics {
 		authorStats[m.Author]++
 		for _, reviewer := range m.Reviewers {
 			reviewerStats[reviewer]++
@@ -71,9 +76,32 @@ func printAuthorReviewerPairs(pairs map[string]map[string]int) {
 	}
 }
 
+// predictionDays are the horizons of the merge probabilities printed.
+var predictionDays = []int{1, 7, 30}
+
 func printPredictions(result AnalysisResult) {
 	fmt.Printf("\n=== PROGNOSIS FOR THE NEW PR ===\n")
-	fmt.Printf("Expected time before merge: %v\n", result.MedianLifetime.Round(time.Hour*24))
+
+	median := result.MedianTimeToMerge
+	if median.Value == 0 {
+		fmt.Printf("Expected time before merge: not enough merged PRs\n")
+	} else {
+		upper := "beyond the observed history"
+		if median.Upper > 0 {
+			upper = median.Upper.Round(time.Hour).String()
+		}
+
+		fmt.Printf("Expected time before merge: %v (95%% CI %v - %s)\n",
+			median.Value.Round(time.Hour), median.Lower.Round(time.Hour), upper)
+	}
+
+	for _, days := range predictionDays {
+		p := result.MergeCurve.MergeProbability(time.Duration(days) * 24 * time.Hour)
+		fmt.Printf("Probability of a merge within %d days: %.1f%% (95%% CI %.1f%% - %.1f%%)\n",
+			days, p.Value*100, p.Lower*100, p.Upper*100)
+	}
+
+	fmt.Printf("Share of PRs that get merged: %.1f%%\n", result.MergeCurve.MergeShare()*100)
 	fmt.Printf("Expected time until the first response: %v\n", result.MedianTimeToFirstReview.Round(time.Hour))
 }
 
@@ -111,15 +139,17 @@ func SaveRawData(metrics []PRMetrics) error {
 }
 
 func PrintComparativeAnalysis(comparative ComparativeAnalyser) {
-	fmt.Printf("\n" + strings.Repeat("=", 60) + "\n")
+	fmt.Printf("\n%s\n", strings.Repeat("=", 60))
 	fmt.Printf("COMPARATIVE ANALYSIS OF REPOSITORIES\n")
-	fmt.Printf(strings.Repeat("=", 60) + "\n")
+	fmt.Printf("%s\n", strings.Repeat("=", 60))
 
 	fmt.Printf("\n📊 GENERAL STATISTICS:\n")
 	fmt.Printf("   Total repositoriesв: %d\n", comparative.Summary.TotalRepositories)
 	fmt.Printf("   Total PR: %d\n", comparative.Summary.TotalPRs)
 	fmt.Printf("   Everything is confused: %d\n", comparative.Summary.TotalMergedPRs)
 	fmt.Printf("   The average percentage of merge: %.1f%%\n", comparative.Summary.AvgMergeRate)
+	fmt.Printf("   Median PR lifetime: %v\n", comparative.Summary.Overall.MedianLifetime.Round(time.Hour))
+	fmt.Printf("   Median time to the first response: %v\n", comparative.Summary.Overall.MedianTimeToFirstReview.Round(time.Hour))
 
 	if len(comparative.Summary.BestPerforming) > 0 {
 		fmt.Printf("\n🏆 TOP-3 REPOSITORIES IN TERMS OF EFFECTIVENESS:\n")
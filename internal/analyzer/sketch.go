@@ -0,0 +1,267 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// SketchAccuracy is the relative error of the quantiles returned by a Sketch.
+const SketchAccuracy = 0.01
+
+// maxSketchBuckets bounds the size of a sketch. Nanosecond durations up to a
+// century need about 2100 buckets at 1% accuracy, so the limit is only hit by
+// pathological inputs; the lowest buckets are then collapsed together.
+const maxSketchBuckets = 2048
+
+var (
+	sketchGamma    = (1 + SketchAccuracy) / (1 - SketchAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+// Sketch is a DDSketch of durations: values are counted in logarithmically
+// sized buckets, so the memory it takes does not grow with the number of
+// values and any quantile is within SketchAccuracy of the exact one. Sketches
+// merge without loss, so the sketch of several repositories, time windows or
+// runs is the same as if all their values were added to one.
+//
+// The zero value is an empty sketch.
+type Sketch struct {
+	// counts maps bucket keys to the number of values in them. Bucket k holds
+	// the values in (gamma^(k-1), gamma^k] nanoseconds.
+	counts map[int]uint64
+	// zeros counts the values that are not positive.
+	zeros uint64
+	count uint64
+	// sum is in seconds: a time.Duration overflows at 292 years, which the
+	// summed lifetimes of a large repository reach.
+	sum float64
+	min time.Duration
+	max time.Duration
+}
+
+func (s *Sketch) Add(v time.Duration) {
+	s.observe(1, v.Seconds(), v, v)
+
+	if v <= 0 {
+		s.zeros++
+		return
+	}
+
+	if s.counts == nil {
+		s.counts = make(map[int]uint64)
+	}
+
+	s.counts[sketchKey(v)]++
+	s.collapse()
+}
+
+// Merge adds the values of other to s.
+func (s *Sketch) Merge(other *Sketch) {
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	s.observe(other.count, other.sum, other.min, other.max)
+	s.zeros += other.zeros
+
+	if len(other.counts) > 0 && s.counts == nil {
+		s.counts = make(map[int]uint64, len(other.counts))
+	}
+
+	for k, n := range other.counts {
+		s.counts[k] += n
+	}
+
+	s.collapse()
+}
+
+func (s *Sketch) observe(count uint64, sum float64, lo, hi time.Duration) {
+	if s.count == 0 || lo < s.min {
+		s.min = lo
+	}
+	if s.count == 0 || hi > s.max {
+		s.max = hi
+	}
+
+	s.count += count
+	s.sum += sum
+}
+
+// collapse merges the lowest buckets into one when the sketch grows past
+// maxSketchBuckets.
+func (s *Sketch) collapse() {
+	if len(s.counts) <= maxSketchBuckets {
+		return
+	}
+
+	keys := slices.Sorted(maps.Keys(s.counts))
+	excess := keys[:len(keys)-maxSketchBuckets]
+	into := keys[len(excess)]
+
+	for _, k := range excess {
+		s.counts[into] += s.counts[k]
+		delete(s.counts, k)
+	}
+}
+
+func (s *Sketch) Count() int {
+	return int(s.count)
+}
+
+// Sum returns the sum of the values in seconds. Unlike the quantiles it is
+// not approximated by the buckets.
+func (s *Sketch) Sum() float64 {
+	return s.sum
+}
+
+// Mean returns the average of the values, zero for an empty sketch.
+func (s *Sketch) Mean() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+
+	return time.Duration(s.sum / float64(s.count) * float64(time.Second))
+}
+
+func (s *Sketch) Min() time.Duration {
+	return s.min
+}
+
+func (s *Sketch) Max() time.Duration {
+	return s.max
+}
+
+// Quantile returns the q-quantile (0 <= q <= 1): the value at rank
+// q*(Count-1), so Quantile(0.5) is the median.
+func (s *Sketch) Quantile(q float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Round(q * float64(s.count-1)))
+
+	var seen uint64
+
+	for v, n := range s.Buckets() {
+		seen += n
+		if seen > rank {
+			return v
+		}
+	}
+
+	return s.max
+}
+
+// Buckets yields the non-empty buckets in ascending order as the value
+// representing the bucket and the number of values in it. Values that are not
+// positive are reported as a bucket of 0.
+func (s *Sketch) Buckets() iter.Seq2[time.Duration, uint64] {
+	return func(yield func(time.Duration, uint64) bool) {
+		if s.zeros > 0 && !yield(0, s.zeros) {
+			return
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(s.counts)) {
+			if !yield(s.bucketValue(k), s.counts[k]) {
+				return
+			}
+		}
+	}
+}
+
+// bucketValue returns the value within SketchAccuracy of every value of
+// bucket k, clamped to the observed range.
+func (s *Sketch) bucketValue(k int) time.Duration {
+	return min(max(sketchValue(k), s.min), s.max)
+}
+
+func sketchKey(v time.Duration) int {
+	return int(math.Ceil(math.Log(float64(v)) / sketchLogGamma))
+}
+
+func (s *Sketch) Percentiles() Percentiles {
+	return Percentiles{
+		P50: s.Quantile(0.5),
+		P75: s.Quantile(0.75),
+		P90: s.Quantile(0.9),
+		P95: s.Quantile(0.95),
+		P99: s.Quantile(0.99),
+	}
+}
+
+// sketchJSON is the stored form of a sketch, so that the Totals saved by one
+// run are merged into the next. Sketches saved before the sum was kept in
+// seconds have it in nanoseconds as LegacySum.
+type sketchJSON struct {
+	Accuracy  float64        `json:"accuracy"`
+	Count     uint64         `json:"count"`
+	Sum       float64        `json:"sum_seconds"`
+	LegacySum time.Duration  `json:"sum,omitempty"`
+	Min       time.Duration  `json:"min"`
+	Max       time.Duration  `json:"max"`
+	Zeros     uint64         `json:"zeros,omitempty"`
+	Buckets   map[int]uint64 `json:"buckets,omitempty"`
+}
+
+// MarshalJSON has a value receiver so that sketches held by value in results
+// are encoded too.
+func (s Sketch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sketchJSON{ //nolint:exhaustruct
+		Accuracy: SketchAccuracy,
+		Count:    s.count,
+		Sum:      s.sum,
+		Min:      s.min,
+		Max:      s.max,
+		Zeros:    s.zeros,
+		Buckets:  s.counts,
+	})
+}
+
+func (s *Sketch) UnmarshalJSON(data []byte) error {
+	var stored sketchJSON
+
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingSketch, err)
+	}
+
+	if stored.Accuracy != SketchAccuracy {
+		return fmt.Errorf("%w: accuracy %g, want %g", ErrIncompatibleSketch, stored.Accuracy, SketchAccuracy)
+	}
+
+	if stored.Sum == 0 {
+		stored.Sum = stored.LegacySum.Seconds()
+	}
+
+	*s = Sketch{
+		counts: stored.Buckets,
+		zeros:  stored.Zeros,
+		count:  stored.Count,
+		sum:    stored.Sum,
+		min:    stored.Min,
+		max:    stored.Max,
+	}
+
+	return nil
+}
+
+// Percentiles of a duration metric.
+type Percentiles struct {
+	P50 time.Duration
+	P75 time.Duration
+	P90 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// Quantiles lists the quantiles of Percentiles in field order.
+var Quantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+// Values returns the percentiles in the order of Quantiles.
+func (p Percentiles) Values() []time.Duration {
+	return []time.Duration{p.P50, p.P75, p.P90, p.P95, p.P99}
+}
@@ -0,0 +1,246 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// exactQuantile returns the value at rank q*(len-1) of sorted values, the
+// definition Sketch.Quantile approximates.
+func exactQuantile(sorted []time.Duration, q float64) time.Duration {
+	return sorted[int(math.Round(q*float64(len(sorted)-1)))]
+}
+
+func TestSketchQuantileAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	generate := func(n int, value func(i int) time.Duration) []time.Duration {
+		values := make([]time.Duration, n)
+		for i := range values {
+			values[i] = value(i)
+		}
+
+		return values
+	}
+
+	tests := []struct {
+		name   string
+		values []time.Duration
+	}{
+		{name: "single value", values: []time.Duration{3 * time.Hour}},
+		{name: "two values", values: []time.Duration{time.Minute, 3 * 24 * time.Hour}},
+		{name: "identical values", values: generate(100, func(int) time.Duration { return 90 * time.Minute })},
+		{
+			name:   "uniform minutes to a month",
+			values: generate(5000, func(int) time.Duration { return time.Minute + time.Duration(rng.Int64N(int64(30*24*time.Hour))) }),
+		},
+		{
+			name: "long tail",
+			values: generate(5000, func(int) time.Duration {
+				return time.Duration(math.Exp(rng.NormFloat64()*2+math.Log(float64(4*time.Hour)))) + time.Second
+			}),
+		},
+		{
+			name:   "nanoseconds to centuries",
+			values: generate(2000, func(i int) time.Duration { return time.Duration(math.Pow(1.02, float64(i))) }),
+		},
+	}
+
+	quantiles := []float64{0, 0.01, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 1}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sketch Sketch
+			for _, v := range tt.values {
+				sketch.Add(v)
+			}
+
+			sorted := slices.Sorted(slices.Values(tt.values))
+
+			for _, q := range quantiles {
+				want := exactQuantile(sorted, q)
+				got := sketch.Quantile(q)
+
+				if diff := math.Abs(float64(got-want)) / float64(want); diff > SketchAccuracy {
+					t.Errorf("Quantile(%g) = %v, want %v within %g, off by %.4f", q, got, want, SketchAccuracy, diff)
+				}
+			}
+
+			if sketch.Min() != sorted[0] || sketch.Max() != sorted[len(sorted)-1] {
+				t.Errorf("got min %v, max %v, want %v and %v", sketch.Min(), sketch.Max(), sorted[0], sorted[len(sorted)-1])
+			}
+
+			if sketch.Count() != len(tt.values) {
+				t.Errorf("got count %d, want %d", sketch.Count(), len(tt.values))
+			}
+		})
+	}
+}
+
+func TestSketchZeros(t *testing.T) {
+	var sketch Sketch
+
+	for _, v := range []time.Duration{0, -time.Second, 0, time.Hour} {
+		sketch.Add(v)
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{q: 0, want: 0},
+		{q: 0.5, want: 0},
+		{q: 1, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		got := sketch.Quantile(tt.q)
+
+		if diff := math.Abs(float64(got - tt.want)); diff > SketchAccuracy*float64(tt.want) {
+			t.Errorf("Quantile(%g) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	if sketch.Min() != -time.Second {
+		t.Errorf("got min %v, want %v", sketch.Min(), -time.Second)
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	values := make([]time.Duration, 3000)
+	for i := range values {
+		values[i] = time.Duration(rng.Int64N(int64(60 * 24 * time.Hour)))
+	}
+
+	tests := []struct {
+		name  string
+		parts []int
+	}{
+		{name: "into an empty sketch", parts: []int{0, 3000}},
+		{name: "empty sketch merged in", parts: []int{0, 0, 3000}},
+		{name: "halves", parts: []int{0, 1500, 3000}},
+		{name: "uneven parts", parts: []int{0, 1, 10, 2999, 3000}},
+	}
+
+	var whole Sketch
+	for _, v := range values {
+		whole.Add(v)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var merged Sketch
+
+			for i := 1; i < len(tt.parts); i++ {
+				var part Sketch
+				for _, v := range values[tt.parts[i-1]:tt.parts[i]] {
+					part.Add(v)
+				}
+
+				merged.Merge(&part)
+			}
+
+			merged.Merge(nil)
+
+			if merged.Count() != whole.Count() || !closeTo(merged.Sum(), whole.Sum()) ||
+				merged.Min() != whole.Min() || merged.Max() != whole.Max() {
+				t.Errorf("got count %d, sum %v, range [%v, %v], want %d, %v, [%v, %v]",
+					merged.Count(), merged.Sum(), merged.Min(), merged.Max(),
+					whole.Count(), whole.Sum(), whole.Min(), whole.Max())
+			}
+
+			if got, want := maps.Collect(merged.Buckets()), maps.Collect(whole.Buckets()); !maps.Equal(got, want) {
+				t.Errorf("merged buckets differ from the buckets of all values")
+			}
+		})
+	}
+}
+
+// closeTo reports whether a and b are equal up to floating-point rounding.
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-12*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func TestSketchSumPastDurationRange(t *testing.T) {
+	const month = 30 * 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		value time.Duration
+		// parts sketches of count values each are merged.
+		parts int
+		count int
+	}{
+		{name: "one sketch of long-lived PRs", value: month, parts: 1, count: 4000},
+		{name: "merged totals of long-lived PRs", value: month, parts: 40, count: 100},
+		{name: "merged sketches of two centuries each", value: 200 * 365 * 24 * time.Hour, parts: 3, count: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var merged Sketch
+
+			for range tt.parts {
+				var part Sketch
+				for range tt.count {
+					part.Add(tt.value)
+				}
+
+				merged.Merge(&part)
+			}
+
+			n := tt.parts * tt.count
+
+			if want := float64(n) * tt.value.Seconds(); !closeTo(merged.Sum(), want) {
+				t.Errorf("Sum() = %g seconds, want %g", merged.Sum(), want)
+			}
+
+			if mean := merged.Mean(); math.Abs(float64(mean-tt.value)) > float64(time.Microsecond) {
+				t.Errorf("Mean() = %v, want %v", mean, tt.value)
+			}
+		})
+	}
+}
+
+func TestSketchJSON(t *testing.T) {
+	var sketch Sketch
+	for _, v := range []time.Duration{0, time.Minute, time.Hour, 50 * time.Hour} {
+		sketch.Add(v)
+	}
+
+	data, err := json.Marshal(sketch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Sketch
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if got, want := maps.Collect(decoded.Buckets()), maps.Collect(sketch.Buckets()); !maps.Equal(got, want) ||
+		decoded.Count() != sketch.Count() || decoded.Sum() != sketch.Sum() {
+		t.Errorf("decoded sketch differs: %s", data)
+	}
+
+	var legacy Sketch
+	if err := json.Unmarshal([]byte(`{"accuracy": 0.01, "count": 2, "sum": 7200000000000, "min": 1, "max": 2}`), &legacy); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if legacy.Sum() != 7200 {
+		t.Errorf("sum saved in nanoseconds decoded as %g seconds, want 7200", legacy.Sum())
+	}
+
+	var other Sketch
+	if err := json.Unmarshal([]byte(`{"accuracy": 0.02, "count": 1}`), &other); err == nil {
+		t.Error("Unmarshal: want an error for another accuracy")
+	}
+}
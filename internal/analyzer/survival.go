@@ -0,0 +1,220 @@
+package analyzer
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// confidenceZ is the normal quantile of the 95% confidence intervals.
+const confidenceZ = 1.959964
+
+// incidenceTolerance absorbs the rounding errors of the incidence.
+const incidenceTolerance = 1e-12
+
+// MergeCurve is the probability of a PR being merged within a given time,
+// estimated over all PRs: merges are the event of interest, closes without a
+// merge are a competing outcome and open PRs are right-censored at their
+// current age. It is the Kaplan–Meier estimate generalised to competing
+// outcomes (Aalen–Johansen), so open PRs no longer bias predictions towards
+// the quick merges, and closed PRs are not taken for PRs that merge later.
+//
+// Times are those of the sketch buckets, within SketchAccuracy of the actual
+// lifetimes.
+type MergeCurve struct {
+	steps []curveStep
+}
+
+// curveStep holds the estimate right after one event time. The sums are the
+// running terms of the delta-method variance of the incidence.
+type curveStep struct {
+	at        time.Duration
+	incidence float64
+
+	sumA, sumAF, sumAF2, sumB, sumC, sumCF float64
+}
+
+// newMergeCurve builds the curve from the lifetimes of merged and closed PRs
+// and the ages of open ones.
+func newMergeCurve(merged, closed, open *Sketch) *MergeCurve {
+	// zeroKey holds values that are not positive, below every bucket.
+	const zeroKey = math.MinInt
+
+	// Numbers of merged, closed and open PRs by bucket.
+	const (
+		mergedPRs = iota
+		closedPRs
+		openPRs
+	)
+
+	// Event times are clamped to the observed range like sketch quantiles.
+	var observed Sketch
+
+	byKey := make(map[int]*[3]uint64)
+	add := func(k int, outcome int, n uint64) {
+		if byKey[k] == nil {
+			byKey[k] = &[3]uint64{}
+		}
+
+		byKey[k][outcome] += n
+	}
+
+	for outcome, s := range []*Sketch{mergedPRs: merged, closedPRs: closed, openPRs: open} {
+		if s.count > 0 {
+			observed.observe(s.count, s.sum, s.min, s.max)
+		}
+
+		if s.zeros > 0 {
+			add(zeroKey, outcome, s.zeros)
+		}
+
+		for k, n := range s.counts {
+			add(k, outcome, n)
+		}
+	}
+
+	curve := &MergeCurve{} //nolint:exhaustruct
+
+	atRisk := float64(merged.count + closed.count + open.count)
+	survival, incidence := 1.0, 0.0
+
+	var step curveStep
+
+	// Merges and closes at a time happen before the censoring at that time.
+	for _, k := range slices.Sorted(maps.Keys(byKey)) {
+		o := byKey[k]
+
+		if events := float64(o[mergedPRs] + o[closedPRs]); events > 0 {
+			n, d1 := atRisk, float64(o[mergedPRs])
+
+			incidence += survival * d1 / n
+
+			if n > events {
+				a := events / (n * (n - events))
+				step.sumA += a
+				step.sumAF += a * incidence
+				step.sumAF2 += a * incidence * incidence
+			}
+
+			c := survival * d1 / (n * n)
+			step.sumB += survival * survival * d1 * (n - d1) / (n * n * n)
+			step.sumC += c
+			step.sumCF += c * incidence
+
+			survival *= 1 - events/n
+
+			step.at = 0
+			if k != zeroKey {
+				step.at = observed.bucketValue(k)
+			}
+			step.incidence = incidence
+
+			curve.steps = append(curve.steps, step)
+		}
+
+		atRisk -= float64(o[mergedPRs] + o[closedPRs] + o[openPRs])
+	}
+
+	return curve
+}
+
+// stdErr returns the standard error of the incidence of step i.
+func (c *MergeCurve) stdErr(i int) float64 {
+	s := c.steps[i]
+	f := s.incidence
+
+	variance := f*f*s.sumA - 2*f*s.sumAF + s.sumAF2 + s.sumB - 2*f*s.sumC + 2*s.sumCF
+
+	return math.Sqrt(max(variance, 0))
+}
+
+// ProbabilityEstimate is an estimated probability with its 95% confidence
+// interval.
+type ProbabilityEstimate struct {
+	Value float64
+	Lower float64
+	Upper float64
+}
+
+// MergeProbability returns the probability that a PR is merged within d.
+func (c *MergeCurve) MergeProbability(d time.Duration) ProbabilityEstimate {
+	var p ProbabilityEstimate
+
+	if c == nil {
+		return p
+	}
+
+	i := -1
+	for i+1 < len(c.steps) && c.steps[i+1].at <= d {
+		i++
+	}
+
+	if i < 0 {
+		return p
+	}
+
+	p.Value = c.steps[i].incidence
+	margin := confidenceZ * c.stdErr(i)
+	p.Lower, p.Upper = max(p.Value-margin, 0), min(p.Value+margin, 1)
+
+	return p
+}
+
+// MergeShare returns the estimated share of PRs that are eventually merged,
+// as far as the observed times tell.
+func (c *MergeCurve) MergeShare() float64 {
+	if c == nil || len(c.steps) == 0 {
+		return 0
+	}
+
+	return c.steps[len(c.steps)-1].incidence
+}
+
+// DurationEstimate is an estimated duration with its 95% confidence interval.
+// Upper is 0 when the interval reaches beyond the observed times.
+type DurationEstimate struct {
+	Value time.Duration
+	Lower time.Duration
+	Upper time.Duration
+}
+
+// MedianTimeToMerge returns the time by which half of the PRs that get merged
+// are merged: the first time the incidence reaches half of MergeShare. The
+// confidence bounds are the times its interval first reaches that half. It
+// is zero without merges.
+func (c *MergeCurve) MedianTimeToMerge() DurationEstimate {
+	var median DurationEstimate
+
+	target := c.MergeShare() / 2
+	if target == 0 {
+		return median
+	}
+
+	// Incidences are running sums of fractions, so a step exactly at the
+	// target may fall short of it by rounding.
+	target -= incidenceTolerance
+
+	var foundLower, foundMedian, foundUpper bool
+
+	for i, s := range c.steps {
+		margin := confidenceZ * c.stdErr(i)
+
+		if !foundLower && s.incidence+margin >= target {
+			median.Lower, foundLower = s.at, true
+		}
+		if !foundMedian && s.incidence >= target {
+			median.Value, foundMedian = s.at, true
+		}
+		if !foundUpper && s.incidence-margin >= target {
+			median.Upper, foundUpper = s.at, true
+		}
+	}
+
+	return median
+}
+
+// sketchValue returns the representative value of bucket k.
+func sketchValue(k int) time.Duration {
+	return time.Duration(2 * math.Pow(sketchGamma, float64(k)) / (sketchGamma + 1))
+}
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// sketchOf returns the sketch of lifetimes given in hours.
+func sketchOf(hours ...float64) *Sketch {
+	var s Sketch
+	for _, h := range hours {
+		s.Add(time.Duration(h * float64(time.Hour)))
+	}
+
+	return &s
+}
+
+// justAfter is a bit past hours, beyond the sketch error.
+func justAfter(hours float64) time.Duration {
+	return time.Duration(hours * (1 + 2*SketchAccuracy) * float64(time.Hour))
+}
+
+func TestMergeCurve(t *testing.T) {
+	type point struct {
+		hours float64
+		want  float64
+	}
+
+	tests := []struct {
+		name                 string
+		merged, closed, open *Sketch
+		points               []point
+		share                float64
+		median               float64
+	}{
+		{
+			name:   "no PRs",
+			merged: sketchOf(), closed: sketchOf(), open: sketchOf(),
+			points: []point{{1, 0}},
+		},
+		{
+			name:   "all censored",
+			merged: sketchOf(), closed: sketchOf(), open: sketchOf(1, 2, 3),
+			points: []point{{0.5, 0}, {2, 0}, {10, 0}},
+		},
+		{
+			name:   "only closed",
+			merged: sketchOf(), closed: sketchOf(1, 2), open: sketchOf(3),
+			points: []point{{2, 0}, {10, 0}},
+		},
+		{
+			name:   "all merged is the empirical distribution",
+			merged: sketchOf(1, 2, 3, 4), closed: sketchOf(), open: sketchOf(),
+			points: []point{{0.5, 0}, {1, 0.25}, {2, 0.5}, {3.5, 0.75}, {4, 1}},
+			share:  1, median: 2,
+		},
+		{
+			name:   "open PR censored before the merges",
+			merged: sketchOf(2, 3), closed: sketchOf(), open: sketchOf(1),
+			points: []point{{1, 0}, {2, 0.5}, {3, 1}},
+			share:  1, median: 2,
+		},
+		{
+			name:   "open PR censored after the merges",
+			merged: sketchOf(1, 2), closed: sketchOf(), open: sketchOf(3),
+			points: []point{{1, 1.0 / 3}, {2, 2.0 / 3}, {5, 2.0 / 3}},
+			share:  2.0 / 3, median: 1,
+		},
+		{
+			name:   "closes compete with merges",
+			merged: sketchOf(1, 3), closed: sketchOf(2, 4), open: sketchOf(),
+			points: []point{{1, 0.25}, {2, 0.25}, {3, 0.5}, {4, 0.5}},
+			share:  0.5, median: 1,
+		},
+		{
+			// At 1h: 5 at risk, 2 merges and 1 close, then the open PR is
+			// censored; at 2h the last PR is merged.
+			name:   "ties of merges, closes and censoring",
+			merged: sketchOf(1, 1, 2), closed: sketchOf(1), open: sketchOf(1),
+			points: []point{{0.5, 0}, {1, 0.4}, {2, 0.8}},
+			share:  0.8, median: 1,
+		},
+		{
+			name:   "merged at creation",
+			merged: sketchOf(0, 0, 2), closed: sketchOf(), open: sketchOf(),
+			points: []point{{0, 2.0 / 3}, {2, 1}},
+			share:  1, median: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve := newMergeCurve(tt.merged, tt.closed, tt.open)
+
+			for _, p := range tt.points {
+				got := curve.MergeProbability(justAfter(p.hours))
+
+				if math.Abs(got.Value-p.want) > 1e-9 {
+					t.Errorf("MergeProbability(%gh) = %g, want %g", p.hours, got.Value, p.want)
+				}
+
+				if got.Lower > got.Value || got.Upper < got.Value || got.Lower < 0 || got.Upper > 1 {
+					t.Errorf("MergeProbability(%gh) = %g outside its interval [%g, %g]", p.hours, got.Value, got.Lower, got.Upper)
+				}
+			}
+
+			if share := curve.MergeShare(); math.Abs(share-tt.share) > 1e-9 {
+				t.Errorf("MergeShare() = %g, want %g", share, tt.share)
+			}
+
+			median := curve.MedianTimeToMerge()
+			want := time.Duration(tt.median * float64(time.Hour))
+
+			if math.Abs(float64(median.Value-want)) > SketchAccuracy*float64(want) {
+				t.Errorf("MedianTimeToMerge() = %v, want %v", median.Value, want)
+			}
+
+			if median.Lower > median.Value || (median.Upper != 0 && median.Upper < median.Value) {
+				t.Errorf("median %v outside its interval [%v, %v]", median.Value, median.Lower, median.Upper)
+			}
+		})
+	}
+}
+
+func TestNilMergeCurve(t *testing.T) {
+	var curve *MergeCurve
+
+	if p := curve.MergeProbability(time.Hour); p != (ProbabilityEstimate{}) {
+		t.Errorf("MergeProbability() = %v, want zero", p)
+	}
+
+	if share := curve.MergeShare(); share != 0 {
+		t.Errorf("MergeShare() = %g, want 0", share)
+	}
+
+	if median := curve.MedianTimeToMerge(); median != (DurationEstimate{}) {
+		t.Errorf("MedianTimeToMerge() = %v, want zero", median)
+	}
+}
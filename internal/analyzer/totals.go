@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Totals is the analysis of a repository accumulated over incremental runs,
+// each of which only sees the PRs updated since the previous one. A PR shows
+// up in every run it was updated in, so Totals remembers which PRs it has
+// counted:
+//
+//   - merged and closed PRs are added to Finished once, and their numbers
+//     are kept in FinishedPRs so later updates (a comment after the merge)
+//     don't count them again. A reopened PR keeps its first outcome;
+//   - open PRs are kept in Open until they are done, since their age keeps
+//     growing between runs.
+type Totals struct {
+	Finished Accumulator `json:"finished"`
+	// FinishedPRs are the numbers of the PRs in Finished, sorted.
+	FinishedPRs []int          `json:"finished_prs"`
+	Open        map[int]OpenPR `json:"open"`
+}
+
+// OpenPR is what Totals needs about a PR that is still open.
+type OpenPR struct {
+	CreatedAt       time.Time `json:"created_at"`
+	FirstReviewTime time.Time `json:"first_review_time,omitzero"`
+}
+
+// Add counts the PRs of a run. Working-time durations must already be set,
+// see Calendar.Apply.
+func (t *Totals) Add(metrics []PRMetrics) {
+	if t.Open == nil {
+		t.Open = make(map[int]OpenPR)
+	}
+
+	for _, m := range metrics {
+		i, counted := slices.BinarySearch(t.FinishedPRs, m.PRNumber)
+		if counted {
+			continue
+		}
+
+		if !m.IsMerged && m.State != "closed" {
+			t.Open[m.PRNumber] = OpenPR{CreatedAt: m.CreatedAt, FirstReviewTime: m.FirstReviewTime}
+			continue
+		}
+
+		t.Finished.Add(m)
+		t.FinishedPRs = slices.Insert(t.FinishedPRs, i, m.PRNumber)
+
+		delete(t.Open, m.PRNumber)
+	}
+}
+
+// Result returns the analysis of every PR counted so far, open PRs aged as of
+// now. calendar sets their working-time durations and may be nil.
+func (t *Totals) Result(now time.Time, calendar *Calendar) AnalysisResult {
+	var total Accumulator
+
+	total.Merge(&t.Finished)
+
+	open := make([]PRMetrics, 0, len(t.Open))
+
+	for _, number := range slices.Sorted(maps.Keys(t.Open)) {
+		pr := t.Open[number]
+
+		m := PRMetrics{ //nolint:exhaustruct
+			PRNumber:        number,
+			State:           "open",
+			CreatedAt:       pr.CreatedAt,
+			FirstReviewTime: pr.FirstReviewTime,
+			TotalLifetime:   now.Sub(pr.CreatedAt),
+		}
+
+		if !pr.FirstReviewTime.IsZero() {
+			m.TimeToFirstReview = pr.FirstReviewTime.Sub(pr.CreatedAt)
+		}
+
+		open = append(open, m)
+	}
+
+	calendar.Apply(open)
+
+	for _, m := range open {
+		total.Add(m)
+	}
+
+	return total.Result()
+}
@@ -0,0 +1,117 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTotalsAdd(t *testing.T) {
+	created := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := created.Add(10 * 24 * time.Hour)
+
+	pr := func(number int, state string, lifetime time.Duration) PRMetrics {
+		return PRMetrics{ //nolint:exhaustruct
+			PRNumber:      number,
+			State:         state,
+			IsMerged:      state == "merged",
+			CreatedAt:     created,
+			TotalLifetime: lifetime,
+		}
+	}
+
+	tests := []struct {
+		name string
+		runs [][]PRMetrics
+		// want is the total, merged, closed and open PRs.
+		want     [4]int
+		finished []int
+	}{
+		{
+			name: "single run",
+			runs: [][]PRMetrics{{pr(1, "merged", time.Hour), pr(2, "closed", time.Hour), pr(3, "open", 0)}},
+			want: [4]int{3, 1, 1, 1}, finished: []int{1, 2},
+		},
+		{
+			name: "finished PR updated again is counted once",
+			runs: [][]PRMetrics{{pr(1, "merged", time.Hour)}, {pr(1, "merged", time.Hour)}, {pr(1, "merged", time.Hour)}},
+			want: [4]int{1, 1, 0, 0}, finished: []int{1},
+		},
+		{
+			name: "open PR seen in several runs is counted once",
+			runs: [][]PRMetrics{{pr(1, "open", 0)}, {pr(1, "open", 0)}},
+			want: [4]int{1, 0, 0, 1}, finished: nil,
+		},
+		{
+			name: "open PR merged later moves to finished",
+			runs: [][]PRMetrics{{pr(1, "open", 0), pr(2, "open", 0)}, {pr(1, "merged", 2*time.Hour)}},
+			want: [4]int{2, 1, 0, 1}, finished: []int{1},
+		},
+		{
+			name: "reopened PR keeps its first outcome",
+			runs: [][]PRMetrics{{pr(1, "closed", time.Hour)}, {pr(1, "open", 0)}, {pr(1, "merged", 5*time.Hour)}},
+			want: [4]int{1, 0, 1, 0}, finished: []int{1},
+		},
+		{
+			name: "finished numbers stay sorted",
+			runs: [][]PRMetrics{{pr(5, "merged", time.Hour), pr(2, "merged", time.Hour)}, {pr(9, "closed", time.Hour), pr(1, "merged", time.Hour)}},
+			want: [4]int{4, 3, 1, 0}, finished: []int{1, 2, 5, 9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var totals Totals
+
+			for _, run := range tt.runs {
+				// Totals are saved between runs.
+				data, err := json.Marshal(&totals)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				totals = Totals{} //nolint:exhaustruct
+				if err := json.Unmarshal(data, &totals); err != nil {
+					t.Fatal(err)
+				}
+
+				totals.Add(run)
+			}
+
+			result := totals.Result(now, nil)
+
+			got := [4]int{result.TotalPRs, result.MergedPRs, result.ClosedPRs, len(totals.Open)}
+			if got != tt.want {
+				t.Errorf("got total, merged, closed, open %v, want %v", got, tt.want)
+			}
+
+			if !slices.Equal(totals.FinishedPRs, tt.finished) {
+				t.Errorf("got finished PRs %v, want %v", totals.FinishedPRs, tt.finished)
+			}
+		})
+	}
+}
+
+func TestTotalsResultAgesOpenPRs(t *testing.T) {
+	created := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var totals Totals
+	totals.Add([]PRMetrics{{PRNumber: 1, State: "open", CreatedAt: created}}) //nolint:exhaustruct
+
+	tests := []struct {
+		now  time.Time
+		want time.Duration
+	}{
+		{now: created.Add(time.Hour), want: time.Hour},
+		{now: created.Add(72 * time.Hour), want: 72 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		result := totals.Result(tt.now, nil)
+
+		if got := result.OpenLifetimes.Max(); got != tt.want {
+			t.Errorf("open PR aged %v at %v, want %v", got, tt.now, tt.want)
+		}
+	}
+}
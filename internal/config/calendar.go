@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Calendar defaults: nine to six, Monday to Friday, UTC.
+const (
+	defaultWorkStart = "09:00"
+	defaultWorkEnd   = "18:00"
+	workTimeLayout   = "15:04"
+)
+
+var (
+	// weekdayNames are indexed by time.Weekday.
+	weekdayNames    = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	defaultWeekdays = []string{"mon", "tue", "wed", "thu", "fri"}
+)
+
+func (c *Config) applyCalendarDefaults() {
+	for name, calendar := range c.Calendars {
+		if calendar.TimeZone == "" {
+			calendar.TimeZone = time.UTC.String()
+		}
+		if calendar.WorkStart == "" {
+			calendar.WorkStart = defaultWorkStart
+		}
+		if calendar.WorkEnd == "" {
+			calendar.WorkEnd = defaultWorkEnd
+		}
+		if len(calendar.Weekdays) == 0 {
+			calendar.Weekdays = slices.Clone(defaultWeekdays)
+		}
+
+		c.Calendars[name] = calendar
+	}
+}
+
+func (c CalendarConfig) Location() (*time.Location, error) {
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("time zone %q: %w", c.TimeZone, err)
+	}
+
+	return location, nil
+}
+
+// WorkingHours returns the start and the end of the working day as offsets
+// from midnight.
+func (c CalendarConfig) WorkingHours() (start, end time.Duration, err error) {
+	if start, err = parseWorkTime(c.WorkStart); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseWorkTime(c.WorkEnd); err != nil {
+		return 0, 0, err
+	}
+
+	if end <= start {
+		return 0, 0, fmt.Errorf("working day ends at %s before it starts at %s", c.WorkEnd, c.WorkStart)
+	}
+
+	return start, end, nil
+}
+
+func parseWorkTime(s string) (time.Duration, error) {
+	t, err := time.Parse(workTimeLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("time of day %q, want HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (c CalendarConfig) WorkingDays() ([]time.Weekday, error) {
+	weekdays := make([]time.Weekday, 0, len(c.Weekdays))
+
+	for _, name := range c.Weekdays {
+		i := slices.Index(weekdayNames, strings.ToLower(name))
+		if i < 0 {
+			return nil, fmt.Errorf("unknown weekday %q, want mon to sun", name)
+		}
+
+		weekdays = append(weekdays, time.Weekday(i))
+	}
+
+	return weekdays, nil
+}
+
+// HolidayDates returns the inline holidays. Those of HolidaysFile are read
+// when the calendar is built.
+func (c CalendarConfig) HolidayDates() ([]time.Time, error) {
+	holidays := make([]time.Time, 0, len(c.Holidays))
+
+	for _, s := range c.Holidays {
+		holiday, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return nil, fmt.Errorf("holiday %q, want YYYY-MM-DD", s)
+		}
+
+		holidays = append(holidays, holiday)
+	}
+
+	return holidays, nil
+}
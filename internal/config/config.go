@@ -3,53 +3,554 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+)
+
+// Config sources are merged in the following order, later sources win:
+//
+//  1. built-in defaults, with the repositories of defaultRepositories when
+//     there is no config file;
+//  2. the config file (--config flag, or CONFIG_PATH when the flag is not set);
+//  3. environment variables (GITHUB_TOKEN, GITHUB_API_URL, GITHUB_BACKEND,
+//     MAX_PAGES, MAX_REVIEW_PAGES, MAX_COMMENT_PAGES, DELAY_MS, PER_PAGE,
+//     CONCURRENCY, MAX_CONCURRENCY, REPO_CONCURRENCY, CACHE_DIR, SPOOL_DIR,
+//     TOTALS_DIR, MODEL_DIR, VM_MODE, VM_URL, VM_INSERT_URL, VM_SELECT_URL,
+//     VM_TENANT, VM_PROTOCOL, VM_REMOTE_WRITE_URL, VM_USERNAME, VM_PASSWORD,
+//     VM_BEARER_TOKEN);
+//  4. command line flags (--vm-mode, --vm-url, --vm-insert-url,
+//     --vm-select-url, --vm-tenant, --vm-protocol, --vm-remote-write-url,
+//     --record, --replay).
+//
+// Per-repository overrides from the config file always take precedence over
+// the resulting global values.
+const configPathEnv = "CONFIG_PATH"
+
+const (
+	defaultVMURL        = "http://ms-victoria-metrics:8428"
+	defaultGitHubAPIURL = "https://api.github.com"
+)
+
+// defaultRepositories are scraped when no config file is given, as they were
+// before config files existed. A config file replaces them.
+func defaultRepositories() []RepoConfig {
+	return []RepoConfig{
+		{Owner: "stmcginnis", Repo: "gofish"},               //nolint:exhaustruct
+		{Owner: "golang", Repo: "go"},                       //nolint:exhaustruct
+		{Owner: "ipmitool", Repo: "ipmitool"},               //nolint:exhaustruct
+		{Owner: "docker", Repo: "compose"},                  //nolint:exhaustruct
+		{Owner: "VictoriaMetrics", Repo: "VictoriaMetrics"}, //nolint:exhaustruct
+		{Owner: "prometheus", Repo: "prometheus"},           //nolint:exhaustruct
+	}
+}
+
+// Unlimited as a page limit fetches the whole history.
+const Unlimited = -1
+
+// VictoriaMetrics deployment modes.
+const (
+	VMModeSingle  = "single"
+	VMModeCluster = "cluster"
+)
+
+// Protocols used to push metrics.
+const (
+	ProtocolImport      = "import"
+	ProtocolRemoteWrite = "remote_write"
+)
+
+// GitHub API backends.
+const (
+	BackendREST    = "rest"
+	BackendGraphQL = "graphql"
 )
 
 type RepoConfig struct {
-	Owner string
-	Repo  string
+	Owner string `json:"owner" yaml:"owner"`
+	Repo  string `json:"repo"  yaml:"repo"`
+
+	// Zero values mean "inherit the global setting". DelayMS is the minimal
+	// pause between two GitHub requests; the actual pace adapts to the
+	// remaining rate limit budget.
+	MaxPages        int `json:"max_pages"         yaml:"max_pages"`
+	MaxReviewPages  int `json:"max_review_pages"  yaml:"max_review_pages"`
+	MaxCommentPages int `json:"max_comment_pages" yaml:"max_comment_pages"`
+	PerPage         int `json:"per_page"          yaml:"per_page"`
+	DelayMS         int `json:"delay_ms"          yaml:"delay_ms"`
+	Concurrency     int `json:"concurrency"       yaml:"concurrency"`
+
+	// Schedule overrides the daemon schedule for this repository.
+	Schedule string `json:"schedule" yaml:"schedule"`
+
+	// Labels are added to every series of the repository on top of the
+	// global ones. An empty value removes a global label.
+	Labels map[string]string `json:"labels" yaml:"labels"`
+
+	// Calendar names the working calendar of the repository, overriding the
+	// global one. CalendarNone turns working-time metrics off.
+	Calendar string `json:"calendar" yaml:"calendar"`
+}
+
+func (r RepoConfig) Key() string {
+	return r.Owner + "/" + r.Repo
+}
+
+// VictoriaMetricsConfig describes where metrics are pushed and how the last
+// execution timestamp is looked up.
+type VictoriaMetricsConfig struct {
+	// Mode is VMModeSingle for a single-node server at URL or VMModeCluster
+	// for vminsert/vmselect at InsertURL/SelectURL. Tenant is the cluster
+	// tenant, "accountID" or "accountID:projectID".
+	Mode      string `json:"mode"       yaml:"mode"`
+	URL       string `json:"url"        yaml:"url"`
+	InsertURL string `json:"insert_url" yaml:"insert_url"`
+	SelectURL string `json:"select_url" yaml:"select_url"`
+	Tenant    string `json:"tenant"     yaml:"tenant"`
+
+	// Protocol is ProtocolImport for the VictoriaMetrics JSON lines import
+	// or ProtocolRemoteWrite for Prometheus remote-write, sent to
+	// RemoteWriteURL or, when empty, to the /api/v1/write path of the
+	// configured deployment. The query API is used in both cases.
+	Protocol       string `json:"protocol"         yaml:"protocol"`
+	RemoteWriteURL string `json:"remote_write_url" yaml:"remote_write_url"`
+
+	// Basic auth and bearer token are mutually exclusive.
+	Username    string `json:"username"     yaml:"username"`
+	Password    string `json:"password"     yaml:"password"`
+	BearerToken string `json:"bearer_token" yaml:"bearer_token"`
+
+	TLS       TLSConfig `json:"tls"        yaml:"tls"`
+	TimeoutMS int       `json:"timeout_ms" yaml:"timeout_ms"`
+
+	// LastExecLookback is the PromQL range searched for the last execution
+	// timestamp, e.g. "1y" or "30d".
+	LastExecLookback string `json:"last_exec_lookback" yaml:"last_exec_lookback"`
+
+	// Import controls requests of ProtocolImport.
+	Import ImportConfig `json:"import" yaml:"import"`
+
+	// Verify reads pushed samples back through /api/v1/export of the query
+	// side and fails the push if any of them is missing or differs. Only
+	// available with ProtocolImport.
+	Verify bool `json:"verify" yaml:"verify"`
+}
+
+// ImportConfig splits a push into requests of at most ChunkSizeMB of JSON
+// lines, compressed with gzip when Gzip is set, and sends up to Concurrency of
+// them at once.
+type ImportConfig struct {
+	ChunkSizeMB int  `json:"chunk_size_mb" yaml:"chunk_size_mb"`
+	Gzip        bool `json:"gzip"          yaml:"gzip"`
+	Concurrency int  `json:"concurrency"   yaml:"concurrency"`
+}
+
+// ExportConfig selects what ScrapeAndPush pushes. Aggregates are the four
+// per-repository gauges, PerPR the per-PR samples timestamped at merge/close
+// time. PRLabels is the subset of PRLabelNames attached to per-PR samples;
+// dropping labels lowers the number of series.
+//
+// Histograms selects how the distributions of PR lifetime and time to first
+// review are exported: HistogramVMRange buckets for VictoriaMetrics,
+// Prometheus HistogramLE buckets or HistogramNone.
+//
+// MergeWithinDays are the horizons of the exported probabilities that a PR
+// is merged within that many days.
+//
+// AlignMinutes is the length of the deduplication window, which must match
+// -dedup.minScrapeInterval of VictoriaMetrics. Aggregates are timestamped at
+// the start of the run, and of the runs within one window VictoriaMetrics
+// keeps the samples of the latest, so a rerun replaces the earlier values.
+// Zero makes every run a window of its own.
+type ExportConfig struct {
+	Aggregates      bool     `json:"aggregates"        yaml:"aggregates"`
+	PerPR           bool     `json:"per_pr"            yaml:"per_pr"`
+	PRLabels        []string `json:"pr_labels"         yaml:"pr_labels"`
+	Histograms      string   `json:"histograms"        yaml:"histograms"`
+	MergeWithinDays []int    `json:"merge_within_days" yaml:"merge_within_days"`
+	AlignMinutes    int      `json:"align_minutes"     yaml:"align_minutes"`
+}
+
+// Histogram kinds.
+const (
+	HistogramVMRange = "vmrange"
+	HistogramLE      = "le"
+	HistogramNone    = "none"
+)
+
+// Labels that can be attached to per-PR samples.
+const (
+	PRLabelNumber = "pr"
+	PRLabelAuthor = "author"
+	PRLabelState  = "state"
+)
+
+// PRLabelNames lists every label ExportConfig.PRLabels may contain.
+var PRLabelNames = []string{PRLabelNumber, PRLabelAuthor, PRLabelState}
+
+// RepoLabel is the label holding owner/repo.
+const RepoLabel = "repo"
+
+// ReservedLabelNames are set by the scraper itself and can't be configured
+// as extra labels.
+var ReservedLabelNames = append([]string{RepoLabel, "quantile", "days", HistogramLE, HistogramVMRange}, PRLabelNames...)
+
+// CalendarConfig is a working calendar. Durations measured against it count
+// only the time from WorkStart to WorkEnd ("15:04") on Weekdays ("mon" to
+// "sun") in TimeZone, skipping Holidays and the dates listed in HolidaysFile
+// (see analyzer.LoadHolidays). Dates are YYYY-MM-DD.
+type CalendarConfig struct {
+	TimeZone     string   `json:"time_zone"     yaml:"time_zone"`
+	WorkStart    string   `json:"work_start"    yaml:"work_start"`
+	WorkEnd      string   `json:"work_end"      yaml:"work_end"`
+	Weekdays     []string `json:"weekdays"      yaml:"weekdays"`
+	Holidays     []string `json:"holidays"      yaml:"holidays"`
+	HolidaysFile string   `json:"holidays_file" yaml:"holidays_file"`
+}
+
+// CalendarNone as a repository calendar disables working-time metrics.
+const CalendarNone = "none"
+
+// DaemonConfig controls the daemon command. Schedule is a standard 5-field
+// cron expression or a descriptor such as "@hourly" or "@every 30m".
+type DaemonConfig struct {
+	Schedule          string `json:"schedule"            yaml:"schedule"`
+	Listen            string `json:"listen"              yaml:"listen"`
+	StateFile         string `json:"state_file"          yaml:"state_file"`
+	ShutdownTimeoutMS int    `json:"shutdown_timeout_ms" yaml:"shutdown_timeout_ms"`
+}
+
+// TLSConfig configures HTTPS connections. CertFile and KeyFile enable client
+// certificate authentication.
+type TLSConfig struct {
+	CAFile             string `json:"ca_file"              yaml:"ca_file"`
+	CertFile           string `json:"cert_file"            yaml:"cert_file"`
+	KeyFile            string `json:"key_file"             yaml:"key_file"`
+	ServerName         string `json:"server_name"          yaml:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
+// RetryConfig controls how transient GitHub failures (network errors and the
+// listed status codes) are retried with exponential backoff.
+type RetryConfig struct {
+	MaxAttempts          int     `json:"max_attempts"           yaml:"max_attempts"`
+	BaseDelayMS          int     `json:"base_delay_ms"          yaml:"base_delay_ms"`
+	MaxDelayMS           int     `json:"max_delay_ms"           yaml:"max_delay_ms"`
+	Jitter               float64 `json:"jitter"                 yaml:"jitter"`
+	RetryableStatusCodes []int   `json:"retryable_status_codes" yaml:"retryable_status_codes"`
+}
+
+// CacheConfig controls the on-disk cache of GitHub responses.
+type CacheConfig struct {
+	Enabled   bool   `json:"enabled"     yaml:"enabled"`
+	Dir       string `json:"dir"         yaml:"dir"`
+	MaxSizeMB int    `json:"max_size_mb" yaml:"max_size_mb"`
+	TTLHours  int    `json:"ttl_hours"   yaml:"ttl_hours"`
+}
+
+// SpoolConfig controls the on-disk spool metric pushes go through. Batches
+// are written to Dir before delivery and delivered in order; a batch that
+// can't be delivered after MaxAttempts stays in the spool for the next push
+// or `spool flush`. New batches are rejected once the spool holds MaxSizeMB.
+type SpoolConfig struct {
+	Enabled     bool   `json:"enabled"       yaml:"enabled"`
+	Dir         string `json:"dir"           yaml:"dir"`
+	MaxSizeMB   int    `json:"max_size_mb"   yaml:"max_size_mb"`
+	MaxAttempts int    `json:"max_attempts"  yaml:"max_attempts"`
+	BaseDelayMS int    `json:"base_delay_ms" yaml:"base_delay_ms"`
+	MaxDelayMS  int    `json:"max_delay_ms"  yaml:"max_delay_ms"`
+}
+
+// TotalsConfig controls the cumulative analysis of incremental runs. Each run
+// only fetches the PRs updated since the previous one; with totals enabled,
+// they are added to the totals of the repository saved in Dir and the
+// aggregates cover every PR seen so far. Disabled, the aggregates describe the
+// PRs of the run alone.
+type TotalsConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Dir     string `json:"dir"     yaml:"dir"`
+}
+
+// ModelConfig controls the merge-time model of the predict and backtest
+// commands. Models are saved in Dir, one file per repository. Lambda is the
+// ridge penalty of the regression and MaxLabels the number of the most
+// frequent PR labels used as features.
+type ModelConfig struct {
+	Dir       string  `json:"dir"        yaml:"dir"`
+	Lambda    float64 `json:"lambda"     yaml:"lambda"`
+	MaxLabels int     `json:"max_labels" yaml:"max_labels"`
 }
 
 type Config struct {
-	GitHubToken  string
-	Repositories []RepoConfig
-	MaxPages     int
-	DelayMS      int
-	PerPage      int
-}
-
-func LoadConfig() *Config {
-	repos := []RepoConfig{
-		{Owner: "stmcginnis", Repo: "gofish"},
-		{Owner: "golang", Repo: "go"},
-		{Owner: "ipmitool", Repo: "ipmitool"},
-		{Owner: "docker", Repo: "compose"},
-		{Owner: "VictoriaMetrics", Repo: "VictoriaMetrics"},
-		{Owner: "prometheus", Repo: "prometheus"},
-	}
+	GitHubToken     string       `json:"github_token"     yaml:"github_token"`
+	GitHubAPIURL    string       `json:"github_api_url"   yaml:"github_api_url"`
+	GitHubBackend   string       `json:"github_backend"   yaml:"github_backend"`
+	Repositories    []RepoConfig `json:"repositories"     yaml:"repositories"`
+	MaxPages        int          `json:"max_pages"        yaml:"max_pages"`
+	MaxReviewPages  int          `json:"max_review_pages"  yaml:"max_review_pages"`
+	MaxCommentPages int          `json:"max_comment_pages" yaml:"max_comment_pages"`
+	DelayMS         int          `json:"delay_ms"         yaml:"delay_ms"`
+	PerPage         int          `json:"per_page"         yaml:"per_page"`
+
+	// Concurrency is the number of PRs fetched in parallel per repository,
+	// MaxConcurrency bounds it across all repositories and RepoConcurrency
+	// is the number of repositories processed at the same time. All workers
+	// share one rate limit budget.
+	Concurrency     int `json:"concurrency"      yaml:"concurrency"`
+	MaxConcurrency  int `json:"max_concurrency"  yaml:"max_concurrency"`
+	RepoConcurrency int `json:"repo_concurrency" yaml:"repo_concurrency"`
+
+	Retry           RetryConfig           `json:"retry"            yaml:"retry"`
+	Cache           CacheConfig           `json:"cache"            yaml:"cache"`
+	Spool           SpoolConfig           `json:"spool"            yaml:"spool"`
+	Totals          TotalsConfig          `json:"totals"           yaml:"totals"`
+	VictoriaMetrics VictoriaMetricsConfig `json:"victoria_metrics" yaml:"victoria_metrics"`
+	Daemon          DaemonConfig          `json:"daemon"           yaml:"daemon"`
+	Export          ExportConfig          `json:"export"           yaml:"export"`
+	Model           ModelConfig           `json:"model"            yaml:"model"`
+
+	// Labels are added to every exported series, e.g. team or env. Names
+	// from ReservedLabelNames and names starting with "__" are not allowed.
+	Labels map[string]string `json:"labels" yaml:"labels"`
+
+	// Calendars are the working calendars by name. Calendar is the one used
+	// by repositories that don't name their own; empty means wall-clock
+	// durations only.
+	Calendars map[string]CalendarConfig `json:"calendars" yaml:"calendars"`
+	Calendar  string                    `json:"calendar"  yaml:"calendar"`
+
+	// RecordDir and ReplayDir are set by the --record and --replay flags.
+	RecordDir string `json:"-" yaml:"-"`
+	ReplayDir string `json:"-" yaml:"-"`
+}
 
+// LoadConfig builds the configuration from defaults, the config file at path
+// and the environment. An empty path falls back to CONFIG_PATH. Callers apply
+// command-line overrides and then call Validate, and ValidateScraping when
+// they scrape GitHub.
+func LoadConfig(path string) (*Config, error) {
 	cfg := &Config{
-		GitHubToken:  getGitHubToken(),
-		Repositories: repos,
-		MaxPages:     getEnvAsInt("MAX_PAGES", 3),
-		DelayMS:      getEnvAsInt("DELAY_MS", 1000),
-		PerPage:      getEnvAsInt("PER_PAGE", 5),
+		GitHubAPIURL:    defaultGitHubAPIURL,
+		GitHubBackend:   BackendREST,
+		MaxPages:        3,
+		MaxReviewPages:  10,
+		MaxCommentPages: 10,
+		DelayMS:         250,
+		PerPage:         5,
+		Concurrency:     4,
+		MaxConcurrency:  8,
+		RepoConcurrency: 2,
+		Retry: RetryConfig{
+			MaxAttempts:          4,
+			BaseDelayMS:          500,
+			MaxDelayMS:           30000,
+			Jitter:               0.5,
+			RetryableStatusCodes: []int{500, 502, 503, 504},
+		},
+		Cache: CacheConfig{
+			Enabled:   true,
+			Dir:       defaultCacheDir(),
+			MaxSizeMB: 512,
+			TTLHours:  7 * 24,
+		},
+		Spool: SpoolConfig{
+			Enabled:     true,
+			Dir:         defaultSpoolDir(),
+			MaxSizeMB:   256,
+			MaxAttempts: 5,
+			BaseDelayMS: 1000,
+			MaxDelayMS:  30000,
+		},
+		Totals: TotalsConfig{
+			Enabled: true,
+			Dir:     defaultTotalsDir(),
+		},
+		Export: ExportConfig{
+			Aggregates:      true,
+			PerPR:           true,
+			PRLabels:        slices.Clone(PRLabelNames),
+			Histograms:      HistogramVMRange,
+			MergeWithinDays: []int{1, 7, 30},
+			AlignMinutes:    60,
+		},
+		Model: ModelConfig{
+			Dir:       defaultModelDir(),
+			Lambda:    1,
+			MaxLabels: 20,
+		},
+		Daemon: DaemonConfig{
+			Schedule:          "@every 1h",
+			Listen:            ":9102",
+			StateFile:         filepath.Join(defaultStateDir(), "daemon-state.json"),
+			ShutdownTimeoutMS: 60000,
+		},
+		VictoriaMetrics: VictoriaMetricsConfig{
+			Mode:             VMModeSingle,
+			Protocol:         ProtocolImport,
+			URL:              defaultVMURL,
+			Tenant:           "0",
+			TimeoutMS:        30000,
+			LastExecLookback: "1y",
+			Import: ImportConfig{
+				ChunkSizeMB: 16,
+				Gzip:        true,
+				Concurrency: 4,
+			},
+		},
+	}
+
+	if path == "" {
+		path = getEnv(configPathEnv, "")
 	}
 
+	if path != "" {
+		if err := readFile(path, cfg); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.Repositories = defaultRepositories()
+	}
+
+	cfg.GitHubToken = getEnv("GITHUB_TOKEN", cfg.GitHubToken)
+	cfg.GitHubAPIURL = strings.TrimSuffix(getEnv("GITHUB_API_URL", cfg.GitHubAPIURL), "/")
+	cfg.GitHubBackend = getEnv("GITHUB_BACKEND", cfg.GitHubBackend)
+	cfg.MaxPages = getEnvAsInt("MAX_PAGES", cfg.MaxPages)
+	cfg.MaxReviewPages = getEnvAsInt("MAX_REVIEW_PAGES", cfg.MaxReviewPages)
+	cfg.MaxCommentPages = getEnvAsInt("MAX_COMMENT_PAGES", cfg.MaxCommentPages)
+	cfg.DelayMS = getEnvAsInt("DELAY_MS", cfg.DelayMS)
+	cfg.PerPage = getEnvAsInt("PER_PAGE", cfg.PerPage)
+	cfg.Concurrency = getEnvAsInt("CONCURRENCY", cfg.Concurrency)
+	cfg.MaxConcurrency = getEnvAsInt("MAX_CONCURRENCY", cfg.MaxConcurrency)
+	cfg.RepoConcurrency = getEnvAsInt("REPO_CONCURRENCY", cfg.RepoConcurrency)
+	cfg.VictoriaMetrics.Mode = getEnv("VM_MODE", cfg.VictoriaMetrics.Mode)
+	cfg.VictoriaMetrics.URL = getEnv("VM_URL", cfg.VictoriaMetrics.URL)
+	cfg.VictoriaMetrics.InsertURL = getEnv("VM_INSERT_URL", cfg.VictoriaMetrics.InsertURL)
+	cfg.VictoriaMetrics.SelectURL = getEnv("VM_SELECT_URL", cfg.VictoriaMetrics.SelectURL)
+	cfg.VictoriaMetrics.Tenant = getEnv("VM_TENANT", cfg.VictoriaMetrics.Tenant)
+	cfg.VictoriaMetrics.Protocol = getEnv("VM_PROTOCOL", cfg.VictoriaMetrics.Protocol)
+	cfg.VictoriaMetrics.RemoteWriteURL = getEnv("VM_REMOTE_WRITE_URL", cfg.VictoriaMetrics.RemoteWriteURL)
+	cfg.VictoriaMetrics.Username = getEnv("VM_USERNAME", cfg.VictoriaMetrics.Username)
+	cfg.VictoriaMetrics.Password = getEnv("VM_PASSWORD", cfg.VictoriaMetrics.Password)
+	cfg.VictoriaMetrics.BearerToken = getEnv("VM_BEARER_TOKEN", cfg.VictoriaMetrics.BearerToken)
+	cfg.Cache.Dir = getEnv("CACHE_DIR", cfg.Cache.Dir)
+	cfg.Spool.Dir = getEnv("SPOOL_DIR", cfg.Spool.Dir)
+	cfg.Totals.Dir = getEnv("TOTALS_DIR", cfg.Totals.Dir)
+	cfg.Model.Dir = getEnv("MODEL_DIR", cfg.Model.Dir)
+
+	cfg.applyCalendarDefaults()
+	cfg.applyRepoDefaults()
+
 	if cfg.GitHubToken == "" {
 		fmt.Println("⚠️  Working without a token (limited number of requests)")
 		fmt.Println("   To increase the limits, create a GITHUB_TOKEN")
 	}
 
-	return cfg
+	return cfg, nil
 }
 
-func getGitHubToken() string {
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		return token
+// Repository returns the settings of owner/repo with overrides applied. Unknown
+// repositories get the global settings.
+func (c *Config) Repository(owner, repo string) RepoConfig {
+	for _, r := range c.Repositories {
+		if r.Owner == owner && r.Repo == repo {
+			return r
+		}
+	}
+
+	return RepoConfig{
+		Owner:           owner,
+		Repo:            repo,
+		MaxPages:        c.MaxPages,
+		MaxReviewPages:  c.MaxReviewPages,
+		MaxCommentPages: c.MaxCommentPages,
+		PerPage:         c.PerPage,
+		DelayMS:         c.DelayMS,
+		Concurrency:     c.Concurrency,
+		Schedule:        c.Daemon.Schedule,
+		Labels:          mergeLabels(c.Labels, nil),
+		Calendar:        c.Calendar,
 	}
-	return ""
+}
+
+func (c *Config) applyRepoDefaults() {
+	for i := range c.Repositories {
+		r := &c.Repositories[i]
+
+		if r.MaxPages == 0 {
+			r.MaxPages = c.MaxPages
+		}
+		if r.MaxReviewPages == 0 {
+			r.MaxReviewPages = c.MaxReviewPages
+		}
+		if r.MaxCommentPages == 0 {
+			r.MaxCommentPages = c.MaxCommentPages
+		}
+		if r.PerPage == 0 {
+			r.PerPage = c.PerPage
+		}
+		if r.DelayMS == 0 {
+			r.DelayMS = c.DelayMS
+		}
+		if r.Concurrency == 0 {
+			r.Concurrency = c.Concurrency
+		}
+		if r.Schedule == "" {
+			r.Schedule = c.Daemon.Schedule
+		}
+		if r.Calendar == "" {
+			r.Calendar = c.Calendar
+		}
+
+		r.Labels = mergeLabels(c.Labels, r.Labels)
+	}
+}
+
+// mergeLabels returns global with overrides applied. Labels with an empty
+// value are left out.
+func mergeLabels(global, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(global)+len(overrides))
+
+	for name, value := range global {
+		merged[name] = value
+	}
+
+	for name, value := range overrides {
+		merged[name] = value
+	}
+
+	for name, value := range merged {
+		if value == "" {
+			delete(merged, name)
+		}
+	}
+
+	return merged
+}
+
+func defaultCacheDir() string {
+	return filepath.Join(defaultStateDir(), "http")
+}
+
+func defaultSpoolDir() string {
+	return filepath.Join(defaultStateDir(), "spool")
+}
+
+func defaultTotalsDir() string {
+	return filepath.Join(defaultStateDir(), "totals")
+}
+
+func defaultModelDir() string {
+	return filepath.Join(defaultStateDir(), "models")
+}
+
+func defaultStateDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "metrics-scrapper")
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMergeLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    map[string]string
+		overrides map[string]string
+		want      map[string]string
+	}{
+		{name: "nothing", global: nil, overrides: nil, want: map[string]string{}},
+		{
+			name:      "global only",
+			global:    map[string]string{"team": "core", "env": "prod"},
+			overrides: nil,
+			want:      map[string]string{"team": "core", "env": "prod"},
+		},
+		{
+			name:      "override wins",
+			global:    map[string]string{"team": "core", "env": "prod"},
+			overrides: map[string]string{"team": "web", "language": "go"},
+			want:      map[string]string{"team": "web", "env": "prod", "language": "go"},
+		},
+		{
+			name:      "empty override removes a global label",
+			global:    map[string]string{"team": "core", "env": "prod"},
+			overrides: map[string]string{"env": ""},
+			want:      map[string]string{"team": "core"},
+		},
+		{
+			name:      "empty global value is dropped",
+			global:    map[string]string{"team": ""},
+			overrides: nil,
+			want:      map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global := maps.Clone(tt.global)
+
+			got := mergeLabels(tt.global, tt.overrides)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("mergeLabels() = %v, want %v", got, tt.want)
+			}
+
+			if !maps.Equal(tt.global, global) {
+				t.Errorf("global labels changed to %v", tt.global)
+			}
+		})
+	}
+}
+
+// loadYAML loads a config file with the given contents.
+func loadYAML(t *testing.T, contents string) *Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	return cfg
+}
+
+func TestValidateLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  string
+		repo    string
+		wantErr string
+	}{
+		{name: "valid global and repository labels", labels: "{team: core, env_2: prod}", repo: "{team: web}"},
+		{name: "empty repository value removes a label", labels: "{team: core}", repo: "{team: ''}"},
+		{name: "invalid global name", labels: "{team-name: core}", wantErr: `labels: invalid label name "team-name"`},
+		{name: "name starting with a digit", labels: "{2team: core}", wantErr: `labels: invalid label name "2team"`},
+		{name: "reserved global name", labels: "{repo: other}", wantErr: `labels: label "repo" is reserved`},
+		{name: "reserved PR label", labels: "{author: bob}", wantErr: `labels: label "author" is reserved`},
+		{name: "double underscore prefix", labels: "{__name__: x}", wantErr: `labels: label "__name__" is reserved`},
+		{name: "histogram label", labels: "{vmrange: x}", wantErr: `labels: label "vmrange" is reserved`},
+		{name: "reserved repository name", repo: "{quantile: x}", wantErr: `repositories[0].labels: label "quantile" is reserved`},
+		{name: "invalid repository name", repo: "{'a.b': x}", wantErr: `repositories[0].labels: invalid label name "a.b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := "repositories:\n  - owner: o\n    repo: r\n"
+			if tt.repo != "" {
+				contents += "    labels: " + tt.repo + "\n"
+			}
+
+			if tt.labels != "" {
+				contents += "labels: " + tt.labels + "\n"
+			}
+
+			err := loadYAML(t, contents).Validate()
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tt.wantErr != "" && (!errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateScraping(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		replay   string
+		wantErr  string
+	}{
+		{name: "repositories with the REST backend", contents: "repositories: [{owner: o, repo: r}]\n"},
+		{name: "no repositories", contents: "labels: {team: core}\n", wantErr: "repositories: no repositories configured"},
+		{
+			name:     "GraphQL without a token",
+			contents: "repositories: [{owner: o, repo: r}]\ngithub_backend: graphql\n",
+			wantErr:  `github_backend: "graphql" requires a GITHUB_TOKEN`,
+		},
+		{
+			name:     "GraphQL replaying recorded responses",
+			contents: "repositories: [{owner: o, repo: r}]\ngithub_backend: graphql\n",
+			replay:   "testdata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", "")
+
+			cfg := loadYAML(t, tt.contents)
+			cfg.ReplayDir = tt.replay
+
+			// Settings alone are valid either way.
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			err := cfg.ValidateScraping()
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateScraping: %v", err)
+			case tt.wantErr != "" && (!errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	defaults := make([]string, 0, len(defaultRepositories()))
+	for _, repo := range defaultRepositories() {
+		defaults = append(defaults, repo.Key())
+	}
+
+	type settings struct {
+		repos       []string
+		perPage     int
+		repoPerPage int
+		vmURL       string
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		// viaEnv passes the file in CONFIG_PATH instead of as the path.
+		viaEnv  bool
+		env     map[string]string
+		want    settings
+		wantErr error
+	}{
+		{
+			name: "defaults without a config file",
+			want: settings{repos: defaults, perPage: 5, repoPerPage: 5, vmURL: defaultVMURL},
+		},
+		{
+			name:     "YAML file",
+			file:     "config.yaml",
+			contents: "repositories: [{owner: o, repo: a}]\nper_page: 50\nvictoria_metrics: {url: http://file}\n",
+			want:     settings{repos: []string{"o/a"}, perPage: 50, repoPerPage: 50, vmURL: "http://file"},
+		},
+		{
+			name:     "JSON file",
+			file:     "config.json",
+			contents: `{"repositories": [{"owner": "o", "repo": "a"}], "per_page": 50, "victoria_metrics": {"url": "http://file"}}`,
+			want:     settings{repos: []string{"o/a"}, perPage: 50, repoPerPage: 50, vmURL: "http://file"},
+		},
+		{
+			name:     "file keeps the defaults it doesn't set",
+			file:     "config.yml",
+			contents: "repositories: [{owner: o, repo: a}]\n",
+			want:     settings{repos: []string{"o/a"}, perPage: 5, repoPerPage: 5, vmURL: defaultVMURL},
+		},
+		{
+			name:     "CONFIG_PATH without a path",
+			file:     "config.yaml",
+			contents: "repositories: [{owner: o, repo: a}]\n",
+			viaEnv:   true,
+			want:     settings{repos: []string{"o/a"}, perPage: 5, repoPerPage: 5, vmURL: defaultVMURL},
+		},
+		{
+			name:     "environment overrides the file",
+			file:     "config.yaml",
+			contents: "repositories: [{owner: o, repo: a}]\nper_page: 50\nvictoria_metrics: {url: http://file}\n",
+			env:      map[string]string{"PER_PAGE": "7", "VM_URL": "http://env"},
+			want:     settings{repos: []string{"o/a"}, perPage: 7, repoPerPage: 7, vmURL: "http://env"},
+		},
+		{
+			name: "environment overrides the defaults",
+			env:  map[string]string{"PER_PAGE": "7", "VM_URL": "http://env"},
+			want: settings{repos: defaults, perPage: 7, repoPerPage: 7, vmURL: "http://env"},
+		},
+		{
+			name:     "repository override beats the environment",
+			file:     "config.yaml",
+			contents: "repositories: [{owner: o, repo: a, per_page: 9}]\n",
+			env:      map[string]string{"PER_PAGE": "7"},
+			want:     settings{repos: []string{"o/a"}, perPage: 7, repoPerPage: 9, vmURL: defaultVMURL},
+		},
+		{
+			name:     "unknown key",
+			file:     "config.yaml",
+			contents: "repositories: [{owner: o, repo: a}]\nper_pages: 50\n",
+			wantErr:  ErrParsingConfigFile,
+		},
+		{
+			name:     "unknown format",
+			file:     "config.toml",
+			contents: "per_page = 50\n",
+			wantErr:  ErrUnknownConfigFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"CONFIG_PATH", "GITHUB_TOKEN", "PER_PAGE", "VM_URL"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			var path string
+
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), tt.file)
+				if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.viaEnv {
+				t.Setenv("CONFIG_PATH", path)
+				path = ""
+			}
+
+			cfg, err := LoadConfig(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadConfig: got error %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got := settings{
+				perPage:     cfg.PerPage,
+				repoPerPage: cfg.Repositories[0].PerPage,
+				vmURL:       cfg.VictoriaMetrics.URL,
+			}
+
+			for _, repo := range cfg.Repositories {
+				got.repos = append(got.repos, repo.Key())
+			}
+
+			if !slices.Equal(got.repos, tt.want.repos) || got.perPage != tt.want.perPage ||
+				got.repoPerPage != tt.want.repoPerPage || got.vmURL != tt.want.vmURL {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
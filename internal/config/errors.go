@@ -0,0 +1,10 @@
+package config
+
+import "errors"
+
+var (
+	ErrReadingConfigFile   = errors.New("reading config file")
+	ErrParsingConfigFile   = errors.New("parsing config file")
+	ErrUnknownConfigFormat = errors.New("unknown config file format")
+	ErrInvalidConfig       = errors.New("invalid config")
+)
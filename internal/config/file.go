@@ -0,0 +1,43 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes the config file at path on top of cfg. The format is chosen
+// by extension: .json, .yaml or .yml. Unknown keys are rejected so that typos
+// don't silently fall back to defaults.
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReadingConfigFile, err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(cfg)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("%w: %q (expected .json, .yaml or .yml)", ErrUnknownConfigFormat, ext)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrParsingConfigFile, path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Validate reports every problem found in the config at once, each prefixed
+// with the path of the offending field. What only scraping needs is checked
+// by ValidateScraping.
+func (c *Config) Validate() error {
+	var errs []error
+
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, field, fmt.Sprintf(format, args...)))
+	}
+
+	pageLimit := func(field string, limit int) {
+		if limit <= 0 && limit != Unlimited {
+			invalid(field, "must be positive or %d (unlimited), got %d", Unlimited, limit)
+		}
+	}
+
+	pageLimit("max_pages", c.MaxPages)
+	pageLimit("max_review_pages", c.MaxReviewPages)
+	pageLimit("max_comment_pages", c.MaxCommentPages)
+
+	if c.PerPage <= 0 || c.PerPage > 100 {
+		invalid("per_page", "must be between 1 and 100, got %d", c.PerPage)
+	}
+	if c.DelayMS < 0 {
+		invalid("delay_ms", "must not be negative, got %d", c.DelayMS)
+	}
+	if c.Concurrency < 1 {
+		invalid("concurrency", "must be at least 1, got %d", c.Concurrency)
+	}
+	if c.MaxConcurrency < 1 {
+		invalid("max_concurrency", "must be at least 1, got %d", c.MaxConcurrency)
+	}
+	if c.RepoConcurrency < 1 {
+		invalid("repo_concurrency", "must be at least 1, got %d", c.RepoConcurrency)
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		invalid("retry.max_attempts", "must be at least 1, got %d", c.Retry.MaxAttempts)
+	}
+	if c.Retry.BaseDelayMS < 0 {
+		invalid("retry.base_delay_ms", "must not be negative, got %d", c.Retry.BaseDelayMS)
+	}
+	if c.Retry.MaxDelayMS < c.Retry.BaseDelayMS {
+		invalid("retry.max_delay_ms", "must not be less than base_delay_ms, got %d", c.Retry.MaxDelayMS)
+	}
+	if c.Retry.Jitter < 0 || c.Retry.Jitter > 1 {
+		invalid("retry.jitter", "must be between 0 and 1, got %g", c.Retry.Jitter)
+	}
+	for _, code := range c.Retry.RetryableStatusCodes {
+		if code < 100 || code > 599 {
+			invalid("retry.retryable_status_codes", "%d is not an HTTP status code", code)
+		}
+	}
+
+	if c.Cache.Enabled {
+		if c.Cache.Dir == "" {
+			invalid("cache.dir", "must not be empty when the cache is enabled")
+		}
+		if c.Cache.MaxSizeMB < 0 {
+			invalid("cache.max_size_mb", "must not be negative, got %d", c.Cache.MaxSizeMB)
+		}
+		if c.Cache.TTLHours < 0 {
+			invalid("cache.ttl_hours", "must not be negative, got %d", c.Cache.TTLHours)
+		}
+	}
+
+	if c.Spool.Enabled {
+		if c.Spool.Dir == "" {
+			invalid("spool.dir", "must not be empty when the spool is enabled")
+		}
+		if c.Spool.MaxSizeMB < 0 {
+			invalid("spool.max_size_mb", "must not be negative, got %d", c.Spool.MaxSizeMB)
+		}
+		if c.Spool.MaxAttempts < 1 {
+			invalid("spool.max_attempts", "must be at least 1, got %d", c.Spool.MaxAttempts)
+		}
+		if c.Spool.BaseDelayMS < 0 {
+			invalid("spool.base_delay_ms", "must not be negative, got %d", c.Spool.BaseDelayMS)
+		}
+		if c.Spool.MaxDelayMS < c.Spool.BaseDelayMS {
+			invalid("spool.max_delay_ms", "must not be less than base_delay_ms, got %d", c.Spool.MaxDelayMS)
+		}
+	}
+
+	if c.RecordDir != "" && c.ReplayDir != "" {
+		invalid("--record", "cannot be combined with --replay")
+	}
+
+	if c.GitHubBackend != BackendREST && c.GitHubBackend != BackendGraphQL {
+		invalid("github_backend", "must be %q or %q, got %q", BackendREST, BackendGraphQL, c.GitHubBackend)
+	}
+
+	if u, err := url.Parse(c.GitHubAPIURL); err != nil || u.Scheme == "" || u.Host == "" {
+		invalid("github_api_url", "must be an absolute URL, got %q", c.GitHubAPIURL)
+	}
+
+	c.VictoriaMetrics.validate(invalid)
+
+	validSchedule := func(field, schedule string) {
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			invalid(field, "%v", err)
+		}
+	}
+
+	validSchedule("daemon.schedule", c.Daemon.Schedule)
+
+	validLabel := func(field, name string) {
+		switch {
+		case !labelNamePattern.MatchString(name):
+			invalid(field, "invalid label name %q, must match %s", name, labelNamePattern)
+		case strings.HasPrefix(name, "__") || slices.Contains(ReservedLabelNames, name):
+			invalid(field, "label %q is reserved", name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Labels)) {
+		validLabel("labels", name)
+	}
+
+	if !c.Export.Aggregates && !c.Export.PerPR {
+		invalid("export", "at least one of aggregates and per_pr must be enabled")
+	}
+
+	for _, label := range c.Export.PRLabels {
+		if !slices.Contains(PRLabelNames, label) {
+			invalid("export.pr_labels", "unknown label %q, must be one of %v", label, PRLabelNames)
+		}
+	}
+
+	if histograms := []string{HistogramVMRange, HistogramLE, HistogramNone}; !slices.Contains(histograms, c.Export.Histograms) {
+		invalid("export.histograms", "must be one of %v, got %q", histograms, c.Export.Histograms)
+	}
+
+	for _, days := range c.Export.MergeWithinDays {
+		if days < 1 {
+			invalid("export.merge_within_days", "must be at least 1, got %d", days)
+		}
+	}
+
+	if c.Export.AlignMinutes < 0 {
+		invalid("export.align_minutes", "must not be negative, got %d", c.Export.AlignMinutes)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Calendars)) {
+		field := "calendars." + name
+		calendar := c.Calendars[name]
+
+		if name == CalendarNone {
+			invalid(field, "calendar name %q is reserved", CalendarNone)
+		}
+		if _, err := calendar.Location(); err != nil {
+			invalid(field+".time_zone", "%v", err)
+		}
+		if _, _, err := calendar.WorkingHours(); err != nil {
+			invalid(field, "%v", err)
+		}
+		if _, err := calendar.WorkingDays(); err != nil {
+			invalid(field+".weekdays", "%v", err)
+		}
+		if _, err := calendar.HolidayDates(); err != nil {
+			invalid(field+".holidays", "%v", err)
+		}
+	}
+
+	knownCalendar := func(field, name string) {
+		if _, ok := c.Calendars[name]; !ok && name != "" && name != CalendarNone {
+			invalid(field, "unknown calendar %q", name)
+		}
+	}
+
+	knownCalendar("calendar", c.Calendar)
+
+	if c.Totals.Enabled && c.Totals.Dir == "" {
+		invalid("totals.dir", "must not be empty when totals are enabled")
+	}
+
+	if c.Model.Dir == "" {
+		invalid("model.dir", "must not be empty")
+	}
+	if c.Model.Lambda <= 0 {
+		invalid("model.lambda", "must be positive, got %g", c.Model.Lambda)
+	}
+	if c.Model.MaxLabels < 0 {
+		invalid("model.max_labels", "must not be negative, got %d", c.Model.MaxLabels)
+	}
+
+	if c.Daemon.ShutdownTimeoutMS < 0 {
+		invalid("daemon.shutdown_timeout_ms", "must not be negative, got %d", c.Daemon.ShutdownTimeoutMS)
+	}
+
+	seen := make(map[string]bool)
+
+	for i, r := range c.Repositories {
+		field := fmt.Sprintf("repositories[%d]", i)
+
+		if r.Owner == "" {
+			invalid(field+".owner", "must not be empty")
+		}
+		if r.Repo == "" {
+			invalid(field+".repo", "must not be empty")
+		}
+
+		if seen[r.Key()] {
+			invalid(field, "duplicate repository %s", r.Key())
+		}
+		seen[r.Key()] = true
+
+		pageLimit(field+".max_pages", r.MaxPages)
+		pageLimit(field+".max_review_pages", r.MaxReviewPages)
+		pageLimit(field+".max_comment_pages", r.MaxCommentPages)
+		if r.PerPage < 0 || r.PerPage > 100 {
+			invalid(field+".per_page", "must be between 1 and 100, got %d", r.PerPage)
+		}
+		if r.DelayMS < 0 {
+			invalid(field+".delay_ms", "must not be negative, got %d", r.DelayMS)
+		}
+		if r.Concurrency < 0 {
+			invalid(field+".concurrency", "must not be negative, got %d", r.Concurrency)
+		}
+		if r.Schedule != c.Daemon.Schedule {
+			validSchedule(field+".schedule", r.Schedule)
+		}
+		if r.Calendar != c.Calendar {
+			knownCalendar(field+".calendar", r.Calendar)
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(r.Labels)) {
+			// Global labels are already checked.
+			if global, ok := c.Labels[name]; !ok || global != r.Labels[name] {
+				validLabel(field+".labels", name)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateScraping reports what the commands scraping GitHub need on top of a
+// valid config: repositories, and a token for the GraphQL backend.
+func (c *Config) ValidateScraping() error {
+	var errs []error
+
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, field, fmt.Sprintf(format, args...)))
+	}
+
+	if len(c.Repositories) == 0 {
+		invalid("repositories", "no repositories configured in the config file")
+	}
+
+	if c.GitHubBackend == BackendGraphQL && c.GitHubToken == "" && c.ReplayDir == "" {
+		invalid("github_backend", "%q requires a GITHUB_TOKEN", BackendGraphQL)
+	}
+
+	return errors.Join(errs...)
+}
+
+var (
+	tenantPattern   = regexp.MustCompile(`^\d+(:\d+)?$`)
+	durationPattern = regexp.MustCompile(`^(\d+(ms|[smhdwy]))+$`)
+	// labelNamePattern is the Prometheus label name syntax.
+	labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+func (v *VictoriaMetricsConfig) validate(invalid func(field, format string, args ...any)) {
+	absoluteURL := func(field, value string) {
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid(field, "must be an absolute URL, got %q", value)
+		}
+	}
+
+	switch v.Mode {
+	case VMModeSingle:
+		absoluteURL("victoria_metrics.url", v.URL)
+	case VMModeCluster:
+		absoluteURL("victoria_metrics.insert_url", v.InsertURL)
+		absoluteURL("victoria_metrics.select_url", v.SelectURL)
+
+		if !tenantPattern.MatchString(v.Tenant) {
+			invalid("victoria_metrics.tenant", "must be accountID or accountID:projectID, got %q", v.Tenant)
+		}
+	default:
+		invalid("victoria_metrics.mode", "must be %q or %q, got %q", VMModeSingle, VMModeCluster, v.Mode)
+	}
+
+	switch v.Protocol {
+	case ProtocolImport:
+	case ProtocolRemoteWrite:
+		if v.RemoteWriteURL != "" {
+			absoluteURL("victoria_metrics.remote_write_url", v.RemoteWriteURL)
+		}
+		// Remote-write receivers other than VictoriaMetrics have no
+		// /api/v1/export to read the samples back from.
+		if v.Verify {
+			invalid("victoria_metrics.verify", "requires protocol %q", ProtocolImport)
+		}
+	default:
+		invalid("victoria_metrics.protocol", "must be %q or %q, got %q", ProtocolImport, ProtocolRemoteWrite, v.Protocol)
+	}
+
+	if v.BearerToken != "" && (v.Username != "" || v.Password != "") {
+		invalid("victoria_metrics.bearer_token", "cannot be combined with username/password")
+	}
+	if v.Password != "" && v.Username == "" {
+		invalid("victoria_metrics.username", "must be set together with password")
+	}
+
+	if (v.TLS.CertFile == "") != (v.TLS.KeyFile == "") {
+		invalid("victoria_metrics.tls", "cert_file and key_file must be set together")
+	}
+
+	if v.TimeoutMS <= 0 {
+		invalid("victoria_metrics.timeout_ms", "must be positive, got %d", v.TimeoutMS)
+	}
+
+	if v.Import.ChunkSizeMB < 1 {
+		invalid("victoria_metrics.import.chunk_size_mb", "must be at least 1, got %d", v.Import.ChunkSizeMB)
+	}
+
+	if v.Import.Concurrency < 1 {
+		invalid("victoria_metrics.import.concurrency", "must be at least 1, got %d", v.Import.Concurrency)
+	}
+
+	if !durationPattern.MatchString(v.LastExecLookback) {
+		invalid("victoria_metrics.last_exec_lookback", "must be a duration like 1y or 30d, got %q", v.LastExecLookback)
+	}
+}
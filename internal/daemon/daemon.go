@@ -0,0 +1,304 @@
+// Package daemon runs ScrapeAndPush for every repository on its own schedule
+// in a long-running process.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/exposition"
+	"metrics-scrapper/internal/manager"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Daemon schedules one job per repository. A repository never has two runs
+// in flight: a tick that arrives while the previous run is still going is
+// skipped. All runs share the GitHub client, its rate limit budget and the
+// MaxConcurrency bound.
+type Daemon struct {
+	cfg     *config.Config
+	manager *manager.MetricManager
+	state   *state
+	store   *exposition.Store
+	limiter analyzer.Limiter
+	cron    *cron.Cron
+	entries map[string]cron.EntryID
+
+	// jobCtx is cancelled only when in-flight runs outlive the shutdown
+	// timeout.
+	jobCtx     context.Context //nolint:containedctx
+	cancelJobs context.CancelFunc
+
+	mu       sync.Mutex
+	started  bool
+	stopping bool
+	inflight sync.WaitGroup
+}
+
+func New(cfg *config.Config, metricManager *manager.MetricManager) (*Daemon, error) {
+	st, err := loadState(cfg.Daemon.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+
+	d := &Daemon{ //nolint:exhaustruct
+		cfg:        cfg,
+		manager:    metricManager,
+		state:      st,
+		store:      exposition.NewStore(cfg.Labels, cfg.Export.MergeWithinDays),
+		limiter:    analyzer.NewLimiter(cfg.MaxConcurrency),
+		cron:       cron.New(),
+		entries:    make(map[string]cron.EntryID),
+		jobCtx:     jobCtx,
+		cancelJobs: cancelJobs,
+	}
+
+	for _, repo := range cfg.Repositories {
+		id, err := d.cron.AddFunc(repo.Schedule, func() { d.runRepo(repo) })
+		if err != nil {
+			cancelJobs()
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidSchedule, repo.Key(), err)
+		}
+
+		d.entries[repo.Key()] = id
+	}
+
+	return d, nil
+}
+
+// Run starts the scheduler and the HTTP server on listen and blocks until ctx
+// is done. Every repository runs once right away. On shutdown no new runs are
+// started and in-flight ones get the configured timeout to finish; after that
+// they are cancelled, which leaves their checkpoints where they were.
+func (d *Daemon) Run(ctx context.Context, listen string) error {
+	server := &http.Server{ //nolint:exhaustruct
+		Addr:              listen,
+		Handler:           d.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		fmt.Printf("Daemon listening on %s (/healthz, /readyz, /status, /metrics)\n", listen)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	d.start()
+
+	var err error
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+	}
+
+	d.shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil && !errors.Is(shutdownErr, http.ErrServerClosed) {
+		return errors.Join(err, shutdownErr)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// start starts the scheduler and runs every repository once right away.
+func (d *Daemon) start() {
+	d.cron.Start()
+
+	d.mu.Lock()
+	d.started = true
+	d.mu.Unlock()
+
+	for _, repo := range d.cfg.Repositories {
+		fmt.Printf("%s: schedule %q, next run at %s\n",
+			repo.Key(), repo.Schedule, d.cron.Entry(d.entries[repo.Key()]).Next.Format(time.DateTime))
+
+		go d.runRepo(repo)
+	}
+}
+
+func (d *Daemon) shutdown() {
+	d.mu.Lock()
+	d.stopping = true
+	d.mu.Unlock()
+
+	// Scheduled runs still going are tracked in inflight like the others, so
+	// they are left to the shutdown timeout below instead of being waited
+	// for here.
+	d.cron.Stop()
+
+	done := make(chan struct{})
+
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+
+	timeout := time.Duration(d.cfg.Daemon.ShutdownTimeoutMS) * time.Millisecond
+
+	fmt.Printf("Shutting down, waiting up to %v for in-flight runs\n", timeout)
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		fmt.Println("In-flight runs did not finish in time, cancelling them")
+		d.cancelJobs()
+		<-done
+	}
+
+	d.cancelJobs()
+}
+
+// begin marks repo as running unless it already is or the daemon is
+// stopping.
+func (d *Daemon) begin(repo string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopping || d.state.get(repo).Running {
+		return false, nil
+	}
+
+	d.inflight.Add(1)
+
+	return true, d.state.update(repo, func(s *RepoState) { s.Running = true })
+}
+
+func (d *Daemon) runRepo(repo config.RepoConfig) {
+	key := repo.Key()
+
+	ok, err := d.begin(key)
+	if err != nil {
+		fmt.Printf("%s: %v\n", key, err)
+	}
+
+	if !ok {
+		fmt.Printf("%s: previous run is still in progress or the daemon is stopping, skipping\n", key)
+		return
+	}
+
+	defer d.inflight.Done()
+
+	repoCfg := *d.cfg
+	repoCfg.Repositories = []config.RepoConfig{repo}
+
+	// A repository without a checkpoint was never scraped by the daemon. It
+	// starts from the full history, not from the execution timestamp pushed
+	// by runs of other repositories.
+	checkpoint := d.state.get(key).Checkpoint
+
+	report, runErr := d.manager.ScrapeAndPush(d.jobCtx, &repoCfg, manager.RunOptions{ //nolint:exhaustruct
+		ScrapeThreshold:   checkpoint,
+		FullHistory:       checkpoint.IsZero(),
+		SkipExecTimestamp: true,
+		Limiter:           d.limiter,
+	})
+
+	report.Print(os.Stdout)
+	d.store.Update(report)
+
+	repoReport := report.Repositories[0]
+
+	err = d.state.update(key, func(s *RepoState) {
+		s.Running = false
+
+		switch {
+		case (repoReport.Status == manager.StatusOK || repoReport.Status == manager.StatusQueued) && runErr == nil:
+			s.Checkpoint = report.StartedAt
+			s.LastSuccess = report.FinishedAt
+			s.LastError = ""
+		case d.jobCtx.Err() != nil:
+			// Cancelled on shutdown: keep the checkpoint so the next start
+			// repeats this run.
+			fmt.Printf("%s: run cancelled, checkpoint stays at %s\n", key, s.Checkpoint.Format(time.DateTime))
+		default:
+			s.LastFailure = report.FinishedAt
+			s.LastError = repoReport.Error
+			if runErr != nil {
+				s.LastError = runErr.Error()
+			}
+		}
+	})
+	if err != nil {
+		fmt.Printf("%s: %v\n", key, err)
+	}
+
+	if next := d.cron.Entry(d.entries[key]).Next; !next.IsZero() {
+		fmt.Printf("%s: next run at %s\n", key, next.Format(time.DateTime))
+	}
+}
+
+// RepoStatus is a repository as reported by /status.
+type RepoStatus struct {
+	Repository string    `json:"repository"`
+	Schedule   string    `json:"schedule"`
+	NextRun    time.Time `json:"next_run,omitzero"`
+	RepoState
+}
+
+func (d *Daemon) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		d.mu.Lock()
+		ready := d.started && !d.stopping
+		d.mu.Unlock()
+
+		if !ready {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(d.status()) //nolint:errcheck,errchkjson
+	})
+
+	mux.Handle("/metrics", d.store)
+
+	return mux
+}
+
+func (d *Daemon) status() []RepoStatus {
+	states := d.state.snapshot()
+	statuses := make([]RepoStatus, 0, len(d.cfg.Repositories))
+
+	for _, repo := range d.cfg.Repositories {
+		statuses = append(statuses, RepoStatus{
+			Repository: repo.Key(),
+			Schedule:   repo.Schedule,
+			NextRun:    d.cron.Entry(d.entries[repo.Key()]).Next,
+			RepoState:  states[repo.Key()],
+		})
+	}
+
+	return statuses
+}
@@ -0,0 +1,316 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/manager"
+	"metrics-scrapper/internal/vmdb"
+)
+
+var (
+	errFetch = errors.New("fetch failed")
+
+	// lastExec is the execution timestamp pushed by other runs.
+	lastExec   = time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	checkpoint = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// fakeGitHub records the threshold of every PR listing and fails it with err.
+// With release set, a listing waits for release to be closed or for its
+// context to be cancelled; entered is signalled as it starts waiting.
+type fakeGitHub struct {
+	err     error
+	entered chan struct{}
+	release chan struct{}
+
+	mu    sync.Mutex
+	since []time.Time
+}
+
+func (g *fakeGitHub) GetAllPullRequests(ctx context.Context, _, _ string, since time.Time) ([]github.PullRequest, error) {
+	g.mu.Lock()
+	g.since = append(g.since, since)
+	g.mu.Unlock()
+
+	if g.release != nil {
+		g.entered <- struct{}{}
+
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, g.err
+}
+
+func (g *fakeGitHub) GetReviews(context.Context, string, string, int) ([]github.Review, error) {
+	return nil, nil
+}
+
+func (g *fakeGitHub) GetComments(context.Context, string, string, int) ([]github.IssueComment, error) {
+	return nil, nil
+}
+
+func (g *fakeGitHub) RateLimit() github.RateLimitInfo {
+	return github.RateLimitInfo{} //nolint:exhaustruct
+}
+
+func (g *fakeGitHub) thresholds() []time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]time.Time(nil), g.since...)
+}
+
+// blockingGitHub returns a fakeGitHub whose listings wait for release.
+func blockingGitHub() *fakeGitHub {
+	return &fakeGitHub{ //nolint:exhaustruct
+		entered: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+}
+
+// fakeExporter accepts every push and reports lastExec as the latest
+// execution.
+type fakeExporter struct{}
+
+func (fakeExporter) PushMetrics(context.Context, *vmdb.Metrics) error {
+	return nil
+}
+
+func (fakeExporter) PushExecTimestamp(context.Context, time.Time) error {
+	return nil
+}
+
+func (fakeExporter) GetLastExecTimestamp(context.Context) (time.Time, error) {
+	return lastExec, nil
+}
+
+func (fakeExporter) VerifyMetrics(context.Context, *vmdb.Metrics) error {
+	return nil
+}
+
+var repo = config.RepoConfig{Owner: "o", Repo: "a", Schedule: "@every 1h"} //nolint:exhaustruct
+
+// newTestDaemon creates a daemon for repo whose state starts with the given
+// checkpoint, none when zero.
+func newTestDaemon(t *testing.T, gh github.GitHubService, start time.Time, shutdownTimeout time.Duration) *Daemon {
+	t.Helper()
+
+	cfg := &config.Config{ //nolint:exhaustruct
+		Repositories:    []config.RepoConfig{repo},
+		RepoConcurrency: 1,
+		MaxConcurrency:  1,
+		Export:          config.ExportConfig{Aggregates: true}, //nolint:exhaustruct
+		Daemon: config.DaemonConfig{ //nolint:exhaustruct
+			StateFile:         filepath.Join(t.TempDir(), "state.json"),
+			ShutdownTimeoutMS: int(shutdownTimeout / time.Millisecond),
+		},
+	}
+
+	d, err := New(cfg, manager.NewMetricManager(fakeExporter{}, gh))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if !start.IsZero() {
+		if err := d.state.update(repo.Key(), func(s *RepoState) { s.Checkpoint = start }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return d
+}
+
+func TestRunRepoCheckpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		checkpoint   time.Time
+		err          error
+		wantSince    time.Time
+		wantAdvanced bool
+		wantFailed   bool
+	}{
+		{
+			name:         "no checkpoint scrapes the full history",
+			wantSince:    time.Time{},
+			wantAdvanced: true,
+		},
+		{
+			name:         "checkpoint limits scraping",
+			checkpoint:   checkpoint,
+			wantSince:    checkpoint,
+			wantAdvanced: true,
+		},
+		{
+			name:       "failure keeps the checkpoint",
+			checkpoint: checkpoint,
+			err:        errFetch,
+			wantSince:  checkpoint,
+			wantFailed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{err: tt.err} //nolint:exhaustruct
+			d := newTestDaemon(t, gh, tt.checkpoint, time.Second)
+
+			started := time.Now()
+
+			d.runRepo(repo)
+
+			if since := gh.thresholds(); len(since) != 1 || !since[0].Equal(tt.wantSince) {
+				t.Errorf("scraped PRs updated after %v, want [%v]", since, tt.wantSince)
+			}
+
+			got := d.state.get(repo.Key())
+
+			if advanced := !got.Checkpoint.Equal(tt.checkpoint); advanced != tt.wantAdvanced {
+				t.Errorf("checkpoint %v advanced %t, want %t", got.Checkpoint, advanced, tt.wantAdvanced)
+			}
+
+			if tt.wantAdvanced && got.Checkpoint.Before(started.Truncate(time.Second)) {
+				t.Errorf("checkpoint %v, want the start of the run at %v", got.Checkpoint, started)
+			}
+
+			if failed := got.LastError != "" && !got.LastFailure.IsZero(); failed != tt.wantFailed {
+				t.Errorf("last error %q at %v, want failed %t", got.LastError, got.LastFailure, tt.wantFailed)
+			}
+
+			if got.Running {
+				t.Error("repository still marked as running")
+			}
+		})
+	}
+}
+
+func TestRunRepoSkipsWhileInFlight(t *testing.T) {
+	gh := blockingGitHub()
+	d := newTestDaemon(t, gh, checkpoint, time.Second)
+
+	done := make(chan struct{})
+
+	go func() {
+		d.runRepo(repo)
+		close(done)
+	}()
+
+	<-gh.entered
+
+	// A tick arriving while the first run is going returns right away.
+	d.runRepo(repo)
+
+	close(gh.release)
+	<-done
+
+	if since := gh.thresholds(); len(since) != 1 {
+		t.Errorf("repository scraped %d times, want once", len(since))
+	}
+
+	if got := d.state.get(repo.Key()); got.Checkpoint.Equal(checkpoint) || got.Running {
+		t.Errorf("state %+v after the run, want an advanced checkpoint", got)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		finishAfter  time.Duration
+		wantAdvanced bool
+	}{
+		{
+			name:         "in-flight run finishes within the timeout",
+			timeout:      10 * time.Second,
+			finishAfter:  10 * time.Millisecond,
+			wantAdvanced: true,
+		},
+		{
+			name:    "in-flight run is cancelled after the timeout",
+			timeout: 20 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := blockingGitHub()
+			d := newTestDaemon(t, gh, checkpoint, tt.timeout)
+
+			go d.runRepo(repo)
+
+			<-gh.entered
+
+			if tt.finishAfter > 0 {
+				time.AfterFunc(tt.finishAfter, func() { close(gh.release) })
+			}
+
+			started := time.Now()
+
+			d.shutdown()
+
+			if elapsed := time.Since(started); !tt.wantAdvanced && elapsed < tt.timeout {
+				t.Errorf("shutdown took %v, want at least the %v timeout", elapsed, tt.timeout)
+			}
+
+			got := d.state.get(repo.Key())
+
+			if advanced := !got.Checkpoint.Equal(checkpoint); advanced != tt.wantAdvanced {
+				t.Errorf("checkpoint %v advanced %t, want %t", got.Checkpoint, advanced, tt.wantAdvanced)
+			}
+
+			// A cancelled run is not a failure.
+			if got.LastError != "" || got.Running {
+				t.Errorf("state %+v after shutdown, want no error and not running", got)
+			}
+
+			// No runs start once the daemon is stopping.
+			d.runRepo(repo)
+
+			if since := gh.thresholds(); len(since) != 1 {
+				t.Errorf("repository scraped %d times, want once", len(since))
+			}
+		})
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	d := newTestDaemon(t, &fakeGitHub{}, checkpoint, time.Second) //nolint:exhaustruct
+	handler := d.routes()
+
+	readyz := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		return rec.Code
+	}
+
+	steps := []struct {
+		name string
+		do   func()
+		want int
+	}{
+		{name: "before start", do: func() {}, want: http.StatusServiceUnavailable},
+		{name: "started", do: d.start, want: http.StatusOK},
+		{name: "stopping", do: d.shutdown, want: http.StatusServiceUnavailable},
+	}
+
+	for _, step := range steps {
+		step.do()
+
+		if got := readyz(); got != step.want {
+			t.Errorf("%s: /readyz %d, want %d", step.name, got, step.want)
+		}
+	}
+}
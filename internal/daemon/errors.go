@@ -0,0 +1,9 @@
+package daemon
+
+import "errors"
+
+var (
+	ErrLoadingState    = errors.New("loading daemon state")
+	ErrSavingState     = errors.New("saving daemon state")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RepoState is what the daemon remembers about a repository between runs and
+// restarts.
+type RepoState struct {
+	// Checkpoint is the start of the latest successful run. The next run
+	// scrapes PRs updated after it.
+	Checkpoint  time.Time `json:"checkpoint"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastFailure time.Time `json:"last_failure,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+	Running     bool      `json:"running"`
+}
+
+// state is persisted to a JSON file after every change, so a restarted daemon
+// continues from the same checkpoints.
+type state struct {
+	path string
+
+	mu    sync.Mutex
+	repos map[string]RepoState
+}
+
+func loadState(path string) (*state, error) {
+	s := &state{ //nolint:exhaustruct
+		path:  path,
+		repos: make(map[string]RepoState),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadingState, err)
+	}
+
+	if err := json.Unmarshal(data, &s.repos); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoadingState, path, err)
+	}
+
+	// A repository can't be running in a freshly started daemon.
+	for key, repo := range s.repos {
+		repo.Running = false
+		s.repos[key] = repo
+	}
+
+	return s, nil
+}
+
+func (s *state) get(repo string) RepoState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.repos[repo]
+}
+
+func (s *state) snapshot() map[string]RepoState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos := make(map[string]RepoState, len(s.repos))
+	for key, repo := range s.repos {
+		repos[key] = repo
+	}
+
+	return repos
+}
+
+// update applies fn to the state of repo and saves the result.
+func (s *state) update(repo string, fn func(*RepoState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repoState := s.repos[repo]
+	fn(&repoState)
+	s.repos[repo] = repoState
+
+	return s.save()
+}
+
+func (s *state) save() error {
+	data, err := json.MarshalIndent(s.repos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingState, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingState, err)
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingState, err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingState, err)
+	}
+
+	return nil
+}
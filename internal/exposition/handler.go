@@ -0,0 +1,210 @@
+package exposition
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/manager"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	textContentType        = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// ServeHTTP writes the OpenMetrics format when the scraper accepts it and the
+// classic text exposition format otherwise.
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+
+	if openMetrics {
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", textContentType)
+	}
+
+	buf := bufio.NewWriter(w)
+	s.write(buf, openMetrics)
+	buf.Flush() //nolint:errcheck
+}
+
+type sample struct {
+	repo   string
+	labels map[string]string
+	value  float64
+}
+
+func (s *Store) write(w io.Writer, openMetrics bool) {
+	keys, states, lastRefresh, refreshDuration, refreshes := s.snapshot()
+
+	enc := encoder{w: w, openMetrics: openMetrics}
+
+	// Summary metrics keep the names used by the push mode, so dashboards
+	// work with either.
+	var families []manager.SummaryMetric
+
+	values := make(map[string][]sample)
+
+	for _, key := range keys {
+		analysis := states[key].Analysis
+		if analysis == nil {
+			continue
+		}
+
+		for _, metric := range manager.SummaryMetrics(*analysis, s.mergeWithinDays) {
+			if _, seen := values[metric.Name]; !seen {
+				families = append(families, metric)
+			}
+
+			values[metric.Name] = append(values[metric.Name], sample{repo: key, labels: manager.WithLabels(states[key].Labels, metric.Labels), value: metric.Value})
+		}
+	}
+
+	// Without any analysis the families are still described, once each.
+	if len(families) == 0 {
+		for _, metric := range manager.SummaryMetrics(analyzer.AnalysisResult{}, s.mergeWithinDays) { //nolint:exhaustruct
+			if _, seen := values[metric.Name]; !seen {
+				families = append(families, metric)
+				values[metric.Name] = nil
+			}
+		}
+	}
+
+	for _, family := range families {
+		enc.family(family.Name, "gauge", family.Help, values[family.Name])
+	}
+
+	var up, lastSuccess, lastFailure []sample
+
+	for _, key := range keys {
+		state := states[key]
+
+		up = append(up, sample{repo: key, labels: state.Labels, value: boolValue(state.Up)})
+
+		if !state.LastSuccess.IsZero() {
+			lastSuccess = append(lastSuccess, sample{repo: key, labels: state.Labels, value: unixSeconds(state.LastSuccess)})
+		}
+
+		if !state.LastFailure.IsZero() {
+			lastFailure = append(lastFailure, sample{repo: key, labels: state.Labels, value: unixSeconds(state.LastFailure)})
+		}
+	}
+
+	enc.family("metrics_scrapper_repo_up", "gauge",
+		"Whether the latest refresh of the repository succeeded.", up)
+	enc.family("metrics_scrapper_repo_last_success_timestamp_seconds", "gauge",
+		"Time of the latest successful refresh of the repository.", lastSuccess)
+	enc.family("metrics_scrapper_repo_last_failure_timestamp_seconds", "gauge",
+		"Time of the latest failed refresh of the repository.", lastFailure)
+
+	if refreshes > 0 {
+		enc.family("metrics_scrapper_last_refresh_timestamp_seconds", "gauge",
+			"Time the latest refresh finished.", []sample{{labels: s.labels, value: unixSeconds(lastRefresh)}})
+		enc.family("metrics_scrapper_refresh_duration_seconds", "gauge",
+			"Duration of the latest refresh.", []sample{{labels: s.labels, value: refreshDuration.Seconds()}})
+	}
+
+	enc.family("metrics_scrapper_refreshes", "counter",
+		"Number of completed refreshes.", []sample{{labels: s.labels, value: float64(refreshes)}})
+
+	if openMetrics {
+		fmt.Fprintln(w, "# EOF")
+	}
+}
+
+type encoder struct {
+	w           io.Writer
+	openMetrics bool
+}
+
+// family writes the HELP/TYPE metadata and the samples of one metric. Counter
+// samples get the _total suffix; OpenMetrics leaves it out of the metadata.
+func (e encoder) family(name, metricType, help string, samples []sample) {
+	sampleName := name
+	if metricType == "counter" {
+		sampleName = name + "_total"
+
+		if !e.openMetrics {
+			name = sampleName
+		}
+	}
+
+	fmt.Fprintf(e.w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(e.w, "# TYPE %s %s\n", name, metricType)
+
+	for _, s := range samples {
+		fmt.Fprintf(e.w, "%s%s %s\n", sampleName, formatLabels(s.repo, s.labels), formatValue(s.value))
+	}
+}
+
+// formatLabels renders the repo label followed by the extra labels sorted by
+// name, or nothing when there are none.
+func formatLabels(repo string, labels map[string]string) string {
+	var pairs []string
+
+	if repo != "" {
+		pairs = append(pairs, fmt.Sprintf("repo=\"%s\"", escapeLabelValue(repo)))
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(labels[name])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
@@ -0,0 +1,121 @@
+package exposition
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/manager"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// populated returns a store refreshed once with an analyzed repository
+// carrying labels that need escaping and a failed one.
+func populated() *Store {
+	store := NewStore(map[string]string{"env": "prod"}, []int{7})
+
+	finished := time.Date(2024, time.January, 2, 3, 4, 5, 678_000_000, time.UTC)
+
+	store.Update(&manager.RunReport{ //nolint:exhaustruct
+		FinishedAt: finished,
+		Duration:   1500 * time.Millisecond,
+		Repositories: []manager.RepoReport{
+			{ //nolint:exhaustruct
+				Repository: "o/a",
+				Labels:     map[string]string{"env": "prod", "team": "say \"hi\"\nto C:\\"},
+				Status:     manager.StatusOK,
+				Result: &analyzer.RepositoryResult{ //nolint:exhaustruct
+					Analysis: analyzer.AnalysisResult{ //nolint:exhaustruct
+						MergeRate:                62.5,
+						MedianLifetime:           36 * time.Hour,
+						AverageTimeToFirstReview: 90 * time.Minute,
+						HeuristicTimeToMerge:     26 * time.Hour,
+						LifetimePercentiles: analyzer.Percentiles{
+							P50: time.Hour, P75: 2 * time.Hour, P90: 3 * time.Hour, P95: 4 * time.Hour, P99: 5 * time.Hour,
+						},
+						MedianTimeToMerge: analyzer.DurationEstimate{Value: 20 * time.Hour, Lower: 10 * time.Hour, Upper: 0},
+					},
+				},
+			},
+			{ //nolint:exhaustruct
+				Repository: "o/b",
+				Labels:     map[string]string{"env": "prod"},
+				Status:     manager.StatusFailed,
+			},
+		},
+	})
+
+	return store
+}
+
+func TestServeHTTPGolden(t *testing.T) {
+	tests := []struct {
+		name            string
+		store           *Store
+		accept          string
+		wantContentType string
+	}{
+		{
+			name:            "empty.txt",
+			store:           NewStore(nil, []int{7}),
+			wantContentType: textContentType,
+		},
+		{
+			name:            "empty.openmetrics",
+			store:           NewStore(nil, []int{7}),
+			accept:          "application/openmetrics-text; version=1.0.0,text/plain;q=0.5",
+			wantContentType: openMetricsContentType,
+		},
+		{
+			name:            "populated.txt",
+			store:           populated(),
+			accept:          "text/plain",
+			wantContentType: textContentType,
+		},
+		{
+			name:            "populated.openmetrics",
+			store:           populated(),
+			accept:          "application/openmetrics-text",
+			wantContentType: openMetricsContentType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			tt.store.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type %q, want %q", got, tt.wantContentType)
+			}
+
+			golden := filepath.Join("testdata", tt.name)
+
+			if *update {
+				if err := os.WriteFile(golden, rec.Body.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := rec.Body.String(); got != string(want) {
+				t.Errorf("output differs from %s, rerun with -update to inspect:\n%s", golden, got)
+			}
+		})
+	}
+}
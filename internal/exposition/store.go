@@ -0,0 +1,112 @@
+// Package exposition keeps the latest analysis of every repository in memory
+// and serves it in the Prometheus text exposition or OpenMetrics format.
+package exposition
+
+import (
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/manager"
+	"sort"
+	"sync"
+	"time"
+)
+
+// repoState is what is known about a repository after the latest refresh.
+// Analysis covers every PR seen so far when totals are enabled (see
+// config.TotalsConfig) and only the PRs of the latest refresh otherwise. It
+// survives failed refreshes, so a flaky repository keeps its last known
+// values while its up metric drops to 0.
+type repoState struct {
+	Labels      map[string]string
+	Analysis    *analyzer.AnalysisResult
+	Up          bool
+	LastSuccess time.Time
+	LastFailure time.Time
+}
+
+// Store is safe for concurrent use by the refresh loop and HTTP handlers.
+type Store struct {
+	// labels are added to the series that don't belong to a repository.
+	labels map[string]string
+	// mergeWithinDays are the horizons of the merge probabilities.
+	mergeWithinDays []int
+
+	mu              sync.RWMutex
+	repos           map[string]*repoState
+	lastRefresh     time.Time
+	refreshDuration time.Duration
+	refreshes       int
+}
+
+// NewStore returns an empty store. labels are the global extra labels;
+// repositories carry their own in the run reports.
+func NewStore(labels map[string]string, mergeWithinDays []int) *Store {
+	return &Store{ //nolint:exhaustruct
+		labels:          labels,
+		mergeWithinDays: mergeWithinDays,
+		repos:           make(map[string]*repoState),
+	}
+}
+
+// Update applies the outcome of a ScrapeAndPush run.
+func (s *Store) Update(report *manager.RunReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, repo := range report.Repositories {
+		state, ok := s.repos[repo.Repository]
+		if !ok {
+			state = &repoState{} //nolint:exhaustruct
+			s.repos[repo.Repository] = state
+		}
+
+		if repo.Status != manager.StatusCancelled {
+			state.Labels = repo.Labels
+		}
+
+		switch repo.Status {
+		case manager.StatusOK, manager.StatusQueued:
+			state.Up = true
+			state.LastSuccess = report.FinishedAt
+
+			if repo.Result != nil {
+				analysis := repo.Result.Analysis
+				state.Analysis = &analysis
+			}
+		case manager.StatusFailed, manager.StatusSkipped:
+			state.Up = false
+			state.LastFailure = report.FinishedAt
+		case manager.StatusCancelled:
+			// Interrupted refreshes tell nothing about the repository.
+		}
+	}
+
+	s.lastRefresh = report.FinishedAt
+	s.refreshDuration = report.Duration
+	s.refreshes++
+}
+
+// Ready reports whether at least one refresh has completed.
+func (s *Store) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.refreshes > 0
+}
+
+// snapshot returns a copy of the state sorted by repository.
+func (s *Store) snapshot() ([]string, map[string]repoState, time.Time, time.Duration, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.repos))
+	states := make(map[string]repoState, len(s.repos))
+
+	for key, state := range s.repos {
+		keys = append(keys, key)
+		states[key] = *state
+	}
+
+	sort.Strings(keys)
+
+	return keys, states, s.lastRefresh, s.refreshDuration, s.refreshes
+}
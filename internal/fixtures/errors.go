@@ -0,0 +1,10 @@
+package fixtures
+
+import "errors"
+
+var (
+	ErrNoFixture       = errors.New("no recorded response")
+	ErrCorruptFixture  = errors.New("corrupt fixture")
+	ErrWritingFixture  = errors.New("writing fixture")
+	ErrOpeningFixtures = errors.New("opening fixtures directory")
+)
@@ -0,0 +1,71 @@
+// Package fixtures records GitHub API traffic to a directory and replays it
+// later, so runs can be reproduced without a token or network access.
+//
+// Every request/response pair is stored as one JSON file named after a hash
+// of the request method, URL and body. Credentials are never written.
+package fixtures
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+type fixture struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+func fixturePath(dir, method, url string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + url + "\n")) //nolint:errcheck
+	h.Write(body)                              //nolint:errcheck
+
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))[:20]+".json")
+}
+
+func readFixture(path string) (fixture, error) {
+	var f fixture
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("%w %s: %w", ErrCorruptFixture, path, err)
+	}
+
+	return f, nil
+}
+
+func writeFixture(path string, f fixture) error {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(f); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,88 @@
+package fixtures
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// sensitiveHeaders are dropped from recorded responses.
+var sensitiveHeaders = []string{"Set-Cookie", "Authorization"}
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// Recorder wraps next and stores every request/response pair in dir.
+func Recorder(dir string, next http.RoundTripper) (http.RoundTripper, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningFixtures, err)
+	}
+
+	return &recorder{dir: dir, next: next}, nil
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+	for _, name := range sensitiveHeaders {
+		header.Del(name)
+	}
+
+	url := req.URL.String()
+
+	err = writeFixture(fixturePath(r.dir, req.Method, url, reqBody), fixture{
+		Request: recordedRequest{
+			Method: req.Method,
+			URL:    url,
+			Body:   string(reqBody),
+		},
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       string(respBody),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWritingFixture, err)
+	}
+
+	return resp, nil
+}
+
+// readRequestBody returns the request body and leaves an unread copy in req.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close() //nolint:errcheck
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,70 @@
+package fixtures
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type replayer struct {
+	dir string
+}
+
+// Replayer serves responses recorded in dir and never touches the network.
+// Rate limit headers are dropped, so replayed runs are not paced by the budget
+// that was left at recording time.
+func Replayer(dir string) (http.RoundTripper, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningFixtures, err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrOpeningFixtures, dir)
+	}
+
+	return &replayer{dir: dir}, nil
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := req.URL.String()
+
+	f, err := readFixture(fixturePath(r.dir, req.Method, url, body))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w for %s %s", ErrNoFixture, req.Method, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := f.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ratelimit-") {
+			header.Del(name)
+		}
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(f.Response.StatusCode) + " " + http.StatusText(f.Response.StatusCode),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}, nil
+}
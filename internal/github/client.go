@@ -1,24 +1,48 @@
 package github
 
 import (
-	"fmt"
+	"context"
 	"metrics-scrapper/internal/config"
 	"net/http"
 	"time"
 )
 
 type Client struct {
-	config     *config.Config
-	httpClient *http.Client
+	config    *config.Config
+	baseURL   string
+	requester *requester
 }
 
-func NewClient(cfg *config.Config) *Client {
+func NewClient(cfg *config.Config, transport http.RoundTripper) *Client {
 	return &Client{
-		config:     cfg,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		config:    cfg,
+		baseURL:   cfg.GitHubAPIURL,
+		requester: newRequester(cfg, transport),
 	}
 }
 
+func newRequester(cfg *config.Config, transport http.RoundTripper) *requester {
+	policy := newRetryPolicy(cfg.Retry)
+
+	// Replayed responses are deterministic: pacing and retries only slow
+	// the run down.
+	offline := cfg.ReplayDir != ""
+	if offline {
+		policy.maxAttempts = 1
+	}
+
+	return &requester{
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		policy:     policy,
+		unpaced:    offline,
+	}
+}
+
+// RateLimit returns the REST API budget reported by the last response.
+func (c *Client) RateLimit() RateLimitInfo {
+	return c.requester.rateLimit()
+}
+
 func (c *Client) createRequest(url string) (*http.Request, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -34,28 +58,9 @@ func (c *Client) createRequest(url string) (*http.Request, error) {
 	return req, nil
 }
 
-func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	c.checkRateLimit(resp)
-
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("HTTP ошибка %d: %s", resp.StatusCode, resp.Status)
-	}
-
-	return resp, nil
-}
-
-func (c *Client) checkRateLimit(resp *http.Response) {
-	remaining := resp.Header.Get("X-RateLimit-Remaining")
-	reset := resp.Header.Get("X-RateLimit-Reset")
-	limit := resp.Header.Get("X-RateLimit-Limit")
+// doRequest sends req with the pacing floor configured for owner/repo.
+func (c *Client) doRequest(ctx context.Context, req *http.Request, owner, repo string) (*http.Response, error) {
+	minInterval := time.Duration(c.config.Repository(owner, repo).DelayMS) * time.Millisecond
 
-	if remaining != "" {
-		fmt.Printf("Limit API: %s/%s requests, reset: %s\n", remaining, limit, reset)
-	}
+	return c.requester.do(ctx, req, minInterval)
 }
@@ -0,0 +1,30 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrRateLimited      = errors.New("rate limit exceeded")
+	ErrServerError      = errors.New("github server error")
+	ErrUnexpectedStatus = errors.New("unexpected http response status code")
+)
+
+// statusError maps a non-OK response status to one of the sentinel errors.
+func statusError(code int) error {
+	switch {
+	case code == http.StatusNotFound:
+		return ErrNotFound
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrUnauthorized
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code >= http.StatusInternalServerError:
+		return ErrServerError
+	default:
+		return ErrUnexpectedStatus
+	}
+}
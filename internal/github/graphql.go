@@ -0,0 +1,389 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"metrics-scrapper/internal/config"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nestedPageSize is how many reviews, comments and timeline events are fetched
+// together with each PR. Longer discussions are completed with follow-up
+// queries.
+const nestedPageSize = 100
+
+// GraphQLClient implements GitHubService on top of the GitHub GraphQL v4 API.
+// Reviews and comments arrive in the same query as the pull requests and are
+// kept in memory, so GetReviews and GetComments normally cost no requests.
+type GraphQLClient struct {
+	config    *config.Config
+	baseURL   string
+	endpoint  string
+	requester *requester
+
+	// details holds the reviews and comments of the last listing of each
+	// repository by PR number. A new listing replaces the previous one, so a
+	// long-running daemon keeps one listing per repository.
+	mu      sync.Mutex
+	details map[string]map[int]prDetails
+}
+
+type prDetails struct {
+	reviews  []Review
+	comments []IssueComment
+}
+
+func NewGraphQLClient(cfg *config.Config, transport http.RoundTripper) *GraphQLClient {
+	return &GraphQLClient{
+		config:    cfg,
+		baseURL:   cfg.GitHubAPIURL,
+		endpoint:  graphQLEndpoint(cfg.GitHubAPIURL),
+		requester: newRequester(cfg, transport),
+		details:   make(map[string]map[int]prDetails),
+	}
+}
+
+// graphQLEndpoint derives the GraphQL URL from the REST base URL:
+// https://api.github.com -> https://api.github.com/graphql and, for GitHub
+// Enterprise, https://host/api/v3 -> https://host/api/graphql.
+func graphQLEndpoint(restURL string) string {
+	if base, ok := strings.CutSuffix(restURL, "/v3"); ok {
+		return base + "/graphql"
+	}
+
+	return restURL + "/graphql"
+}
+
+func (c *GraphQLClient) GetAllPullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error) {
+	var (
+		allPRs []PullRequest
+		cursor *string
+	)
+
+	settings := c.config.Repository(owner, repo)
+	listing := make(map[int]prDetails)
+
+	for page := 1; ; page++ {
+		fmt.Printf("Page Request %d...\n", page)
+
+		var data struct {
+			Repository struct {
+				PullRequests struct {
+					PageInfo gqlPageInfo      `json:"pageInfo"`
+					Nodes    []gqlPullRequest `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"repository"`
+		}
+
+		err := c.query(ctx, pullRequestsQuery, map[string]any{
+			"owner":  owner,
+			"name":   repo,
+			"first":  settings.PerPage,
+			"after":  cursor,
+			"nested": nestedPageSize,
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		conn := data.Repository.PullRequests
+		reachedSince := false
+		received := 0
+
+		for _, node := range conn.Nodes {
+			if !since.IsZero() && !node.UpdatedAt.After(since) {
+				reachedSince = true
+				break
+			}
+
+			pr, details, err := c.collect(ctx, owner, repo, node)
+			if err != nil {
+				return nil, err
+			}
+
+			allPRs = append(allPRs, pr)
+			listing[pr.Number] = details
+			received++
+		}
+
+		fmt.Printf("Received %d PR from the page %d\n", received, page)
+
+		if reachedSince {
+			fmt.Printf("Reached PRs not updated since %s\n", since.Format(time.DateTime))
+			break
+		}
+
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+
+		if settings.MaxPages != config.Unlimited && page >= settings.MaxPages {
+			fmt.Printf("The page limit has been reached (%d)\n", settings.MaxPages)
+			break
+		}
+
+		cursor = &conn.PageInfo.EndCursor
+	}
+
+	c.mu.Lock()
+	c.details[owner+"/"+repo] = listing
+	c.mu.Unlock()
+
+	return allPRs, nil
+}
+
+// RateLimit returns the GraphQL API budget (points, not requests) reported by
+// the last response.
+func (c *GraphQLClient) RateLimit() RateLimitInfo {
+	return c.requester.rateLimit()
+}
+
+func (c *GraphQLClient) GetReviews(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	if details, ok := c.cached(owner, repo, prNumber); ok {
+		return details.reviews, nil
+	}
+
+	maxPages := c.config.Repository(owner, repo).MaxReviewPages
+
+	return c.fetchReviews(ctx, owner, repo, prNumber, nil, maxPages)
+}
+
+func (c *GraphQLClient) GetComments(ctx context.Context, owner, repo string, prNumber int) ([]IssueComment, error) {
+	if details, ok := c.cached(owner, repo, prNumber); ok {
+		return details.comments, nil
+	}
+
+	maxPages := c.config.Repository(owner, repo).MaxCommentPages
+
+	return c.fetchComments(ctx, owner, repo, prNumber, nil, maxPages)
+}
+
+// collect converts a PR node and completes its reviews and comments when the
+// discussion didn't fit into the first nested page, which counts towards the
+// max_review_pages and max_comment_pages limits.
+func (c *GraphQLClient) collect(ctx context.Context, owner, repo string, node gqlPullRequest) (PullRequest, prDetails, error) {
+	pr := node.toPullRequest(c.baseURL, owner, repo)
+	settings := c.config.Repository(owner, repo)
+
+	reviews := node.Reviews.toReviews()
+	if node.Reviews.PageInfo.HasNextPage && hasMorePages(settings.MaxReviewPages, 1) {
+		rest, err := c.fetchReviews(ctx, owner, repo, pr.Number, &node.Reviews.PageInfo.EndCursor, remainingPages(settings.MaxReviewPages, 1))
+		if err != nil {
+			return pr, prDetails{}, fmt.Errorf("reviews of PR #%d: %w", pr.Number, err)
+		}
+
+		reviews = append(reviews, rest...)
+	}
+
+	comments := node.Comments.toComments()
+	if node.Comments.PageInfo.HasNextPage && hasMorePages(settings.MaxCommentPages, 1) {
+		rest, err := c.fetchComments(ctx, owner, repo, pr.Number, &node.Comments.PageInfo.EndCursor, remainingPages(settings.MaxCommentPages, 1))
+		if err != nil {
+			return pr, prDetails{}, fmt.Errorf("comments of PR #%d: %w", pr.Number, err)
+		}
+
+		comments = append(comments, rest...)
+	}
+
+	return pr, prDetails{reviews: reviews, comments: comments}, nil
+}
+
+func (c *GraphQLClient) fetchReviews(ctx context.Context, owner, repo string, prNumber int, cursor *string, maxPages int) ([]Review, error) {
+	var reviews []Review
+
+	for page := 1; ; page++ {
+		var data struct {
+			Repository struct {
+				PullRequest struct {
+					Reviews gqlReviews `json:"reviews"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+
+		err := c.query(ctx, reviewsQuery, map[string]any{
+			"owner":  owner,
+			"name":   repo,
+			"number": prNumber,
+			"after":  cursor,
+			"nested": nestedPageSize,
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		conn := data.Repository.PullRequest.Reviews
+		reviews = append(reviews, conn.toReviews()...)
+
+		if !conn.PageInfo.HasNextPage || !hasMorePages(maxPages, page) {
+			return reviews, nil
+		}
+
+		cursor = &conn.PageInfo.EndCursor
+	}
+}
+
+func (c *GraphQLClient) fetchComments(ctx context.Context, owner, repo string, prNumber int, cursor *string, maxPages int) ([]IssueComment, error) {
+	var comments []IssueComment
+
+	for page := 1; ; page++ {
+		var data struct {
+			Repository struct {
+				PullRequest struct {
+					Comments gqlComments `json:"comments"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+
+		err := c.query(ctx, commentsQuery, map[string]any{
+			"owner":  owner,
+			"name":   repo,
+			"number": prNumber,
+			"after":  cursor,
+			"nested": nestedPageSize,
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		conn := data.Repository.PullRequest.Comments
+		comments = append(comments, conn.toComments()...)
+
+		if !conn.PageInfo.HasNextPage || !hasMorePages(maxPages, page) {
+			return comments, nil
+		}
+
+		cursor = &conn.PageInfo.EndCursor
+	}
+}
+
+func hasMorePages(maxPages, fetched int) bool {
+	return maxPages == config.Unlimited || fetched < maxPages
+}
+
+func remainingPages(maxPages, fetched int) int {
+	if maxPages == config.Unlimited {
+		return config.Unlimited
+	}
+
+	return maxPages - fetched
+}
+
+func (c *GraphQLClient) cached(owner, repo string, prNumber int) (prDetails, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	details, ok := c.details[owner+"/"+repo][prNumber]
+
+	return details, ok
+}
+
+type gqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+type gqlError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (r gqlResponse) rateLimited() bool {
+	return r.hasError("RATE_LIMITED")
+}
+
+func (r gqlResponse) notFound() bool {
+	return r.hasError("NOT_FOUND")
+}
+
+func (r gqlResponse) hasError(errorType string) bool {
+	for _, e := range r.Errors {
+		if e.Type == errorType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// query sends a GraphQL query and decodes its "data" object into data. Rate
+// limits reported as a RATE_LIMITED error in the body pause the client and
+// re-send the query; everything else is handled by the requester.
+func (c *GraphQLClient) query(ctx context.Context, query string, variables map[string]any, data any) error {
+	body, err := json.Marshal(gqlRequest{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("encoding graphql request: %v", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.send(ctx, body)
+		if err != nil {
+			return err
+		}
+
+		if response.rateLimited() {
+			if attempt >= maxRateLimitWaits {
+				return fmt.Errorf("%w: graphql", ErrRateLimited)
+			}
+
+			c.requester.limiter.pauseUntilReset()
+			fmt.Printf("Rate limit exceeded, pausing until %s\n", c.requester.limiter.pausedTill().Format(time.TimeOnly))
+
+			continue
+		}
+
+		if len(response.Errors) > 0 {
+			messages := make([]string, 0, len(response.Errors))
+			for _, e := range response.Errors {
+				messages = append(messages, e.Message)
+			}
+
+			if response.notFound() {
+				return fmt.Errorf("%w: %s", ErrNotFound, strings.Join(messages, "; "))
+			}
+
+			return fmt.Errorf("graphql error: %s", strings.Join(messages, "; "))
+		}
+
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			return fmt.Errorf("parsing JSON error: %v", err)
+		}
+
+		return nil
+	}
+}
+
+func (c *GraphQLClient) send(ctx context.Context, body []byte) (gqlResponse, error) {
+	var response gqlResponse
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return response, fmt.Errorf("request creation error: %v", err)
+	}
+
+	req.Header.Set("User-Agent", "GoFish-Analyzer-1.0")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+c.config.GitHubToken)
+
+	// GraphQL costs vary per query, so the budget alone sets the pace.
+	resp, err := c.requester.do(ctx, req, 0)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return response, fmt.Errorf("parsing JSON error: %v", err)
+	}
+
+	return response, nil
+}
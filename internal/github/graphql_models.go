@@ -0,0 +1,216 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const actorFields = `login ... on User { databaseId }`
+
+const reviewFields = `
+	pageInfo { hasNextPage endCursor }
+	nodes { databaseId state submittedAt author { ` + actorFields + ` } }`
+
+const commentFields = `
+	pageInfo { hasNextPage endCursor }
+	nodes { databaseId createdAt updatedAt author { ` + actorFields + ` } }`
+
+const pullRequestsQuery = `
+query($owner: String!, $name: String!, $first: Int!, $after: String, $nested: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(first: $first, after: $after, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId number state title url
+        createdAt updatedAt closedAt mergedAt
+        additions deletions changedFiles
+        labels(first: $nested) { nodes { name } }
+        author { ` + actorFields + ` }
+        reviews(first: $nested) { ` + reviewFields + ` }
+        comments(first: $nested) { ` + commentFields + ` }
+        timelineItems(first: $nested, itemTypes: [READY_FOR_REVIEW_EVENT, REVIEW_REQUESTED_EVENT, CONVERT_TO_DRAFT_EVENT, REOPENED_EVENT]) {
+          nodes {
+            __typename
+            ... on ReadyForReviewEvent { createdAt actor { ` + actorFields + ` } }
+            ... on ReviewRequestedEvent { createdAt actor { ` + actorFields + ` } }
+            ... on ConvertToDraftEvent { createdAt actor { ` + actorFields + ` } }
+            ... on ReopenedEvent { createdAt actor { ` + actorFields + ` } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const reviewsQuery = `
+query($owner: String!, $name: String!, $number: Int!, $after: String, $nested: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviews(first: $nested, after: $after) { ` + reviewFields + ` }
+    }
+  }
+}`
+
+const commentsQuery = `
+query($owner: String!, $name: String!, $number: Int!, $after: String, $nested: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      comments(first: $nested, after: $after) { ` + commentFields + ` }
+    }
+  }
+}`
+
+type gqlPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// gqlActor is nil for deleted accounts, which the REST API reports as "ghost".
+type gqlActor struct {
+	Login      string `json:"login"`
+	DatabaseID int    `json:"databaseId"`
+}
+
+func (a *gqlActor) toUser() User {
+	if a == nil {
+		return User{Login: "ghost"}
+	}
+
+	return User{ID: a.DatabaseID, Login: a.Login}
+}
+
+type gqlPullRequest struct {
+	DatabaseID   int        `json:"databaseId"`
+	Number       int        `json:"number"`
+	State        string     `json:"state"`
+	Title        string     `json:"title"`
+	URL          string     `json:"url"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	ClosedAt     *time.Time `json:"closedAt"`
+	MergedAt     *time.Time `json:"mergedAt"`
+	Additions    int        `json:"additions"`
+	Deletions    int        `json:"deletions"`
+	ChangedFiles int        `json:"changedFiles"`
+	Labels       struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	Author        *gqlActor   `json:"author"`
+	Reviews       gqlReviews  `json:"reviews"`
+	Comments      gqlComments `json:"comments"`
+	TimelineItems struct {
+		Nodes []gqlTimelineItem `json:"nodes"`
+	} `json:"timelineItems"`
+}
+
+// toPullRequest maps the node onto the REST model. GraphQL reports merged PRs
+// with a separate MERGED state, REST reports them as "closed".
+func (p gqlPullRequest) toPullRequest(baseURL, owner, repo string) PullRequest {
+	state := strings.ToLower(p.State)
+	if state == "merged" {
+		state = "closed"
+	}
+
+	pr := PullRequest{
+		ID:           p.DatabaseID,
+		Number:       p.Number,
+		State:        state,
+		Title:        p.Title,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+		ClosedAt:     p.ClosedAt,
+		MergedAt:     p.MergedAt,
+		User:         p.Author.toUser(),
+		URL:          fmt.Sprintf("%s/repos/%s/%s/pulls/%d", baseURL, owner, repo, p.Number),
+		HTMLURL:      p.URL,
+		Additions:    p.Additions,
+		Deletions:    p.Deletions,
+		ChangedFiles: p.ChangedFiles,
+		Labels:       p.Labels.Nodes,
+	}
+
+	for _, item := range p.TimelineItems.Nodes {
+		pr.Timeline = append(pr.Timeline, TimelineEvent{
+			Type:      timelineEventType(item.Typename),
+			Actor:     item.Actor.toUser(),
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	return pr
+}
+
+type gqlReviews struct {
+	PageInfo gqlPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		DatabaseID  int        `json:"databaseId"`
+		State       string     `json:"state"`
+		SubmittedAt *time.Time `json:"submittedAt"`
+		Author      *gqlActor  `json:"author"`
+	} `json:"nodes"`
+}
+
+func (r gqlReviews) toReviews() []Review {
+	reviews := make([]Review, 0, len(r.Nodes))
+
+	for _, node := range r.Nodes {
+		reviews = append(reviews, Review{
+			ID:          node.DatabaseID,
+			User:        node.Author.toUser(),
+			State:       node.State,
+			SubmittedAt: node.SubmittedAt,
+		})
+	}
+
+	return reviews
+}
+
+type gqlComments struct {
+	PageInfo gqlPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		DatabaseID int       `json:"databaseId"`
+		CreatedAt  time.Time `json:"createdAt"`
+		UpdatedAt  time.Time `json:"updatedAt"`
+		Author     *gqlActor `json:"author"`
+	} `json:"nodes"`
+}
+
+func (c gqlComments) toComments() []IssueComment {
+	comments := make([]IssueComment, 0, len(c.Nodes))
+
+	for _, node := range c.Nodes {
+		comments = append(comments, IssueComment{
+			ID:        node.DatabaseID,
+			User:      node.Author.toUser(),
+			CreatedAt: node.CreatedAt,
+			UpdatedAt: node.UpdatedAt,
+		})
+	}
+
+	return comments
+}
+
+type gqlTimelineItem struct {
+	Typename  string    `json:"__typename"` //nolint:tagliatelle
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     *gqlActor `json:"actor"`
+}
+
+// timelineEventType converts a GraphQL type name to the REST timeline event
+// name, e.g. ReadyForReviewEvent -> ready_for_review.
+func timelineEventType(typename string) string {
+	name := strings.TrimSuffix(typename, "Event")
+
+	var b strings.Builder
+
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return strings.ToLower(b.String())
+}
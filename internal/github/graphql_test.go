@@ -0,0 +1,382 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/config"
+)
+
+// graphQLStub answers the queries of GraphQLClient with canned "data"
+// objects, keyed by the query and the "after" cursor ("" for the first page),
+// and records the cursors it was asked for.
+type graphQLStub struct {
+	pages map[string]map[string]string
+
+	mu      sync.Mutex
+	cursors map[string][]string
+}
+
+func newGraphQLStub(t *testing.T, pages map[string]map[string]string) (*graphQLStub, *GraphQLClient) {
+	t.Helper()
+
+	stub := &graphQLStub{pages: pages, cursors: make(map[string][]string)} //nolint:exhaustruct
+
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{ //nolint:exhaustruct
+		GitHubAPIURL:    server.URL,
+		MaxPages:        config.Unlimited,
+		MaxReviewPages:  config.Unlimited,
+		MaxCommentPages: config.Unlimited,
+		PerPage:         2,
+	}
+
+	return stub, NewGraphQLClient(cfg, http.DefaultTransport)
+}
+
+func (s *graphQLStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req gqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := map[string]string{
+		pullRequestsQuery: "pullRequests",
+		reviewsQuery:      "reviews",
+		commentsQuery:     "comments",
+	}[req.Query]
+
+	cursor, _ := req.Variables["after"].(string)
+
+	s.mu.Lock()
+	s.cursors[name] = append(s.cursors[name], cursor)
+	s.mu.Unlock()
+
+	data, ok := s.pages[name][cursor]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unexpected %s query after %q", name, cursor), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"data": %s}`, data)
+}
+
+func (s *graphQLStub) requested(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cursors[name]
+}
+
+// pullRequestsPage is a page of the PR listing holding nodes.
+func pullRequestsPage(hasNextPage bool, endCursor string, nodes ...string) string {
+	return fmt.Sprintf(`{"repository": {"pullRequests": {
+		"pageInfo": {"hasNextPage": %t, "endCursor": %q},
+		"nodes": [%s]}}}`, hasNextPage, endCursor, strings.Join(nodes, ","))
+}
+
+// pullRequestNode is a PR node with the given nested review and comment
+// connections.
+func pullRequestNode(number int, state, author, reviews, comments string) string {
+	return fmt.Sprintf(`{
+		"databaseId": %d, "number": %d, "state": %q, "title": "PR %d",
+		"url": "https://github.com/o/r/pull/%d",
+		"createdAt": "2024-01-01T00:00:00Z", "updatedAt": "2024-01-02T00:00:00Z",
+		"author": %s,
+		"labels": {"nodes": []},
+		"reviews": %s,
+		"comments": %s,
+		"timelineItems": {"nodes": []}}`,
+		number*10, number, state, number, number, author, reviews, comments)
+}
+
+func reviewsConnection(hasNextPage bool, endCursor string, ids ...int) string {
+	nodes := make([]string, len(ids))
+	for i, id := range ids {
+		nodes[i] = fmt.Sprintf(`{"databaseId": %d, "state": "COMMENTED",
+			"submittedAt": "2024-01-01T12:00:00Z", "author": {"login": "reviewer"}}`, id)
+	}
+
+	return fmt.Sprintf(`{"pageInfo": {"hasNextPage": %t, "endCursor": %q}, "nodes": [%s]}`,
+		hasNextPage, endCursor, strings.Join(nodes, ","))
+}
+
+func commentsConnection(hasNextPage bool, endCursor string, ids ...int) string {
+	nodes := make([]string, len(ids))
+	for i, id := range ids {
+		nodes[i] = fmt.Sprintf(`{"databaseId": %d, "createdAt": "2024-01-01T06:00:00Z",
+			"updatedAt": "2024-01-01T06:00:00Z", "author": {"login": "commenter"}}`, id)
+	}
+
+	return fmt.Sprintf(`{"pageInfo": {"hasNextPage": %t, "endCursor": %q}, "nodes": [%s]}`,
+		hasNextPage, endCursor, strings.Join(nodes, ","))
+}
+
+var (
+	noReviews  = reviewsConnection(false, "")
+	noComments = commentsConnection(false, "")
+	author     = `{"login": "alice", "databaseId": 7}`
+)
+
+func TestGraphQLPullRequestPagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxPages int
+		want     []int
+		cursors  []string
+	}{
+		{name: "follows endCursor until the last page", maxPages: config.Unlimited, want: []int{3, 2, 1}, cursors: []string{"", "c1"}},
+		{name: "stops at max_pages", maxPages: 1, want: []int{3, 2}, cursors: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newGraphQLStub(t, map[string]map[string]string{
+				"pullRequests": {
+					"": pullRequestsPage(true, "c1",
+						pullRequestNode(3, "OPEN", author, noReviews, noComments),
+						pullRequestNode(2, "OPEN", author, noReviews, noComments)),
+					"c1": pullRequestsPage(false, "c2",
+						pullRequestNode(1, "OPEN", author, noReviews, noComments)),
+				},
+			})
+			client.config.MaxPages = tt.maxPages
+
+			prs, err := client.GetAllPullRequests(context.Background(), "o", "r", time.Time{})
+			if err != nil {
+				t.Fatalf("GetAllPullRequests: %v", err)
+			}
+
+			var got []int
+			for _, pr := range prs {
+				got = append(got, pr.Number)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got PRs %v, want %v", got, tt.want)
+			}
+
+			if cursors := stub.requested("pullRequests"); !slices.Equal(cursors, tt.cursors) {
+				t.Errorf("requested cursors %q, want %q", cursors, tt.cursors)
+			}
+		})
+	}
+}
+
+func TestGraphQLNestedFollowUp(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxPages       int
+		wantReviews    []int
+		wantComments   []int
+		reviewCursors  []string
+		commentCursors []string
+	}{
+		{
+			name:           "fetches the remaining pages",
+			maxPages:       config.Unlimited,
+			wantReviews:    []int{1, 2, 3},
+			wantComments:   []int{11, 12},
+			reviewCursors:  []string{"r1", "r2"},
+			commentCursors: []string{"k1"},
+		},
+		{
+			name:           "nested page counts towards the limit",
+			maxPages:       2,
+			wantReviews:    []int{1, 2},
+			wantComments:   []int{11, 12},
+			reviewCursors:  []string{"r1"},
+			commentCursors: []string{"k1"},
+		},
+		{
+			name:         "no follow-up with a single page allowed",
+			maxPages:     1,
+			wantReviews:  []int{1},
+			wantComments: []int{11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newGraphQLStub(t, map[string]map[string]string{
+				"pullRequests": {
+					"": pullRequestsPage(false, "",
+						pullRequestNode(1, "OPEN", author, reviewsConnection(true, "r1", 1), commentsConnection(true, "k1", 11))),
+				},
+				"reviews": {
+					"r1": `{"repository": {"pullRequest": {"reviews": ` + reviewsConnection(true, "r2", 2) + `}}}`,
+					"r2": `{"repository": {"pullRequest": {"reviews": ` + reviewsConnection(false, "r3", 3) + `}}}`,
+				},
+				"comments": {
+					"k1": `{"repository": {"pullRequest": {"comments": ` + commentsConnection(false, "k2", 12) + `}}}`,
+				},
+			})
+			client.config.MaxReviewPages = tt.maxPages
+			client.config.MaxCommentPages = tt.maxPages
+
+			ctx := context.Background()
+
+			if _, err := client.GetAllPullRequests(ctx, "o", "r", time.Time{}); err != nil {
+				t.Fatalf("GetAllPullRequests: %v", err)
+			}
+
+			reviews, err := client.GetReviews(ctx, "o", "r", 1)
+			if err != nil {
+				t.Fatalf("GetReviews: %v", err)
+			}
+
+			comments, err := client.GetComments(ctx, "o", "r", 1)
+			if err != nil {
+				t.Fatalf("GetComments: %v", err)
+			}
+
+			var gotReviews, gotComments []int
+			for _, r := range reviews {
+				gotReviews = append(gotReviews, r.ID)
+			}
+
+			for _, c := range comments {
+				gotComments = append(gotComments, c.ID)
+			}
+
+			if !slices.Equal(gotReviews, tt.wantReviews) {
+				t.Errorf("got reviews %v, want %v", gotReviews, tt.wantReviews)
+			}
+
+			if !slices.Equal(gotComments, tt.wantComments) {
+				t.Errorf("got comments %v, want %v", gotComments, tt.wantComments)
+			}
+
+			// Both were collected with the PR, so reading them costs nothing.
+			if cursors := stub.requested("reviews"); !slices.Equal(cursors, tt.reviewCursors) {
+				t.Errorf("requested review cursors %q, want %q", cursors, tt.reviewCursors)
+			}
+
+			if cursors := stub.requested("comments"); !slices.Equal(cursors, tt.commentCursors) {
+				t.Errorf("requested comment cursors %q, want %q", cursors, tt.commentCursors)
+			}
+		})
+	}
+}
+
+func TestGraphQLPullRequestMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      string
+		author     string
+		wantState  string
+		wantAuthor User
+	}{
+		{name: "open", state: "OPEN", author: author, wantState: "open", wantAuthor: User{ID: 7, Login: "alice"}},
+		{name: "closed", state: "CLOSED", author: author, wantState: "closed", wantAuthor: User{ID: 7, Login: "alice"}},
+		{name: "merged is closed", state: "MERGED", author: author, wantState: "closed", wantAuthor: User{ID: 7, Login: "alice"}},
+		{name: "deleted author is ghost", state: "OPEN", author: "null", wantState: "open", wantAuthor: User{ID: 0, Login: "ghost"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newGraphQLStub(t, map[string]map[string]string{
+				"pullRequests": {
+					"": pullRequestsPage(false, "", pullRequestNode(1, tt.state, tt.author, noReviews, noComments)),
+				},
+			})
+
+			prs, err := client.GetAllPullRequests(context.Background(), "o", "r", time.Time{})
+			if err != nil {
+				t.Fatalf("GetAllPullRequests: %v", err)
+			}
+
+			if len(prs) != 1 {
+				t.Fatalf("got %d PRs, want 1", len(prs))
+			}
+
+			if prs[0].State != tt.wantState {
+				t.Errorf("got state %q, want %q", prs[0].State, tt.wantState)
+			}
+
+			if prs[0].User != tt.wantAuthor {
+				t.Errorf("got author %+v, want %+v", prs[0].User, tt.wantAuthor)
+			}
+		})
+	}
+}
+
+func TestGraphQLDetailsPerListing(t *testing.T) {
+	tests := []struct {
+		name string
+		// list runs after the first listing of o/r.
+		list          func(ctx context.Context, client *GraphQLClient) error
+		reviewCursors []string
+	}{
+		{
+			name:          "first listing serves the reviews",
+			list:          func(context.Context, *GraphQLClient) error { return nil },
+			reviewCursors: nil,
+		},
+		{
+			name: "listing another repository keeps them",
+			list: func(ctx context.Context, client *GraphQLClient) error {
+				_, err := client.GetAllPullRequests(ctx, "o", "other", time.Time{})
+				return err
+			},
+			reviewCursors: nil,
+		},
+		{
+			name: "relisting the repository replaces them",
+			list: func(ctx context.Context, client *GraphQLClient) error {
+				_, err := client.GetAllPullRequests(ctx, "o", "r", date(2))
+				return err
+			},
+			reviewCursors: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newGraphQLStub(t, map[string]map[string]string{
+				"pullRequests": {
+					"": pullRequestsPage(false, "",
+						pullRequestNode(1, "OPEN", author, reviewsConnection(false, "", 1), noComments)),
+				},
+				"reviews": {
+					"": `{"repository": {"pullRequest": {"reviews": ` + reviewsConnection(false, "", 2) + `}}}`,
+				},
+			})
+
+			ctx := context.Background()
+
+			if _, err := client.GetAllPullRequests(ctx, "o", "r", time.Time{}); err != nil {
+				t.Fatalf("GetAllPullRequests: %v", err)
+			}
+
+			if err := tt.list(ctx, client); err != nil {
+				t.Fatalf("GetAllPullRequests: %v", err)
+			}
+
+			reviews, err := client.GetReviews(ctx, "o", "r", 1)
+			if err != nil {
+				t.Fatalf("GetReviews: %v", err)
+			}
+
+			if len(reviews) != 1 {
+				t.Fatalf("got %d reviews, want 1", len(reviews))
+			}
+
+			if cursors := stub.requested("reviews"); !slices.Equal(cursors, tt.reviewCursors) {
+				t.Errorf("requested review cursors %q, want %q", cursors, tt.reviewCursors)
+			}
+		})
+	}
+}
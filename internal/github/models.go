@@ -14,6 +14,28 @@ type PullRequest struct {
 	User      User       `json:"user"`
 	URL       string     `json:"url"`
 	HTMLURL   string     `json:"html_url"`
+
+	// Size fields are filled by the GraphQL backend and by the REST
+	// single-PR endpoint (Client.GetPullRequest); the REST list endpoint
+	// leaves them zero.
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	ChangedFiles int `json:"changed_files"`
+
+	Labels []Label `json:"labels"`
+
+	// Timeline is only filled by the GraphQL backend.
+	Timeline []TimelineEvent `json:"timeline,omitempty"`
+}
+
+type TimelineEvent struct {
+	Type      string    `json:"type"`
+	Actor     User      `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Label struct {
+	Name string `json:"name"`
 }
 
 type User struct {
@@ -34,9 +56,3 @@ type IssueComment struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
-
-type RateLimitInfo struct {
-	Remaining string
-	Reset     string
-	Limit     string
-}
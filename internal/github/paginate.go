@@ -0,0 +1,77 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"metrics-scrapper/internal/config"
+	"net/http"
+	"strings"
+)
+
+// detailsPerPage is the page size for reviews and comments, the maximum
+// GitHub allows.
+const detailsPerPage = 100
+
+// paginate requests url and follows the Link rel="next" header, decoding each
+// page as a JSON array of T and passing it to handle. It stops when there is
+// no next page, after maxPages pages (config.Unlimited for no limit) or when
+// handle returns false.
+func paginate[T any](
+	ctx context.Context,
+	c *Client,
+	owner, repo, url string,
+	maxPages int,
+	handle func(page int, items []T) bool,
+) error {
+	for page := 1; url != ""; page++ {
+		if maxPages != config.Unlimited && page > maxPages {
+			fmt.Printf("The page limit has been reached (%d)\n", maxPages)
+			return nil
+		}
+
+		req, err := c.createRequest(url)
+		if err != nil {
+			return fmt.Errorf("request creation error: %v", err)
+		}
+
+		resp, err := c.doRequest(ctx, req, owner, repo)
+		if err != nil {
+			return err
+		}
+
+		var items []T
+		err = json.NewDecoder(resp.Body).Decode(&items)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("parsing JSON error: %v", err)
+		}
+
+		if !handle(page, items) {
+			return nil
+		}
+
+		url = nextPageURL(resp.Header)
+	}
+
+	return nil
+}
+
+// nextPageURL extracts the rel="next" target from a Link header such as
+// `<https://api.github.com/...&page=2>; rel="next", <...&page=5>; rel="last"`.
+func nextPageURL(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, found := strings.Cut(link, ";")
+		if !found {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"metrics-scrapper/internal/config"
+)
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{name: "no header", link: "", want: ""},
+		{
+			name: "next and last",
+			link: `<https://api.github.com/repos/o/r/pulls?page=2>; rel="next", <https://api.github.com/repos/o/r/pulls?page=5>; rel="last"`,
+			want: "https://api.github.com/repos/o/r/pulls?page=2",
+		},
+		{
+			name: "next after prev and first",
+			link: `<https://x/?page=1>; rel="first", <https://x/?page=2>; rel="prev", <https://x/?page=4>; rel="next"`,
+			want: "https://x/?page=4",
+		},
+		{
+			name: "last page has no next",
+			link: `<https://x/?page=1>; rel="first", <https://x/?page=4>; rel="prev"`,
+			want: "",
+		},
+		{
+			name: "extra parameters and spacing",
+			link: ` <https://x/?page=3&per_page=100> ;  type="json" ; rel="next" `,
+			want: "https://x/?page=3&per_page=100",
+		},
+		{name: "unquoted rel is not matched", link: `<https://x/?page=2>; rel=next`, want: ""},
+		{name: "malformed link", link: `https://x/?page=2`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.link != "" {
+				header.Set("Link", tt.link)
+			}
+
+			if got := nextPageURL(header); got != tt.want {
+				t.Errorf("nextPageURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// numberedPages serves pages 1 to pages, page n holding the single item n and
+// linking to page n+1 when there is one.
+func numberedPages(t *testing.T, pages int) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			page = 1
+		}
+
+		if page < pages {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=%d>; rel="next"`, server.URL, page+1))
+		}
+
+		fmt.Fprintf(w, "[%d]", page)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pages    int
+		maxPages int
+		stopAt   int
+		want     []int
+	}{
+		{name: "follows every next link", pages: 4, maxPages: config.Unlimited, want: []int{1, 2, 3, 4}},
+		{name: "single page", pages: 1, maxPages: config.Unlimited, want: []int{1}},
+		{name: "stops at max pages", pages: 4, maxPages: 2, want: []int{1, 2}},
+		{name: "max pages above the total", pages: 3, maxPages: 10, want: []int{1, 2, 3}},
+		{name: "handler stops early", pages: 4, maxPages: config.Unlimited, stopAt: 3, want: []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := numberedPages(t, tt.pages)
+
+			client := NewClient(&config.Config{GitHubAPIURL: server.URL}, http.DefaultTransport) //nolint:exhaustruct
+
+			var got []int
+
+			err := paginate(context.Background(), client, "o", "r", server.URL+"/items", tt.maxPages,
+				func(page int, items []int) bool {
+					if len(items) != 1 || items[0] != page {
+						t.Errorf("page %d: got items %v", page, items)
+					}
+
+					got = append(got, items...)
+
+					return page != tt.stopAt
+				})
+			if err != nil {
+				t.Fatalf("paginate: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got pages %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
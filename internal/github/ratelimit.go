@@ -0,0 +1,190 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secondaryLimitPause is how long to back off after a secondary rate limit
+// response without Retry-After, as recommended by the GitHub docs.
+const secondaryLimitPause = time.Minute
+
+// resetSlack is added to X-RateLimit-Reset to absorb clock skew.
+const resetSlack = time.Second
+
+// maxRateLimitWaits bounds how many times a single request is re-sent after
+// being rejected by a rate limit.
+const maxRateLimitWaits = 3
+
+// RateLimitInfo is the API budget reported by the most recent response.
+type RateLimitInfo struct {
+	Remaining int
+	Limit     int
+	Reset     time.Time
+}
+
+// rateLimiter schedules requests so that the remaining budget is spent evenly
+// until the reset time, and pauses all requests once the budget is exhausted
+// or GitHub asks to back off. It is safe for concurrent use.
+type rateLimiter struct {
+	mu          sync.Mutex
+	budget      RateLimitInfo
+	known       bool
+	pausedUntil time.Time
+	nextSlot    time.Time
+}
+
+// wait blocks until the next request may be sent or ctx is done. minInterval
+// is the lower bound between two requests, even when the budget allows
+// a faster pace.
+func (r *rateLimiter) wait(ctx context.Context, minInterval time.Duration) error {
+	r.mu.Lock()
+
+	now := time.Now()
+
+	slot := r.nextSlot
+	if slot.Before(now) {
+		slot = now
+	}
+
+	if r.pausedUntil.After(slot) {
+		slot = r.pausedUntil
+	}
+
+	if r.known && r.budget.Remaining <= 0 && r.budget.Reset.After(slot) {
+		slot = r.budget.Reset.Add(resetSlack)
+	}
+
+	r.nextSlot = slot.Add(r.interval(minInterval, slot))
+
+	if r.known && r.budget.Remaining > 0 {
+		r.budget.Remaining--
+	}
+
+	r.mu.Unlock()
+
+	return sleep(ctx, time.Until(slot))
+}
+
+// interval spreads the remaining budget over the time left until reset.
+func (r *rateLimiter) interval(minInterval time.Duration, at time.Time) time.Duration {
+	if !r.known {
+		return minInterval
+	}
+
+	untilReset := r.budget.Reset.Sub(at)
+	if untilReset <= 0 || r.budget.Remaining <= 0 {
+		return minInterval
+	}
+
+	return max(minInterval, untilReset/time.Duration(r.budget.Remaining))
+}
+
+// observe records the budget reported by resp and reports whether the request
+// was rejected by a primary or secondary rate limit. For rejected requests it
+// schedules a pause and consumes the response body.
+func (r *rateLimiter) observe(resp *http.Response) bool {
+	limited := isRateLimited(resp)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if budget, ok := parseRateLimit(resp.Header); ok {
+		r.budget = budget
+		r.known = true
+	}
+
+	if !limited {
+		return false
+	}
+
+	r.pause(retryAfter(resp.Header, r.budget))
+
+	return true
+}
+
+// pauseUntilReset stops all requests until the current budget resets. Used
+// when the limit is reported in a response body rather than a status code.
+func (r *rateLimiter) pauseUntilReset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pause(time.Until(r.budget.Reset) + resetSlack)
+}
+
+func (r *rateLimiter) pause(d time.Duration) {
+	if d <= 0 {
+		d = secondaryLimitPause
+	}
+
+	if until := time.Now().Add(d); until.After(r.pausedUntil) {
+		r.pausedUntil = until
+	}
+}
+
+func (r *rateLimiter) pausedTill() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.pausedUntil
+}
+
+func (r *rateLimiter) current() RateLimitInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.budget
+}
+
+func parseRateLimit(header http.Header) (RateLimitInfo, bool) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimitInfo{}, false
+	}
+
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	return RateLimitInfo{
+		Remaining: remaining,
+		Limit:     limit,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
+
+// isRateLimited recognizes both primary (exhausted budget) and secondary
+// (abuse detection) limits. GitHub reports either as 403 or 429.
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Header.Get("Retry-After") != "" ||
+		resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	return strings.Contains(strings.ToLower(string(body)), "rate limit")
+}
+
+// retryAfter prefers the server's Retry-After, then the budget reset time.
+// Zero means "unknown" and results in secondaryLimitPause.
+func retryAfter(header http.Header, budget RateLimitInfo) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if budget.Remaining == 0 && !budget.Reset.IsZero() {
+		return time.Until(budget.Reset) + resetSlack
+	}
+
+	return 0
+}
@@ -0,0 +1,111 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// requester sends GitHub API requests on behalf of both backends: it paces
+// them through the rate limiter, waits out rate limit rejections and retries
+// transient failures according to the retry policy.
+type requester struct {
+	httpClient *http.Client
+	limiter    rateLimiter
+	policy     retryPolicy
+	unpaced    bool
+}
+
+// do sends req and returns the response if its status is 200 OK. Otherwise the
+// error wraps one of ErrNotFound, ErrUnauthorized, ErrRateLimited,
+// ErrServerError or ErrUnexpectedStatus, or the transport error.
+func (r *requester) do(ctx context.Context, req *http.Request, minInterval time.Duration) (*http.Response, error) {
+	req = req.WithContext(ctx)
+
+	if r.unpaced {
+		minInterval = 0
+	}
+
+	rateLimitWaits := 0
+
+	for attempt := 0; ; {
+		if err := r.limiter.wait(ctx, minInterval); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
+			}
+
+			req.Body = body
+		}
+
+		resp, err := r.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || !r.policy.canRetry(attempt) {
+				return nil, err
+			}
+
+			if err := r.retryAfter(ctx, attempt, err); err != nil {
+				return nil, err
+			}
+
+			attempt++
+
+			continue
+		}
+
+		if r.limiter.observe(resp) {
+			resp.Body.Close()
+
+			if rateLimitWaits >= maxRateLimitWaits {
+				return nil, fmt.Errorf("%w: %s", ErrRateLimited, req.URL)
+			}
+
+			rateLimitWaits++
+
+			fmt.Printf("Rate limit exceeded, pausing until %s\n", r.limiter.pausedTill().Format(time.TimeOnly))
+
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+
+		err = fmt.Errorf("%w: %s: %s", statusError(resp.StatusCode), req.URL, resp.Status)
+
+		if !r.policy.retryable[resp.StatusCode] || !r.policy.canRetry(attempt) {
+			return nil, err
+		}
+
+		if err := r.retryAfter(ctx, attempt, err); err != nil {
+			return nil, err
+		}
+
+		attempt++
+	}
+}
+
+func (r *requester) retryAfter(ctx context.Context, attempt int, cause error) error {
+	delay := r.policy.backoff(attempt)
+
+	fmt.Printf("Request failed (%v), retry %d/%d in %v\n",
+		cause, attempt+1, r.policy.maxAttempts-1, delay.Round(time.Millisecond))
+
+	if err := sleep(ctx, delay); err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return nil
+}
+
+func (r *requester) rateLimit() RateLimitInfo {
+	return r.limiter.current()
+}
@@ -0,0 +1,68 @@
+package github
+
+import (
+	"context"
+	"math/rand/v2"
+	"metrics-scrapper/internal/config"
+	"time"
+)
+
+// retryPolicy decides whether and when a failed request is re-sent. Rate limit
+// rejections are not retries: they wait for the limiter and are bounded by
+// maxRateLimitWaits instead.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+	retryable   map[int]bool
+}
+
+func newRetryPolicy(cfg config.RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: cfg.MaxAttempts,
+		baseDelay:   time.Duration(cfg.BaseDelayMS) * time.Millisecond,
+		maxDelay:    time.Duration(cfg.MaxDelayMS) * time.Millisecond,
+		jitter:      cfg.Jitter,
+		retryable:   make(map[int]bool, len(cfg.RetryableStatusCodes)),
+	}
+
+	for _, code := range cfg.RetryableStatusCodes {
+		policy.retryable[code] = true
+	}
+
+	return policy
+}
+
+func (p retryPolicy) canRetry(attempt int) bool {
+	return attempt+1 < p.maxAttempts
+}
+
+// backoff returns the delay before the retry following attempt (0-based):
+// exponential growth capped at maxDelay, with up to jitter of it randomly
+// subtracted so that parallel clients don't retry in lockstep.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.maxDelay
+	if attempt < 32 {
+		delay = min(p.maxDelay, p.baseDelay<<attempt)
+	}
+
+	return delay - time.Duration(p.jitter*rand.Float64()*float64(delay))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
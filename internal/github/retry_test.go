@@ -0,0 +1,243 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := retryPolicy{ //nolint:exhaustruct
+		baseDelay: 100 * time.Millisecond,
+		maxDelay:  time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 100 * time.Millisecond},
+		{attempt: 1, want: 200 * time.Millisecond},
+		{attempt: 2, want: 400 * time.Millisecond},
+		{attempt: 3, want: 800 * time.Millisecond},
+		{attempt: 4, want: time.Second},
+		{attempt: 31, want: time.Second},
+		{attempt: 64, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			if got := policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := retryPolicy{ //nolint:exhaustruct
+		baseDelay: 100 * time.Millisecond,
+		maxDelay:  time.Second,
+		jitter:    0.5,
+	}
+
+	for attempt := range 6 {
+		full := min(time.Second, 100*time.Millisecond<<attempt)
+
+		for range 100 {
+			if got := policy.backoff(attempt); got < full/2 || got > full {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, got, full/2, full)
+			}
+		}
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   bool
+	}{
+		{name: "429", status: http.StatusTooManyRequests, want: true},
+		{name: "403 with Retry-After", status: http.StatusForbidden, header: http.Header{"Retry-After": {"30"}}, want: true},
+		{name: "403 with exhausted budget", status: http.StatusForbidden, header: http.Header{"X-Ratelimit-Remaining": {"0"}}, want: true},
+		{name: "403 secondary limit in body", status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit"}`, want: true},
+		{name: "403 without a limit", status: http.StatusForbidden, body: `{"message": "Resource not accessible"}`, want: false},
+		{name: "500", status: http.StatusInternalServerError, header: http.Header{"Retry-After": {"30"}}, want: false},
+		{name: "200", status: http.StatusOK, header: http.Header{"X-Ratelimit-Remaining": {"0"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			resp := &http.Response{ //nolint:exhaustruct
+				StatusCode: tt.status,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			if got := isRateLimited(resp); got != tt.want {
+				t.Errorf("isRateLimited() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	reset := time.Now().Add(10 * time.Minute)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		budget RateLimitInfo
+		want   time.Duration
+	}{
+		{
+			name:   "Retry-After wins",
+			header: http.Header{"Retry-After": {"30"}},
+			budget: RateLimitInfo{Remaining: 0, Limit: 5000, Reset: reset},
+			want:   30 * time.Second,
+		},
+		{
+			name:   "exhausted budget waits for the reset",
+			header: http.Header{},
+			budget: RateLimitInfo{Remaining: 0, Limit: 5000, Reset: reset},
+			want:   10*time.Minute + resetSlack,
+		},
+		{
+			name:   "budget left means unknown",
+			header: http.Header{},
+			budget: RateLimitInfo{Remaining: 10, Limit: 5000, Reset: reset},
+			want:   0,
+		},
+		{
+			name:   "nothing known",
+			header: http.Header{"Retry-After": {"soon"}},
+			budget: RateLimitInfo{}, //nolint:exhaustruct
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryAfter(tt.header, tt.budget)
+
+			if diff := got - tt.want; diff < -time.Second || diff > 0 {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitPause(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "Retry-After", header: http.Header{"Retry-After": {"5"}}, want: 5 * time.Second},
+		{name: "secondary limit without a hint", header: http.Header{}, want: secondaryLimitPause},
+		{
+			name: "exhausted budget",
+			header: http.Header{
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Limit":     {"5000"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(2*time.Minute).Unix(), 10)},
+			},
+			want: 2*time.Minute + resetSlack,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var limiter rateLimiter
+
+			resp := &http.Response{ //nolint:exhaustruct
+				StatusCode: http.StatusTooManyRequests,
+				Header:     tt.header,
+				Body:       http.NoBody,
+			}
+
+			start := time.Now()
+
+			if !limiter.observe(resp) {
+				t.Fatal("observe() = false, want a rate limit")
+			}
+
+			// Reset is whole seconds, so the pause may be up to a second short.
+			got := limiter.pausedTill().Sub(start)
+			if got < tt.want-time.Second || got > tt.want+time.Second {
+				t.Errorf("paused for %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequesterRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []int
+		maxAttempts int
+		wantErr     error
+		wantCalls   int32
+	}{
+		{name: "retries until success", statuses: []int{502, 503, 200}, maxAttempts: 3, wantErr: nil, wantCalls: 3},
+		{name: "gives up after max attempts", statuses: []int{502, 502, 502}, maxAttempts: 2, wantErr: ErrServerError, wantCalls: 2},
+		{name: "does not retry other statuses", statuses: []int{404, 200}, maxAttempts: 3, wantErr: ErrNotFound, wantCalls: 1},
+		{name: "single attempt", statuses: []int{502, 200}, maxAttempts: 1, wantErr: ErrServerError, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				status := tt.statuses[min(int(calls.Add(1)), len(tt.statuses))-1]
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			r := &requester{ //nolint:exhaustruct
+				httpClient: server.Client(),
+				policy: retryPolicy{
+					maxAttempts: tt.maxAttempts,
+					baseDelay:   time.Millisecond,
+					maxDelay:    time.Millisecond,
+					jitter:      0,
+					retryable:   map[int]bool{502: true, 503: true},
+				},
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := r.do(context.Background(), req, 0)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("sent %d requests, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
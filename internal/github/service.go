@@ -1,102 +1,134 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"metrics-scrapper/internal/config"
 	"time"
 )
 
+// GitHubService is implemented by every GitHub API backend.
 type GitHubService interface {
-	GetAllPullRequests() ([]PullRequest, error)
-	GetReviews(prNumber int) ([]Review, error)
-	GetComments(prNumber int) ([]IssueComment, error)
+	GetAllPullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error)
+	GetReviews(ctx context.Context, owner, repo string, prNumber int) ([]Review, error)
+	GetComments(ctx context.Context, owner, repo string, prNumber int) ([]IssueComment, error)
+	RateLimit() RateLimitInfo
 }
 
-func (c *Client) GetAllPullRequests(owner, repo string) ([]PullRequest, error) {
-	var allPRs []PullRequest
-	page := 1
+// PullRequestSizer is implemented by backends whose PR listing leaves out the
+// size of each PR, which then has to be fetched PR by PR.
+type PullRequestSizer interface {
+	GetPullRequest(ctx context.Context, owner, repo string, prNumber int) (PullRequest, error)
+}
 
-	for {
-		url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls?state=all&page=%d&per_page=%d&sort=created&direction=desc",
-			owner, repo, page, c.config.PerPage)
+// NewService returns the backend selected by cfg.GitHubBackend.
+func NewService(cfg *config.Config) (GitHubService, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		fmt.Printf("Page Request %d...\n", page)
+	if cfg.GitHubBackend == config.BackendGraphQL {
+		return NewGraphQLClient(cfg, transport), nil
+	}
 
-		req, err := c.createRequest(url)
-		if err != nil {
-			return nil, fmt.Errorf("request creation error: %v", err)
-		}
+	return NewClient(cfg, transport), nil
+}
 
-		resp, err := c.doRequest(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
+// GetAllPullRequests returns pull requests of owner/repo updated after since,
+// most recently updated first. Results are sorted by updated_at, so pagination
+// stops at the first PR that is not newer than since. A zero since fetches
+// everything up to the page limit.
+func (c *Client) GetAllPullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error) {
+	var allPRs []PullRequest
 
-		var prs []PullRequest
-		if err := json.NewDecoder(resp.Body).Decode(&prs); err != nil {
-			return nil, fmt.Errorf("parsing JSON error: %v", err)
-		}
+	settings := c.config.Repository(owner, repo)
+
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=all&per_page=%d&sort=updated&direction=desc",
+		c.baseURL, owner, repo, settings.PerPage)
 
-		if len(prs) == 0 {
-			break
+	err := paginate(ctx, c, owner, repo, url, settings.MaxPages, func(page int, prs []PullRequest) bool {
+		fresh := prs
+		for i, pr := range prs {
+			if !since.IsZero() && !pr.UpdatedAt.After(since) {
+				fresh = prs[:i]
+				break
+			}
 		}
 
-		allPRs = append(allPRs, prs...)
-		fmt.Printf("Received %d PR from the page %d\n", len(prs), page)
+		allPRs = append(allPRs, fresh...)
+		fmt.Printf("Received %d PR from the page %d\n", len(fresh), page)
 
-		page++
-		if page > c.config.MaxPages {
-			fmt.Printf("The page limit has been reached (%d)\n", c.config.MaxPages)
-			break
+		if len(fresh) < len(prs) {
+			fmt.Printf("Reached PRs not updated since %s\n", since.Format(time.DateTime))
+			return false
 		}
 
-		time.Sleep(time.Duration(c.config.DelayMS) * time.Millisecond)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return allPRs, nil
 }
 
-func (c *Client) GetReviews(owner, repo string, prNumber int) ([]Review, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d/reviews",
-		owner, repo, prNumber)
+// GetPullRequest returns PR prNumber of owner/repo. Unlike the list endpoint,
+// the single-PR endpoint reports its additions, deletions and changed files.
+func (c *Client) GetPullRequest(ctx context.Context, owner, repo string, prNumber int) (PullRequest, error) {
+	var pr PullRequest
 
-	req, err := c.createRequest(url)
+	req, err := c.createRequest(fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, prNumber))
 	if err != nil {
-		return nil, err
+		return pr, fmt.Errorf("request creation error: %v", err)
 	}
 
-	resp, err := c.doRequest(req)
+	resp, err := c.doRequest(ctx, req, owner, repo)
 	if err != nil {
-		return nil, err
+		return pr, err
 	}
 	defer resp.Body.Close()
 
-	var reviews []Review
-	if err := json.NewDecoder(resp.Body).Decode(&reviews); err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+		return pr, fmt.Errorf("parsing JSON error: %v", err)
 	}
 
-	return reviews, nil
+	return pr, nil
 }
 
-func (c *Client) GetComments(owner, repo string, prNumber int) ([]IssueComment, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues/%d/comments",
-		owner, repo, prNumber)
+func (c *Client) GetReviews(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	var reviews []Review
+
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=%d",
+		c.baseURL, owner, repo, prNumber, detailsPerPage)
 
-	req, err := c.createRequest(url)
-	if err != nil {
-		return nil, err
-	}
+	maxPages := c.config.Repository(owner, repo).MaxReviewPages
 
-	resp, err := c.doRequest(req)
+	err := paginate(ctx, c, owner, repo, url, maxPages, func(_ int, page []Review) bool {
+		reviews = append(reviews, page...)
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
+	return reviews, nil
+}
+
+func (c *Client) GetComments(ctx context.Context, owner, repo string, prNumber int) ([]IssueComment, error) {
 	var comments []IssueComment
-	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=%d",
+		c.baseURL, owner, repo, prNumber, detailsPerPage)
+
+	maxPages := c.config.Repository(owner, repo).MaxCommentPages
+
+	err := paginate(ctx, c, owner, repo, url, maxPages, func(_ int, page []IssueComment) bool {
+		comments = append(comments, page...)
+		return true
+	})
+	if err != nil {
 		return nil, err
 	}
 
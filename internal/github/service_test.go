@@ -0,0 +1,99 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/config"
+)
+
+// restPullRequests serves PRs 5 to 1, most recently updated first, three on
+// the first page and two on the second. PR n was updated on January n.
+func restPullRequests(t *testing.T) (*Client, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		numbers := []int{5, 4, 3}
+		if r.URL.Query().Get("page") == "2" {
+			numbers = []int{2, 1}
+		} else {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s&page=2>; rel="next"`, server.URL, r.URL.RequestURI()))
+		}
+
+		fmt.Fprint(w, "[")
+
+		for i, n := range numbers {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+
+			fmt.Fprintf(w, `{"number": %d, "state": "open", "updated_at": "2024-01-%02dT00:00:00Z"}`, n, n)
+		}
+
+		fmt.Fprint(w, "]")
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{ //nolint:exhaustruct
+		GitHubAPIURL: server.URL,
+		MaxPages:     config.Unlimited,
+		PerPage:      3,
+	}
+
+	return NewClient(cfg, http.DefaultTransport), &requests
+}
+
+func TestGetAllPullRequestsSince(t *testing.T) {
+	tests := []struct {
+		name     string
+		since    time.Time
+		want     []int
+		requests int32
+	}{
+		{name: "zero since fetches everything", since: time.Time{}, want: []int{5, 4, 3, 2, 1}, requests: 2},
+		{name: "stops within the second page", since: date(2), want: []int{5, 4, 3}, requests: 2},
+		{name: "stops within the first page", since: date(4), want: []int{5}, requests: 1},
+		{name: "PR updated exactly at since is not fresh", since: date(5), want: nil, requests: 1},
+		{name: "nothing updated since", since: date(9), want: nil, requests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := restPullRequests(t)
+
+			prs, err := client.GetAllPullRequests(context.Background(), "o", "r", tt.since)
+			if err != nil {
+				t.Fatalf("GetAllPullRequests: %v", err)
+			}
+
+			var got []int
+			for _, pr := range prs {
+				got = append(got, pr.Number)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got PRs %v, want %v", got, tt.want)
+			}
+
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("sent %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func date(day int) time.Time {
+	return time.Date(2024, time.January, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,46 @@
+package github
+
+import (
+	"fmt"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/fixtures"
+	"metrics-scrapper/internal/httpcache"
+	"net/http"
+)
+
+// newTransport builds the HTTP transport shared by the backends. Replay mode
+// serves recorded fixtures only. Otherwise the default transport is wrapped by
+// the response cache when it is enabled, and by the recorder on top of it, so
+// fixtures hold the responses the client actually saw.
+func newTransport(cfg *config.Config) (http.RoundTripper, error) {
+	if cfg.ReplayDir != "" {
+		transport, err := fixtures.Replayer(cfg.ReplayDir)
+		if err != nil {
+			return nil, fmt.Errorf("creating transport: %w", err)
+		}
+
+		return transport, nil
+	}
+
+	transport := http.DefaultTransport
+
+	if cfg.Cache.Enabled {
+		cache, err := httpcache.FromConfig(cfg.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("creating transport: %w", err)
+		}
+
+		transport = cache.Transport(transport)
+	}
+
+	if cfg.RecordDir != "" {
+		recorder, err := fixtures.Recorder(cfg.RecordDir, transport)
+		if err != nil {
+			return nil, fmt.Errorf("creating transport: %w", err)
+		}
+
+		transport = recorder
+	}
+
+	return transport, nil
+}
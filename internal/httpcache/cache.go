@@ -0,0 +1,221 @@
+// Package httpcache is an on-disk cache of GitHub API responses that turns
+// repeated GET requests into conditional ones. GitHub answers an unchanged
+// resource with 304 Not Modified, which doesn't count against the rate limit,
+// and the cached body is served instead.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"metrics-scrapper/internal/config"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const entryExt = ".entry"
+
+type Cache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	size int64
+}
+
+// entryMeta is stored as the first line of an entry file, followed by the raw
+// response body. Auth is the hash of the Authorization header the response was
+// fetched with, so a token never gets a response cached for another one.
+type entryMeta struct {
+	URL          string      `json:"url"`
+	Auth         string      `json:"auth,omitempty"`
+	ETag         string      `json:"etag"`
+	LastModified string      `json:"last_modified"`
+	StoredAt     time.Time   `json:"stored_at"`
+	Header       http.Header `json:"header"`
+}
+
+// New opens the cache in dir, creating it if needed. Entries not stored or
+// revalidated within ttl are ignored, and the oldest entries are evicted once
+// the total size exceeds maxSize bytes.
+func New(dir string, maxSize int64, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningCache, err)
+	}
+
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		ttl:     ttl,
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningCache, err)
+	}
+
+	for _, e := range entries {
+		c.size += e.size
+	}
+
+	return c, nil
+}
+
+func (c *Cache) path(url, auth string) string {
+	key := url
+	if auth != "" {
+		key += "\n" + auth
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+entryExt)
+}
+
+// authHash returns the hash of the Authorization header of req, empty for an
+// anonymous request.
+func authHash(req *http.Request) string {
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(auth))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// load returns the unexpired entry for url fetched with auth and its body.
+func (c *Cache) load(url, auth string) (entryMeta, []byte, bool) {
+	var meta entryMeta
+
+	data, err := os.ReadFile(c.path(url, auth))
+	if err != nil {
+		return meta, nil, false
+	}
+
+	line, body, found := bytes.Cut(data, []byte{'\n'})
+	if !found || json.Unmarshal(line, &meta) != nil || meta.URL != url || meta.Auth != auth {
+		return meta, nil, false
+	}
+
+	if c.ttl > 0 && time.Since(meta.StoredAt) > c.ttl {
+		return meta, nil, false
+	}
+
+	return meta, body, true
+}
+
+func (c *Cache) store(meta entryMeta, body []byte) error {
+	line, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingEntry, err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingEntry, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	w := bufio.NewWriter(tmp)
+	w.Write(line)     //nolint:errcheck
+	w.WriteByte('\n') //nolint:errcheck
+	w.Write(body)     //nolint:errcheck
+
+	if err := w.Flush(); err != nil {
+		tmp.Close() //nolint:errcheck
+		return fmt.Errorf("%w: %w", ErrWritingEntry, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingEntry, err)
+	}
+
+	path := c.path(meta.URL, meta.Auth)
+
+	var previous int64
+	if info, err := os.Stat(path); err == nil {
+		previous = info.Size()
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingEntry, err)
+	}
+
+	c.mu.Lock()
+	c.size += int64(len(line)+1+len(body)) - previous
+	overLimit := c.maxSize > 0 && c.size > c.maxSize
+	c.mu.Unlock()
+
+	if overLimit {
+		if _, err := c.Prune(false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type entryInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) entries() ([]entryInfo, error) {
+	var entries []entryInfo
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != entryExt {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entryInfo{path: path, size: info.Size(), modTime: info.ModTime()})
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// readMeta reads only the metadata line of an entry file.
+func readMeta(path string) (entryMeta, error) {
+	var meta entryMeta
+
+	f, err := os.Open(path)
+	if err != nil {
+		return meta, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return meta, err
+	}
+
+	return meta, json.Unmarshal(line, &meta)
+}
+
+// FromConfig opens the cache described by cfg.
+func FromConfig(cfg config.CacheConfig) (*Cache, error) {
+	return New(cfg.Dir, int64(cfg.MaxSizeMB)<<20, time.Duration(cfg.TTLHours)*time.Hour)
+}
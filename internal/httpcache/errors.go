@@ -0,0 +1,10 @@
+package httpcache
+
+import "errors"
+
+var (
+	ErrOpeningCache = errors.New("opening http cache")
+	ErrWritingEntry = errors.New("writing cache entry")
+	ErrPruningCache = errors.New("pruning http cache")
+	ErrReadingCache = errors.New("reading http cache")
+)
@@ -0,0 +1,107 @@
+package httpcache
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+type Stats struct {
+	Dir       string
+	Entries   int
+	Expired   int
+	TotalSize int64
+	MaxSize   int64
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+type PruneResult struct {
+	Removed    int
+	FreedBytes int64
+}
+
+// Stats scans the cache directory.
+func (c *Cache) Stats() (Stats, error) {
+	stats := Stats{Dir: c.dir, MaxSize: c.maxSize}
+
+	entries, err := c.entries()
+	if err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrReadingCache, err)
+	}
+
+	for _, e := range entries {
+		stats.Entries++
+		stats.TotalSize += e.size
+
+		if c.expired(e) {
+			stats.Expired++
+		}
+
+		if stats.Oldest.IsZero() || e.modTime.Before(stats.Oldest) {
+			stats.Oldest = e.modTime
+		}
+		if e.modTime.After(stats.Newest) {
+			stats.Newest = e.modTime
+		}
+	}
+
+	return stats, nil
+}
+
+// Prune removes expired entries, then the least recently stored ones until
+// the cache fits into its size limit. With all set, every entry is removed.
+func (c *Cache) Prune(all bool) (PruneResult, error) {
+	var result PruneResult
+
+	entries, err := c.entries()
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrPruningCache, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	for _, e := range entries {
+		overLimit := c.maxSize > 0 && total > c.maxSize
+		if !all && !overLimit && !c.expired(e) {
+			continue
+		}
+
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return result, fmt.Errorf("%w: %w", ErrPruningCache, err)
+		}
+
+		total -= e.size
+		result.Removed++
+		result.FreedBytes += e.size
+	}
+
+	c.mu.Lock()
+	c.size = total
+	c.mu.Unlock()
+
+	return result, nil
+}
+
+// expired uses the stored_at timestamp when it can be read and falls back to
+// the file modification time.
+func (c *Cache) expired(e entryInfo) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+
+	storedAt := e.modTime
+	if meta, err := readMeta(e.path); err == nil {
+		storedAt = meta.StoredAt
+	}
+
+	return time.Since(storedAt) > c.ttl
+}
@@ -0,0 +1,160 @@
+package httpcache
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// put stores an entry for url as if it was fetched at storedAt and returns
+// its size.
+func put(t *testing.T, c *Cache, url string, storedAt time.Time) int64 {
+	t.Helper()
+
+	meta := entryMeta{URL: url, ETag: `"v1"`, StoredAt: storedAt} //nolint:exhaustruct
+	if err := c.store(meta, []byte(`[{"number": 1}]`)); err != nil {
+		t.Fatalf("store %s: %v", url, err)
+	}
+
+	path := c.path(url, "")
+	if err := os.Chtimes(path, storedAt, storedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info.Size()
+}
+
+func TestPrune(t *testing.T) {
+	urls := []string{"/old", "/mid", "/new"}
+	ages := []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour}
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		maxSize func(sizes []int64) int64
+		all     bool
+		want    []string
+	}{
+		{
+			name: "nothing to prune",
+			want: urls,
+		},
+		{
+			name: "expired entries",
+			ttl:  90 * time.Minute,
+			want: []string{"/new"},
+		},
+		{
+			name:    "least recently stored entries over the size limit",
+			maxSize: func(sizes []int64) int64 { return sizes[1] + sizes[2] },
+			want:    []string{"/mid", "/new"},
+		},
+		{
+			name: "all entries",
+			ttl:  90 * time.Minute,
+			all:  true,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fill := newTestCache(t, 0, 0)
+			now := time.Now()
+
+			sizes := make([]int64, len(urls))
+			for i, url := range urls {
+				sizes[i] = put(t, fill, url, now.Add(-ages[i]))
+			}
+
+			var maxSize int64
+			if tt.maxSize != nil {
+				maxSize = tt.maxSize(sizes)
+			}
+
+			c, err := New(fill.dir, maxSize, tt.ttl)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			result, err := c.Prune(tt.all)
+			if err != nil {
+				t.Fatalf("Prune: %v", err)
+			}
+
+			var (
+				kept  []string
+				freed int64
+				size  int64
+			)
+
+			for i, url := range urls {
+				_, err := os.Stat(c.path(url, ""))
+
+				switch {
+				case err == nil:
+					kept = append(kept, url)
+					size += sizes[i]
+				case errors.Is(err, os.ErrNotExist):
+					freed += sizes[i]
+				default:
+					t.Fatal(err)
+				}
+			}
+
+			if !slices.Equal(kept, tt.want) {
+				t.Errorf("kept %v, want %v", kept, tt.want)
+			}
+
+			if result.Removed != len(urls)-len(kept) || result.FreedBytes != freed {
+				t.Errorf("Prune() = %+v, want %d removed, %d bytes freed", result, len(urls)-len(kept), freed)
+			}
+
+			if c.size != size {
+				t.Errorf("cache size %d after pruning, want %d", c.size, size)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := newTestCache(t, 1<<20, 90*time.Minute)
+
+	now := time.Now().Truncate(time.Second)
+
+	oldSize := put(t, c, "/old", now.Add(-2*time.Hour))
+	newSize := put(t, c, "/new", now.Add(-time.Hour))
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	want := Stats{
+		Dir:       c.dir,
+		Entries:   2,
+		Expired:   1,
+		TotalSize: oldSize + newSize,
+		MaxSize:   1 << 20,
+		Oldest:    now.Add(-2 * time.Hour),
+		Newest:    now.Add(-time.Hour),
+	}
+
+	// Compare the times apart, as the modification times carry no monotonic
+	// clock reading.
+	if !stats.Oldest.Equal(want.Oldest) || !stats.Newest.Equal(want.Newest) {
+		t.Errorf("oldest %v, newest %v; want %v, %v", stats.Oldest, stats.Newest, want.Oldest, want.Newest)
+	}
+
+	stats.Oldest, stats.Newest = want.Oldest, want.Newest
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,121 @@
+package httpcache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FromCacheHeader is set on responses served from disk after a 304.
+const FromCacheHeader = "X-From-Cache"
+
+type transport struct {
+	cache *Cache
+	next  http.RoundTripper
+}
+
+// Transport wraps next so that GET requests are revalidated against the cache
+// with If-None-Match / If-Modified-Since.
+func (c *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{cache: c, next: next}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	url := req.URL.String()
+	auth := authHash(req)
+
+	meta, body, cached := t.cache.load(url, auth)
+	if cached {
+		req = req.Clone(req.Context())
+
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close() //nolint:errcheck
+
+		// Revalidated entries stay alive for another TTL.
+		meta.StoredAt = time.Now()
+		if err := t.cache.store(meta, body); err != nil {
+			fmt.Printf("HTTP cache: %v\n", err)
+		}
+
+		return fromCache(req, meta, body, resp.Header), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	fresh, err := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadingCache, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(fresh))
+
+	err = t.cache.store(entryMeta{
+		URL:          url,
+		Auth:         auth,
+		ETag:         etag,
+		LastModified: lastModified,
+		StoredAt:     time.Now(),
+		Header:       resp.Header.Clone(),
+	}, fresh)
+	if err != nil {
+		// A broken cache must not break scraping.
+		fmt.Printf("HTTP cache: %v\n", err)
+	}
+
+	return resp, nil
+}
+
+// fromCache builds a 200 response from the stored entry. Rate limit headers
+// come from the live 304 response so the client keeps tracking its budget.
+func fromCache(req *http.Request, meta entryMeta, body []byte, live http.Header) *http.Response {
+	header := meta.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	for name, values := range live {
+		if strings.HasPrefix(strings.ToLower(name), "x-ratelimit-") {
+			header[name] = values
+		}
+	}
+
+	header.Set(FromCacheHeader, "1")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
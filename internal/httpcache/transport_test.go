@@ -0,0 +1,254 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// origin serves body with the configured validators and answers a matching
+// conditional request with 304. Every response reports a smaller remaining
+// rate limit, like GitHub does.
+type origin struct {
+	etag         string
+	lastModified string
+	status       int
+
+	mu       sync.Mutex
+	requests []http.Header
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	o.requests = append(o.requests, r.Header.Clone())
+	remaining := 5000 - len(o.requests)
+	o.mu.Unlock()
+
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+	if (o.etag != "" && r.Header.Get("If-None-Match") == o.etag) ||
+		(o.lastModified != "" && r.Header.Get("If-Modified-Since") == o.lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if o.etag != "" {
+		w.Header().Set("ETag", o.etag)
+	}
+	if o.lastModified != "" {
+		w.Header().Set("Last-Modified", o.lastModified)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	status := o.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	io.WriteString(w, `[{"number": 1}]`) //nolint:errcheck
+}
+
+// request returns the header of the n-th request the origin received.
+func (o *origin) request(n int) http.Header {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.requests[n]
+}
+
+func newTestCache(t *testing.T, maxSize int64, ttl time.Duration) *Cache {
+	t.Helper()
+
+	c, err := New(t.TempDir(), maxSize, ttl)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return c
+}
+
+// get sends a request to url through client with the given Authorization
+// header and returns the response with its body read.
+func get(t *testing.T, client *http.Client, method, url, auth string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, string(body)
+}
+
+func TestTransportRevalidates(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	tests := []struct {
+		name        string
+		origin      *origin
+		method      string
+		wantHeader  string
+		wantValue   string
+		revalidated bool
+	}{
+		{
+			name:        "ETag",
+			origin:      &origin{etag: `"v1"`}, //nolint:exhaustruct
+			method:      http.MethodGet,
+			wantHeader:  "If-None-Match",
+			wantValue:   `"v1"`,
+			revalidated: true,
+		},
+		{
+			name:        "Last-Modified",
+			origin:      &origin{lastModified: lastModified}, //nolint:exhaustruct
+			method:      http.MethodGet,
+			wantHeader:  "If-Modified-Since",
+			wantValue:   lastModified,
+			revalidated: true,
+		},
+		{
+			name:       "no validators",
+			origin:     &origin{}, //nolint:exhaustruct
+			method:     http.MethodGet,
+			wantHeader: "If-None-Match",
+		},
+		{
+			name:       "error responses are not cached",
+			origin:     &origin{etag: `"v1"`, status: http.StatusNotFound}, //nolint:exhaustruct
+			method:     http.MethodGet,
+			wantHeader: "If-None-Match",
+		},
+		{
+			name:       "only GET is cached",
+			origin:     &origin{etag: `"v1"`}, //nolint:exhaustruct
+			method:     http.MethodPost,
+			wantHeader: "If-None-Match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.origin)
+			defer server.Close()
+
+			client := &http.Client{Transport: newTestCache(t, 0, 0).Transport(http.DefaultTransport)} //nolint:exhaustruct
+
+			first, firstBody := get(t, client, tt.method, server.URL+"/repos/o/r/pulls", "")
+			second, secondBody := get(t, client, tt.method, server.URL+"/repos/o/r/pulls", "")
+
+			if got := tt.origin.request(0).Get(tt.wantHeader); got != "" {
+				t.Errorf("first request: %s %q, want none", tt.wantHeader, got)
+			}
+
+			want := ""
+			if tt.revalidated {
+				want = tt.wantValue
+			}
+
+			if got := tt.origin.request(1).Get(tt.wantHeader); got != want {
+				t.Errorf("second request: %s %q, want %q", tt.wantHeader, got, want)
+			}
+
+			if first.Header.Get(FromCacheHeader) != "" {
+				t.Errorf("first response marked as served from the cache")
+			}
+
+			if fromCache := second.Header.Get(FromCacheHeader) != ""; fromCache != tt.revalidated {
+				t.Errorf("second response served from the cache: %t, want %t", fromCache, tt.revalidated)
+			}
+
+			if second.StatusCode != first.StatusCode || secondBody != firstBody {
+				t.Errorf("second response %d %q, want %d %q", second.StatusCode, secondBody, first.StatusCode, firstBody)
+			}
+
+			// Rate limit headers are the live ones, the rest comes from the cache.
+			if got := second.Header.Get("X-RateLimit-Remaining"); got != "4998" {
+				t.Errorf("X-RateLimit-Remaining %q, want the live 4998", got)
+			}
+
+			if got := second.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type %q, want application/json", got)
+			}
+		})
+	}
+}
+
+func TestTransportKeysByAuthorization(t *testing.T) {
+	o := &origin{etag: `"v1"`} //nolint:exhaustruct
+
+	server := httptest.NewServer(o)
+	defer server.Close()
+
+	client := &http.Client{Transport: newTestCache(t, 0, 0).Transport(http.DefaultTransport)} //nolint:exhaustruct
+	url := server.URL + "/repos/o/r/pulls"
+
+	requests := []struct {
+		auth        string
+		conditional bool
+	}{
+		{auth: "token first", conditional: false},
+		{auth: "token second", conditional: false},
+		{auth: "", conditional: false},
+		{auth: "token first", conditional: true},
+		{auth: "token second", conditional: true},
+	}
+
+	for i, r := range requests {
+		get(t, client, http.MethodGet, url, r.auth)
+
+		if conditional := o.request(i).Get("If-None-Match") != ""; conditional != r.conditional {
+			t.Errorf("request %d with %q: conditional %t, want %t", i, r.auth, conditional, r.conditional)
+		}
+	}
+}
+
+func TestTransportExpiredEntry(t *testing.T) {
+	o := &origin{etag: `"v1"`} //nolint:exhaustruct
+
+	server := httptest.NewServer(o)
+	defer server.Close()
+
+	cache := newTestCache(t, 0, time.Hour)
+	client := &http.Client{Transport: cache.Transport(http.DefaultTransport)} //nolint:exhaustruct
+	url := server.URL + "/repos/o/r/pulls"
+
+	get(t, client, http.MethodGet, url, "")
+
+	meta, body, ok := cache.load(url, "")
+	if !ok {
+		t.Fatal("response not cached")
+	}
+
+	meta.StoredAt = time.Now().Add(-2 * time.Hour)
+	if err := cache.store(meta, body); err != nil {
+		t.Fatal(err)
+	}
+
+	get(t, client, http.MethodGet, url, "")
+
+	if got := o.request(1).Get("If-None-Match"); got != "" {
+		t.Errorf("expired entry revalidated with %q", got)
+	}
+}
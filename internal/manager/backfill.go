@@ -0,0 +1,203 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/spool"
+	"metrics-scrapper/internal/vmdb"
+	"time"
+)
+
+// BackfillOptions controls a Backfill run.
+type BackfillOptions struct {
+	// From and To bound the points to compute, both inclusive.
+	From time.Time
+	To   time.Time
+	// Step is the distance between two points.
+	Step time.Duration
+	// Window is how far back from each point PRs are taken into account.
+	Window time.Duration
+}
+
+// Backfill computes the repository metrics as they would have been at every
+// Step from From to To and imports all of them in one batch, timestamped at
+// their points. The PR history of each repository is fetched once; every point
+// is analyzed from the PRs as they looked at that moment, see
+// analyzer.AsOf.
+//
+// Nothing is pushed unless every repository was fetched, and the execution
+// timestamp is left untouched.
+func (m *MetricManager) Backfill(ctx context.Context, cfg *config.Config, opts BackfillOptions) (*RunReport, error) {
+	fmt.Printf("=== Backfill from %s to %s, step %v, window %v ===\n",
+		opts.From.Format(time.DateTime), opts.To.Format(time.DateTime), opts.Step, opts.Window)
+
+	repoKeys := make([]string, len(cfg.Repositories))
+	for i, repo := range cfg.Repositories {
+		repoKeys[i] = repo.Key()
+	}
+
+	report := newRunReport(repoKeys, true)
+	defer report.finish()
+
+	// Backfilled samples are timestamped at their points, so reruns with the
+	// same points write the same samples and share the run ID.
+	lastPoint := opts.From.Add(opts.To.Sub(opts.From) / opts.Step * opts.Step)
+	report.identify("backfill", opts.From.String(), lastPoint.String(), opts.Step.String(), opts.Window.String())
+
+	fmt.Printf("Run %s\n", report.RunID)
+
+	calendars, err := loadCalendars(cfg)
+	if err != nil {
+		return report, err
+	}
+
+	limiter := analyzer.NewLimiter(cfg.MaxConcurrency)
+	vmMetrics := &vmdb.Metrics{}
+
+	for i, repo := range cfg.Repositories {
+		fmt.Printf("\n=== Repository %d/%d: %s ===\n", i+1, len(cfg.Repositories), repo.Key())
+
+		repoReport := m.backfillRepo(ctx, repo, opts, limiter, calendars[repo.Calendar], cfg.Export, vmMetrics)
+		report.set(i, repoReport)
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		if repoReport.Status == StatusFailed {
+			return report, fmt.Errorf("%w: %s: %s: %w",
+				ErrRepositoriesFailed, repoReport.Repository, repoReport.Stage, repoReport.err)
+		}
+	}
+
+	// The batch is shared, so a failed push or verification fails every
+	// repository that contributed to it.
+	fail := func(stage Stage, err error) (*RunReport, error) {
+		for i, repo := range report.Repositories {
+			if repo.Status == StatusOK {
+				repo.fail(stage, err)
+				report.set(i, repo)
+			}
+		}
+
+		return report, err
+	}
+
+	err = m.VMDBExporter.PushMetrics(ctx, vmMetrics)
+	if errors.Is(err, spool.ErrQueued) {
+		fmt.Println(err)
+
+		for i, repo := range report.Repositories {
+			if repo.Status == StatusOK {
+				repo.Status = StatusQueued
+				repo.Error = err.Error()
+				report.set(i, repo)
+			}
+		}
+
+		return report, nil
+	}
+
+	if err != nil {
+		return fail(StagePush, fmt.Errorf("%w: %w", ErrPushingMetrics, err))
+	}
+
+	if cfg.VictoriaMetrics.Verify {
+		if err := m.VMDBExporter.VerifyMetrics(ctx, vmMetrics); err != nil {
+			return fail(StageVerify, fmt.Errorf("%w: %w", ErrVerifyingMetrics, err))
+		}
+
+		fmt.Printf("Verified %d series\n", len(vmMetrics.Data))
+	}
+
+	return report, nil
+}
+
+// backfillRepo fetches the PR history of repo and adds its points to
+// vmMetrics.
+func (m *MetricManager) backfillRepo(
+	ctx context.Context,
+	repo config.RepoConfig,
+	opts BackfillOptions,
+	limiter analyzer.Limiter,
+	calendar *analyzer.Calendar,
+	export config.ExportConfig,
+	vmMetrics *vmdb.Metrics,
+) (report RepoReport) {
+	repoKey := repo.Key()
+	startedAt := time.Now()
+
+	report = RepoReport{ //nolint:exhaustruct
+		Repository: repoKey,
+		Labels:     repo.Labels,
+		Status:     StatusOK,
+	}
+
+	defer func() { report.Duration = time.Since(startedAt) }()
+
+	prs, err := m.GithubClient.GetAllPullRequests(ctx, repo.Owner, repo.Repo, time.Time{})
+	if errors.Is(err, github.ErrNotFound) {
+		fmt.Printf("Repository %s not found, skipping: %v\n", repoKey, err)
+
+		report.Status = StatusSkipped
+		report.Error = err.Error()
+
+		return report
+	}
+	if err != nil {
+		report.fail(StageFetch, err)
+		return report
+	}
+
+	report.PRsFound = len(prs)
+
+	fmt.Printf("%s: found %d pull requests\n", repoKey, len(prs))
+
+	metrics, prErrors, err := analyzer.CollectPRMetrics(ctx, m.GithubClient, repo.Owner, repo.Repo, prs, analyzer.CollectOptions{
+		Concurrency: repo.Concurrency,
+		Limiter:     limiter,
+		Sizes:       false,
+	})
+	if err != nil {
+		report.fail(StageCollect, err)
+		return report
+	}
+
+	report.PRsProcessed = len(metrics)
+
+	for _, prErr := range prErrors {
+		fmt.Printf("%s: skipping %v\n", repoKey, prErr)
+		report.PRErrors = append(report.PRErrors, prErr.Error())
+	}
+
+	if export.Aggregates {
+		points := 0
+
+		for at := opts.From; !at.After(opts.To); at = at.Add(opts.Step) {
+			snapshot := analyzer.AsOf(metrics, at, opts.Window)
+			calendar.Apply(snapshot)
+
+			result := analyzer.AnalyzeData(snapshot)
+			if result.TotalPRs == 0 {
+				continue
+			}
+
+			addAggregates(vmMetrics, repo, result, at, export)
+
+			points++
+		}
+
+		fmt.Printf("%s: computed %d points\n", repoKey, points)
+	}
+
+	if export.PerPR {
+		calendar.Apply(metrics)
+		addPRSamples(vmMetrics, repo, metrics, export)
+	}
+
+	return report
+}
@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"fmt"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"slices"
+)
+
+// loadCalendars builds the working calendars of cfg by name, reading their
+// holiday files, so every run picks up edits to them.
+func loadCalendars(cfg *config.Config) (map[string]*analyzer.Calendar, error) {
+	calendars := make(map[string]*analyzer.Calendar, len(cfg.Calendars))
+
+	for name, calendar := range cfg.Calendars {
+		built, err := buildCalendar(calendar)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrLoadingCalendar, name, err)
+		}
+
+		calendars[name] = built
+	}
+
+	return calendars, nil
+}
+
+func buildCalendar(calendar config.CalendarConfig) (*analyzer.Calendar, error) {
+	location, err := calendar.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := calendar.WorkingHours()
+	if err != nil {
+		return nil, err
+	}
+
+	weekdays, err := calendar.WorkingDays()
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := calendar.HolidayDates()
+	if err != nil {
+		return nil, err
+	}
+
+	if calendar.HolidaysFile != "" {
+		fromFile, err := analyzer.LoadHolidays(calendar.HolidaysFile)
+		if err != nil {
+			return nil, err
+		}
+
+		holidays = slices.Concat(holidays, fromFile)
+	}
+
+	return analyzer.NewCalendar(location, start, end, weekdays, holidays), nil
+}
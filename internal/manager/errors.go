@@ -0,0 +1,17 @@
+package manager
+
+import "errors"
+
+var (
+	ErrPushingMetrics      = errors.New("pushing metrics")
+	ErrVerifyingMetrics    = errors.New("verifying pushed metrics")
+	ErrGettingLastExecTime = errors.New("getting last exec timestamp")
+	ErrPushingExecTime     = errors.New("pushing exec timestamp")
+	ErrRepositoriesFailed  = errors.New("repositories failed")
+	ErrWritingReport       = errors.New("writing run report")
+	ErrLoadingCalendar     = errors.New("loading calendar")
+	ErrPRNotFound          = errors.New("pull request not found")
+	ErrLoadingTotals       = errors.New("loading totals")
+	ErrSavingTotals        = errors.New("saving totals")
+	ErrCollectingPRs       = errors.New("collecting pull requests")
+)
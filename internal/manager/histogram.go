@@ -0,0 +1,211 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/vmdb"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Histogram names. Samples get the _bucket, _sum and _count suffixes.
+const (
+	PRLifetimeDistribution          = "pr_lifetime_distribution_seconds"
+	PRTimeToFirstReviewDistribution = "pr_time_to_first_review_distribution_seconds"
+
+	PRBusinessLifetimeDistribution          = "pr_business_lifetime_distribution_seconds"
+	PRBusinessTimeToFirstReviewDistribution = "pr_business_time_to_first_review_distribution_seconds"
+)
+
+// leBuckets are the upper bounds of Prometheus histogram buckets, from an
+// hour to a year.
+var leBuckets = []time.Duration{
+	time.Hour, 2 * time.Hour, 4 * time.Hour, 8 * time.Hour, 12 * time.Hour,
+	day, 2 * day, 3 * day, 5 * day, 7 * day, 14 * day,
+	30 * day, 60 * day, 90 * day, 180 * day, 365 * day,
+}
+
+const day = 24 * time.Hour
+
+// HistogramMetrics returns the distributions of PR lifetime and time to first
+// review, in working time too when the result has it, as histogram samples of
+// the given kind (config.HistogramVMRange or config.HistogramLE).
+func HistogramMetrics(result analyzer.AnalysisResult, kind string) []SummaryMetric {
+	type histogram struct {
+		name         string
+		distribution *analyzer.Sketch
+	}
+
+	histograms := []histogram{
+		{PRLifetimeDistribution, &result.Lifetimes},
+		{PRTimeToFirstReviewDistribution, &result.TimesToFirstReview},
+	}
+
+	if business := result.Business; business != nil {
+		histograms = append(histograms,
+			histogram{PRBusinessLifetimeDistribution, &business.Lifetimes},
+			histogram{PRBusinessTimeToFirstReviewDistribution, &business.TimesToFirstReview},
+		)
+	}
+
+	var metrics []SummaryMetric
+
+	for _, histogram := range histograms {
+		name, distribution := histogram.name, histogram.distribution
+
+		if distribution.Count() == 0 {
+			continue
+		}
+
+		switch kind {
+		case config.HistogramVMRange:
+			metrics = append(metrics, vmrangeBuckets(name, distribution)...)
+		case config.HistogramLE:
+			metrics = append(metrics, leBucketSamples(name, distribution)...)
+		default:
+			continue
+		}
+
+		metrics = append(metrics,
+			SummaryMetric{Name: name + "_sum", Value: distribution.Sum()},              //nolint:exhaustruct
+			SummaryMetric{Name: name + "_count", Value: float64(distribution.Count())}, //nolint:exhaustruct
+		)
+	}
+
+	return metrics
+}
+
+// leBucketSamples returns cumulative buckets, the last one being +Inf. The
+// sketch is bucketed more finely than leBuckets, so a value is counted in the
+// bucket of its sketch bucket, within analyzer.SketchAccuracy of the value.
+func leBucketSamples(name string, distribution *analyzer.Sketch) []SummaryMetric {
+	metrics := make([]SummaryMetric, 0, len(leBuckets)+1)
+	counts := make([]uint64, len(leBuckets))
+
+	for v, n := range distribution.Buckets() {
+		if i, _ := slices.BinarySearch(leBuckets, v); i < len(counts) {
+			counts[i] += n
+		}
+	}
+
+	var cumulative uint64
+	for i, bound := range leBuckets {
+		cumulative += counts[i]
+
+		metrics = append(metrics, SummaryMetric{ //nolint:exhaustruct
+			Name:   name + "_bucket",
+			Labels: map[string]string{config.HistogramLE: strconv.FormatFloat(bound.Seconds(), 'f', -1, 64)},
+			Value:  float64(cumulative),
+		})
+	}
+
+	return append(metrics, SummaryMetric{ //nolint:exhaustruct
+		Name:   name + "_bucket",
+		Labels: map[string]string{config.HistogramLE: "+Inf"},
+		Value:  float64(distribution.Count()),
+	})
+}
+
+// VictoriaMetrics histogram buckets: 18 log-spaced buckets per decade from
+// 1e-9 to 1e18, plus one below and one above. Only non-empty buckets are
+// exported.
+const (
+	vmrangeE10Min            = -9
+	vmrangeE10Max            = 18
+	vmrangeBucketsPerDecimal = 18
+	vmrangeBucketsCount      = (vmrangeE10Max - vmrangeE10Min) * vmrangeBucketsPerDecimal
+	vmrangeLower             = "0...1.000e-09"
+	vmrangeUpper             = "1.000e+18...+Inf"
+)
+
+var vmrangeRanges = func() []string {
+	ranges := make([]string, vmrangeBucketsCount)
+	multiplier := math.Pow(10, 1.0/vmrangeBucketsPerDecimal)
+
+	v := math.Pow10(vmrangeE10Min)
+	start := fmt.Sprintf("%.3e", v)
+
+	for i := range ranges {
+		v *= multiplier
+		end := fmt.Sprintf("%.3e", v)
+		ranges[i] = start + "..." + end
+		start = end
+	}
+
+	return ranges
+}()
+
+func vmrangeBucket(v float64) string {
+	if v <= 0 {
+		return vmrangeLower
+	}
+
+	idx := (math.Log10(v) - vmrangeE10Min) * vmrangeBucketsPerDecimal
+
+	switch {
+	case idx < 0:
+		return vmrangeLower
+	case idx >= vmrangeBucketsCount:
+		return vmrangeUpper
+	}
+
+	// Bucket ranges include their upper bound.
+	i := int(idx)
+	if float64(i) == idx && i > 0 {
+		i--
+	}
+
+	return vmrangeRanges[i]
+}
+
+// vmrangeBuckets spreads the sketch buckets over the VictoriaMetrics ones, which
+// are about six times wider.
+func vmrangeBuckets(name string, distribution *analyzer.Sketch) []SummaryMetric {
+	var (
+		metrics []SummaryMetric
+		counts  = make(map[string]uint64)
+	)
+
+	for v, n := range distribution.Buckets() {
+		bucket := vmrangeBucket(v.Seconds())
+		if counts[bucket] == 0 {
+			metrics = append(metrics, SummaryMetric{ //nolint:exhaustruct
+				Name:   name + "_bucket",
+				Labels: map[string]string{config.HistogramVMRange: bucket},
+			})
+		}
+
+		counts[bucket] += n
+	}
+
+	for i := range metrics {
+		metrics[i].Value = float64(counts[metrics[i].Labels[config.HistogramVMRange]])
+	}
+
+	return metrics
+}
+
+// addAggregates adds the repository-level samples of result, timestamped at
+// timestamp.
+func addAggregates(
+	vmMetrics *vmdb.Metrics,
+	repo config.RepoConfig,
+	result analyzer.AnalysisResult,
+	timestamp time.Time,
+	export config.ExportConfig,
+) {
+	metrics := append(SummaryMetrics(result, export.MergeWithinDays), HistogramMetrics(result, export.Histograms)...)
+
+	for _, metric := range metrics {
+		vmMetrics.AddPRMetric(
+			metric.Name,
+			repo.Key(),
+			WithLabels(repo.Labels, metric.Labels),
+			metric.Value,
+			uint64(timestamp.UnixMilli()),
+		)
+	}
+}
@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+)
+
+// parseRange returns the bounds of a vmrange label.
+func parseRange(t *testing.T, vmrange string) (float64, float64) {
+	t.Helper()
+
+	start, end, found := strings.Cut(vmrange, "...")
+	if !found {
+		t.Fatalf("malformed vmrange %q", vmrange)
+	}
+
+	lo, err := strconv.ParseFloat(start, 64)
+	if err != nil {
+		t.Fatalf("vmrange %q: %v", vmrange, err)
+	}
+
+	hi, err := strconv.ParseFloat(end, 64)
+	if err != nil {
+		t.Fatalf("vmrange %q: %v", vmrange, err)
+	}
+
+	return lo, hi
+}
+
+func TestVMRangeRanges(t *testing.T) {
+	if len(vmrangeRanges) != vmrangeBucketsCount {
+		t.Fatalf("got %d ranges, want %d", len(vmrangeRanges), vmrangeBucketsCount)
+	}
+
+	if first := vmrangeRanges[0]; !strings.HasPrefix(first, "1.000e-09...") {
+		t.Errorf("first range %q does not start at the lower bucket", first)
+	}
+
+	if last := vmrangeRanges[len(vmrangeRanges)-1]; !strings.HasSuffix(last, "...1.000e+18") {
+		t.Errorf("last range %q does not end at the upper bucket", last)
+	}
+
+	for i := 1; i < len(vmrangeRanges); i++ {
+		_, prevEnd, _ := strings.Cut(vmrangeRanges[i-1], "...")
+		if start, _, _ := strings.Cut(vmrangeRanges[i], "..."); start != prevEnd {
+			t.Errorf("range %d %q does not continue %q", i, vmrangeRanges[i], vmrangeRanges[i-1])
+		}
+	}
+
+	// Every decade ends exactly on a power of ten.
+	for i := vmrangeBucketsPerDecimal - 1; i < len(vmrangeRanges); i += vmrangeBucketsPerDecimal {
+		e10 := vmrangeE10Min + (i+1)/vmrangeBucketsPerDecimal
+		if want := "..." + strconv.FormatFloat(math.Pow10(e10), 'e', 3, 64); !strings.HasSuffix(vmrangeRanges[i], want) {
+			t.Errorf("range %d %q, want it to end with %q", i, vmrangeRanges[i], want)
+		}
+	}
+}
+
+func TestVMRangeBucket(t *testing.T) {
+	tests := []struct {
+		name string
+		v    float64
+		want string
+	}{
+		{name: "zero", v: 0, want: vmrangeLower},
+		{name: "negative", v: -1, want: vmrangeLower},
+		{name: "below the lowest range", v: 1e-10, want: vmrangeLower},
+		{name: "above the highest range", v: 1e19, want: vmrangeUpper},
+		// As in VictoriaMetrics, 1e-9 falls in the first range rather than
+		// the lower bucket, and 1e18 in the upper bucket.
+		{name: "lowest bound", v: 1e-9, want: vmrangeRanges[0]},
+		{name: "highest bound", v: 1e18, want: vmrangeUpper},
+		{name: "upper bound is inclusive", v: 1, want: vmrangeRanges[9*vmrangeBucketsPerDecimal-1]},
+		{name: "just above a power of ten", v: 1.0001, want: vmrangeRanges[9*vmrangeBucketsPerDecimal]},
+		{name: "an hour", v: 3600, want: "3.594e+03...4.084e+03"},
+		{name: "a day", v: 86400, want: "7.743e+04...8.799e+04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vmrangeBucket(tt.v); got != tt.want {
+				t.Errorf("vmrangeBucket(%g) = %q, want %q", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVMRangeBucketContainsValue(t *testing.T) {
+	for v := 1e-8; v < 1e17; v *= 1.37 {
+		bucket := vmrangeBucket(v)
+
+		lo, hi := parseRange(t, bucket)
+
+		// Bounds are printed with four significant digits.
+		if v < lo*(1-1e-3) || v > hi*(1+1e-3) {
+			t.Fatalf("vmrangeBucket(%g) = %q, value outside the range", v, bucket)
+		}
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	var sketch analyzer.Sketch
+
+	durations := []time.Duration{
+		30 * time.Minute, time.Hour, 90 * time.Minute, 3 * time.Hour,
+		day, 2 * day, 10 * day, 400 * day,
+	}
+	for _, d := range durations {
+		sketch.Add(d)
+	}
+
+	result := analyzer.AnalysisResult{Lifetimes: sketch} //nolint:exhaustruct
+
+	tests := []struct {
+		kind string
+		// bucket returns the value of a bucket sample, whether it is
+		// cumulative and the upper bound of the bucket in seconds.
+		bound      func(labels map[string]string) float64
+		cumulative bool
+	}{
+		{
+			kind: config.HistogramVMRange,
+			bound: func(labels map[string]string) float64 {
+				_, hi := parseRange(t, labels[config.HistogramVMRange])
+				return hi
+			},
+		},
+		{
+			kind: config.HistogramLE,
+			bound: func(labels map[string]string) float64 {
+				if labels[config.HistogramLE] == "+Inf" {
+					return math.Inf(1)
+				}
+
+				le, err := strconv.ParseFloat(labels[config.HistogramLE], 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return le
+			},
+			cumulative: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			var (
+				buckets    float64
+				last       float64
+				lastBound  = math.Inf(-1)
+				sum, count float64
+			)
+
+			for _, metric := range HistogramMetrics(result, tt.kind) {
+				switch metric.Name {
+				case PRLifetimeDistribution + "_bucket":
+					bound := tt.bound(metric.Labels)
+
+					if tt.cumulative {
+						if bound <= lastBound || metric.Value < last {
+							t.Errorf("bucket %v = %g after %g", metric.Labels, metric.Value, last)
+						}
+
+						lastBound, last = bound, metric.Value
+					} else {
+						buckets += metric.Value
+					}
+				case PRLifetimeDistribution + "_sum":
+					sum = metric.Value
+				case PRLifetimeDistribution + "_count":
+					count = metric.Value
+				default:
+					t.Errorf("unexpected series %s", metric.Name)
+				}
+			}
+
+			if tt.cumulative {
+				buckets = last
+			}
+
+			if want := float64(len(durations)); buckets != want || count != want {
+				t.Errorf("got %g in buckets and count %g, want %g", buckets, count, want)
+			}
+
+			var want time.Duration
+			for _, d := range durations {
+				want += d
+			}
+
+			if sum != want.Seconds() {
+				t.Errorf("got sum %g, want %g", sum, want.Seconds())
+			}
+		})
+	}
+}
@@ -1,26 +1,29 @@
 package manager
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"metrics-scrapper/internal/analyzer"
 	"metrics-scrapper/internal/config"
 	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/spool"
 	"metrics-scrapper/internal/vmdb"
+	"strconv"
+	"sync"
 	"time"
 )
 
 const Name = "METRIC_ANALYSIS_RESULT"
 
 type MetricManager struct {
-	GithubClient *github.Client
+	GithubClient github.GitHubService
 	VMDBExporter VMDBExporter
 }
 
 func NewMetricManager(
 	vmdbExporter VMDBExporter,
-	githubClient *github.Client,
+	githubClient github.GitHubService,
 ) *MetricManager {
 	newManager := &MetricManager{ //nolint:exhaustruct
 		VMDBExporter: vmdbExporter,
@@ -30,126 +33,366 @@ func NewMetricManager(
 	return newManager
 }
 
-func (m *MetricManager) ScrapeAndPush(cfg *config.Config) error {
-	fmt.Println("=== PR analysis for multiple repositories ===")
+// RunOptions controls a ScrapeAndPush run.
+type RunOptions struct {
+	// ScrapeThreshold limits scraping to PRs updated after it. Zero means
+	// "since the last successful execution" as recorded in VictoriaMetrics.
+	ScrapeThreshold time.Time
+	// FullHistory scrapes every PR, ignoring both ScrapeThreshold and the
+	// execution timestamp.
+	FullHistory bool
+	// FailFast cancels the remaining repositories after the first failure.
+	// Otherwise every repository is processed and failures are collected in
+	// the report.
+	FailFast bool
+	// SkipExecTimestamp leaves the execution timestamp untouched, for callers
+	// that track their own per-repository checkpoints.
+	SkipExecTimestamp bool
+	// SkipTotals leaves the saved totals untouched, for dry runs. They are
+	// still read, so the aggregates are the same as in a real run.
+	SkipTotals bool
+	// Limiter bounds in-flight PRs across concurrent runs. Nil creates one
+	// for this run sized by MaxConcurrency.
+	Limiter analyzer.Limiter
+}
 
-	allResults := make(map[string]analyzer.RepositoryResult)
+// ScrapeAndPush analyzes PRs of every configured repository and pushes the
+// results. Repositories are processed independently: a failure is recorded in
+// the returned report, and with opts.FailFast also stops the run. The report
+// is returned even when the error is non-nil.
+//
+// The new execution timestamp is pushed only after every repository has been
+// pushed successfully with all of its PRs, so a failed run is retried from the
+// same point.
+func (m *MetricManager) ScrapeAndPush(ctx context.Context, cfg *config.Config, opts RunOptions) (*RunReport, error) {
+	fmt.Println("=== PR analysis for multiple repositories ===")
 
+	repoKeys := make([]string, len(cfg.Repositories))
 	for i, repo := range cfg.Repositories {
-		fmt.Printf("\n=== Repository %d/%d: %s/%s ===\n",
-			i+1, len(cfg.Repositories), repo.Owner, repo.Repo)
+		repoKeys[i] = repo.Key()
+	}
+
+	report := newRunReport(repoKeys, opts.FailFast)
+	defer report.finish()
+
+	report.SampleTime = report.StartedAt.Truncate(time.Millisecond)
+	report.Window = alignTime(report.StartedAt, cfg.Export.AlignMinutes)
+	report.identify(strconv.FormatInt(report.Window.UnixMilli(), 10))
+
+	fmt.Printf("Run %s, aggregates timestamped at %s in the window from %s\n",
+		report.RunID, report.SampleTime.Format(time.DateTime), report.Window.Format(time.DateTime))
+
+	calendars, err := loadCalendars(cfg)
+	if err != nil {
+		return report, err
+	}
+
+	var scrapeFrom time.Time
+
+	if !opts.FullHistory {
+		scrapeFrom = opts.ScrapeThreshold
+	}
 
-		prs, err := m.GithubClient.GetAllPullRequests(repo.Owner, repo.Repo)
+	if scrapeFrom.IsZero() && !opts.FullHistory {
+		lastExec, err := m.VMDBExporter.GetLastExecTimestamp(ctx)
 		if err != nil {
-			log.Fatalf("Error when receiving PR: %v", err)
+			return report, fmt.Errorf("%w: %w", ErrGettingLastExecTime, err)
 		}
 
-		fmt.Printf("Found %d pull requests\n", len(prs))
+		scrapeFrom = lastExec
+	}
+
+	if scrapeFrom.IsZero() {
+		fmt.Println("No previous execution found, scraping full history")
+	} else {
+		fmt.Printf("Scraping PRs updated after %s\n", scrapeFrom.Format(time.DateTime))
+	}
+
+	var totalsDir string
+	if cfg.Totals.Enabled {
+		totalsDir = cfg.Totals.Dir
+	}
+
+	runCtx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	// Repositories are processed RepoConcurrency at a time. All of them share
+	// the GitHub rate limit budget and the MaxConcurrency bound on in-flight
+	// PRs.
+	limiter := opts.Limiter
+	if limiter == nil {
+		limiter = analyzer.NewLimiter(cfg.MaxConcurrency)
+	}
+
+	repos := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range cfg.RepoConcurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
 
-		if len(prs) == 0 {
-			fmt.Println("No PR was found for analysis.")
-			return errors.New("no PR found for analysis.")
+			for i := range repos {
+				// A repository handed out just before a fail-fast abort
+				// stays cancelled.
+				if runCtx.Err() != nil {
+					continue
+				}
+
+				repo := cfg.Repositories[i]
+
+				fmt.Printf("\n=== Repository %d/%d: %s ===\n", i+1, len(cfg.Repositories), repo.Key())
+
+				repoReport, result := m.processRepo(runCtx, repo, repoRun{
+					scrapeFrom: scrapeFrom,
+					sampleTime: report.SampleTime,
+					limiter:    limiter,
+					calendar:   calendars[repo.Calendar],
+					totals:     totalsDir,
+					skipTotals: opts.SkipTotals,
+					export:     cfg.Export,
+					verify:     cfg.VictoriaMetrics.Verify,
+				})
+				repoReport.Result = result
+				report.set(i, repoReport)
+
+				if repoReport.Status == StatusFailed {
+					fmt.Printf("%s: %s failed: %v\n", repo.Key(), repoReport.Stage, repoReport.err)
+
+					if opts.FailFast {
+						abort()
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range cfg.Repositories {
+		if runCtx.Err() != nil {
+			break
 		}
 
-		metrics, err := analyzer.CollectPRMetrics(m.GithubClient, repo.Owner, repo.Repo, prs)
-		if err != nil {
-			log.Fatalf("Error when collecting metrics: %v", err)
+		repos <- i
+	}
+
+	close(repos)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if failed := report.Failed(); len(failed) > 0 {
+		errs := make([]error, 0, len(failed))
+		for _, repo := range failed {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", repo.Repository, repo.Stage, repo.err))
 		}
 
-		result := analyzer.AnalyzeData(metrics)
+		return report, fmt.Errorf("%w: %w", ErrRepositoriesFailed, errors.Join(errs...))
+	}
 
-		repoKey := fmt.Sprintf("%s/%s", repo.Owner, repo.Repo)
-		allResults[repoKey] = analyzer.RepositoryResult{
-			Owner:    repo.Owner,
-			Repo:     repo.Repo,
-			PRCount:  len(prs),
-			Metrics:  metrics,
-			Analysis: result,
+	if !opts.SkipExecTimestamp {
+		// A queued timestamp is delivered after the metrics queued before it.
+		err := m.VMDBExporter.PushExecTimestamp(ctx, report.StartedAt)
+		if errors.Is(err, spool.ErrQueued) {
+			fmt.Printf("Execution timestamp %v\n", err)
+		} else if err != nil {
+			return report, fmt.Errorf("%w: %w", ErrPushingExecTime, err)
 		}
+	}
+
+	return report, nil
+}
+
+// repoRun is what processRepo shares with the other repositories of a run.
+type repoRun struct {
+	scrapeFrom time.Time
+	// sampleTime timestamps the aggregates.
+	sampleTime time.Time
+	limiter    analyzer.Limiter
+	// calendar measures working time, nil without one.
+	calendar *analyzer.Calendar
+	// totals is the directory of the saved totals, empty when they are
+	// disabled and the aggregates cover the PRs of the run alone.
+	totals     string
+	skipTotals bool
+	export     config.ExportConfig
+	verify     bool
+}
 
-		// -------------------------------------------------
+// processRepo scrapes and pushes a single repository. The result is nil when
+// the repository failed or had nothing to analyze.
+func (m *MetricManager) processRepo(
+	ctx context.Context,
+	repo config.RepoConfig,
+	run repoRun,
+) (RepoReport, *analyzer.RepositoryResult) {
+	repoKey := repo.Key()
+	startedAt := time.Now()
 
-		vmMetrics := &vmdb.Metrics{}
+	report := RepoReport{ //nolint:exhaustruct
+		Repository: repoKey,
+		Labels:     repo.Labels,
+		Status:     StatusOK,
+	}
+
+	fail := func(stage Stage, err error) (RepoReport, *analyzer.RepositoryResult) {
+		report.Duration = time.Since(startedAt)
+
+		// A repository interrupted by a fail-fast abort or a shutdown did
+		// not fail on its own.
+		if ctx.Err() != nil {
+			report.Status = StatusCancelled
+			report.Stage = stage
+			report.Error = ctx.Err().Error()
 
-		// 1. Общее время жизни PR
-		vmMetrics.AddPRMetric("PRLifetime", repoKey, result.MedianLifetime/time.Second, uint64(time.Now().UnixMilli()))
+			return report, nil
+		}
+
+		report.fail(stage, err)
 
-		// 2. Время до первого ответа
-		vmMetrics.AddPRMetric("TimeToFirstReview", repoKey, result.AverageTimeToFirstReview/time.Second, uint64(time.Now().UnixMilli()))
+		return report, nil
+	}
 
-		// 3. Процент успешных мержей
-		vmMetrics.AddPRMetric("MergeSuccessRate", repoKey, result.MergeRate, uint64(time.Now().UnixMilli()))
+	prs, err := m.GithubClient.GetAllPullRequests(ctx, repo.Owner, repo.Repo, run.scrapeFrom)
+	if errors.Is(err, github.ErrNotFound) {
+		fmt.Printf("Repository %s not found, skipping: %v\n", repoKey, err)
 
-		// 4. Прогнозное время до мержа нового PR
-		vmMetrics.AddPRMetric("PredictedMergeTime", repoKey, result.PredictedTimeToMerge/time.Second, uint64(time.Now().UnixMilli()))
+		report.Status = StatusSkipped
+		report.Error = err.Error()
+		report.Duration = time.Since(startedAt)
 
-		err = m.VMDBExporter.PushMetrics(vmMetrics)
+		return report, nil
+	}
+	if err != nil {
+		return fail(StageFetch, err)
+	}
+
+	report.PRsFound = len(prs)
+
+	fmt.Printf("%s: found %d pull requests\n", repoKey, len(prs))
+
+	if len(prs) == 0 {
+		fmt.Printf("%s: no PR was found for analysis.\n", repoKey)
+
+		report.Duration = time.Since(startedAt)
+
+		return report, nil
+	}
+
+	metrics, prErrors, err := analyzer.CollectPRMetrics(ctx, m.GithubClient, repo.Owner, repo.Repo, prs, analyzer.CollectOptions{
+		Concurrency: repo.Concurrency,
+		Limiter:     run.limiter,
+		Sizes:       false,
+	})
+	if err != nil {
+		return fail(StageCollect, err)
+	}
+
+	report.PRsProcessed = len(metrics)
+
+	for _, prErr := range prErrors {
+		fmt.Printf("%s: skipping %v\n", repoKey, prErr)
+		report.PRErrors = append(report.PRErrors, prErr.Error())
+	}
+
+	run.calendar.Apply(metrics)
+
+	var (
+		result analyzer.AnalysisResult
+		totals *analyzer.Totals
+	)
+
+	if run.totals == "" {
+		result = analyzer.AnalyzeData(metrics)
+	} else {
+		totals, err = loadTotals(run.totals, repo)
 		if err != nil {
-			return fmt.Errorf("%w: %w", "xueta", err)
+			return fail(StageTotals, err)
 		}
 
-		// -------------------------------------------------
+		totals.Add(metrics)
+		result = totals.Result(time.Now(), run.calendar)
+
+		fmt.Printf("%s: totals cover %d PRs\n", repoKey, result.TotalPRs)
+	}
+
+	// -------------------------------------------------
 
-		// analyzer.PrintAnalysisResults(repo.Owner, repo.Repo, result)
+	vmMetrics := &vmdb.Metrics{}
 
-		if i < len(cfg.Repositories)-1 {
-			fmt.Printf("Waiting for the next repository...\n")
-			time.Sleep(2 * time.Second)
+	if run.export.Aggregates {
+		addAggregates(vmMetrics, repo, result, run.sampleTime, run.export)
+	}
+
+	if run.export.PerPR {
+		addPRSamples(vmMetrics, repo, metrics, run.export)
+	}
+
+	err = m.VMDBExporter.PushMetrics(ctx, vmMetrics)
+	if errors.Is(err, spool.ErrQueued) {
+		fmt.Printf("%s: %v\n", repoKey, err)
+
+		report.Status = StatusQueued
+		report.Error = err.Error()
+	} else if err != nil {
+		return fail(StagePush, fmt.Errorf("%w: %w", ErrPushingMetrics, err))
+	}
+
+	if run.verify && report.Status != StatusQueued {
+		if err := m.VMDBExporter.VerifyMetrics(ctx, vmMetrics); err != nil {
+			return fail(StageVerify, fmt.Errorf("%w: %w", ErrVerifyingMetrics, err))
+		}
+
+		fmt.Printf("%s: verified %d series\n", repoKey, len(vmMetrics.Data))
+	}
+
+	// Totals are saved once their aggregates are pushed. Adding the same PRs
+	// twice changes nothing, so a run repeated after a failure is safe.
+	if totals != nil && !run.skipTotals {
+		if err := saveTotals(run.totals, repo, totals); err != nil {
+			return fail(StageTotals, err)
 		}
 	}
 
-	// comparative := analyzer.ComparativeAnalysis(allResults)
+	if budget := m.GithubClient.RateLimit(); budget.Limit > 0 {
+		fmt.Printf("API budget: %d/%d, reset at %s\n",
+			budget.Remaining, budget.Limit, budget.Reset.Format(time.DateTime))
+	}
+
+	// The PRs left out are missing from what was pushed and saved. Failing
+	// the repository holds back the execution timestamp, so the next run
+	// fetches them again.
+	if len(prErrors) > 0 {
+		errs := make([]error, len(prErrors))
+		for i, prErr := range prErrors {
+			errs[i] = prErr
+		}
 
-	// analyzer.PrintComparativeAnalysis(comparative)
+		return fail(StageCollect, fmt.Errorf("%w: %d of %d failed: %w",
+			ErrCollectingPRs, len(prErrors), len(prs), errors.Join(errs...)))
+	}
 
-	// if err := analyzer.SaveAllData(allResults); err != nil {
-	// 	log.Printf("Error saving data: %v", err)
-	// }
+	report.Duration = time.Since(startedAt)
 
-	return nil
+	return report, &analyzer.RepositoryResult{
+		Owner:    repo.Owner,
+		Repo:     repo.Repo,
+		PRCount:  len(prs),
+		Metrics:  metrics,
+		Analysis: result,
+	}
 }
 
-//func (m *MetricManager) runScraper(
-//	repo string,
-//	projectKey string,
-//	scrapeFrom time.Time,
-//) error {
-//	collection := &collection.ScrapedCollection{RepoName: repo} //nolint:exhaustruct
-//
-//	for _, scraper := range m.Scrapers {
-//		m.Logger.Info(fmt.Sprintf("%q started", scraper.GetName()), "repo", repo)
-//
-//		err := scraper.Scrape(projectKey, collection, scrapeFrom)
-//		if err != nil {
-//			return fmt.Errorf("%q, %q, %w: %w", scraper.GetName(), repo, ErrScraperFailed, err)
-//		}
-//
-//		m.Logger.Info(fmt.Sprintf("%q finished", scraper.GetName()), "repo", repo)
-//	}
-//
-//	err := m.Push(collection)
-//	if err != nil {
-//		return fmt.Errorf("%q, %w: %w", repo, ErrPushingMetrics, err)
-//	}
-//
-//	return nil
-//}
-//
-//func (m *MetricManager) Push(collection *collection.ScrapedCollection) error {
-//	m.Logger.Info("exporters started", "repo", collection.RepoName)
-//
-//	metrics := &vmdb.Metrics{} //nolint:exhaustruct
-//
-//	for _, exporter := range m.Exporters {
-//		exporter.Export(collection, metrics)
-//	}
-//
-//	m.Logger.Info("push metrics", "repo", collection.RepoName)
-//
-//	err := m.VMDBExporter.PushMetrics(metrics)
-//	if err != nil {
-//		return fmt.Errorf("%w: %w", ErrPushingMetrics, err)
-//	}
-//
-//	return nil
-//}
+// alignTime rounds t down to a multiple of minutes since the Unix epoch, the
+// start of the deduplication window t falls into. Zero minutes leaves t as
+// is, truncated to milliseconds, making every run a window of its own.
+func alignTime(t time.Time, minutes int) time.Time {
+	if minutes <= 0 {
+		return t.Truncate(time.Millisecond)
+	}
+
+	return t.Truncate(time.Duration(minutes) * time.Minute)
+}
@@ -0,0 +1,494 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/spool"
+	"metrics-scrapper/internal/vmdb"
+)
+
+var (
+	errFetch = errors.New("fetch failed")
+	errPush  = errors.New("push failed")
+)
+
+// fakeGitHub lists prs, or one open PR when there are none, for every
+// repository except those listed in errs, which fail with the given error.
+// Fetching the reviews of any PR of a repository in reviewErrs fails.
+type fakeGitHub struct {
+	errs       map[string]error
+	reviewErrs map[string]error
+	prs        []github.PullRequest
+}
+
+func (g *fakeGitHub) GetAllPullRequests(_ context.Context, owner, repo string, _ time.Time) ([]github.PullRequest, error) {
+	if err := g.errs[owner+"/"+repo]; err != nil {
+		return nil, err
+	}
+
+	if g.prs != nil {
+		return g.prs, nil
+	}
+
+	return []github.PullRequest{{ //nolint:exhaustruct
+		Number:    1,
+		State:     "open",
+		CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		User:      github.User{ID: 1, Login: "alice"},
+	}}, nil
+}
+
+func (g *fakeGitHub) GetReviews(_ context.Context, owner, repo string, _ int) ([]github.Review, error) {
+	return nil, g.reviewErrs[owner+"/"+repo]
+}
+
+func (g *fakeGitHub) GetComments(context.Context, string, string, int) ([]github.IssueComment, error) {
+	return nil, nil
+}
+
+func (g *fakeGitHub) RateLimit() github.RateLimitInfo {
+	return github.RateLimitInfo{} //nolint:exhaustruct
+}
+
+// fakeExporter rejects the metrics of the repositories listed in failing and
+// leaves those of the repositories in queued undelivered in the spool.
+type fakeExporter struct {
+	failing []string
+	queued  []string
+
+	mu         sync.Mutex
+	execPushed bool
+}
+
+func (e *fakeExporter) PushMetrics(_ context.Context, metrics *vmdb.Metrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, m := range metrics.Data {
+		if repo := m.Labels["repo"]; repo != "" {
+			if slices.Contains(e.failing, repo) {
+				return errPush
+			}
+
+			if slices.Contains(e.queued, repo) {
+				return fmt.Errorf("%w: %w", spool.ErrQueued, errPush)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *fakeExporter) PushExecTimestamp(context.Context, time.Time) error {
+	e.execPushed = true
+
+	return nil
+}
+
+func (e *fakeExporter) GetLastExecTimestamp(context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (e *fakeExporter) VerifyMetrics(context.Context, *vmdb.Metrics) error {
+	return nil
+}
+
+func TestScrapeAndPushIsolatesFailures(t *testing.T) {
+	type outcome struct {
+		status Status
+		stage  Stage
+	}
+
+	tests := []struct {
+		name       string
+		fetchErrs  map[string]error
+		reviewErrs map[string]error
+		failing    []string
+		queued     []string
+		failFast   bool
+		want       []outcome
+		wantErr    error
+		wantExec   bool
+	}{
+		{
+			name:     "all repositories succeed",
+			want:     []outcome{{StatusOK, ""}, {StatusOK, ""}, {StatusOK, ""}},
+			wantExec: true,
+		},
+		{
+			name:      "fetch failure does not stop the others",
+			fetchErrs: map[string]error{"o/b": errFetch},
+			want:      []outcome{{StatusOK, ""}, {StatusFailed, StageFetch}, {StatusOK, ""}},
+			wantErr:   errFetch,
+		},
+		{
+			name:       "PR failure holds back the execution timestamp",
+			reviewErrs: map[string]error{"o/b": errFetch},
+			want:       []outcome{{StatusOK, ""}, {StatusFailed, StageCollect}, {StatusOK, ""}},
+			wantErr:    errFetch,
+		},
+		{
+			name:    "push failure is recorded at its stage",
+			failing: []string{"o/c"},
+			want:    []outcome{{StatusOK, ""}, {StatusOK, ""}, {StatusFailed, StagePush}},
+			wantErr: errPush,
+		},
+		{
+			name:     "queued push counts as delivered later",
+			queued:   []string{"o/a"},
+			want:     []outcome{{StatusQueued, ""}, {StatusOK, ""}, {StatusOK, ""}},
+			wantExec: true,
+		},
+		{
+			name:      "missing repository is skipped",
+			fetchErrs: map[string]error{"o/a": github.ErrNotFound},
+			want:      []outcome{{StatusSkipped, ""}, {StatusOK, ""}, {StatusOK, ""}},
+			wantExec:  true,
+		},
+		{
+			name:      "fail-fast cancels the remaining repositories",
+			fetchErrs: map[string]error{"o/a": errFetch},
+			failFast:  true,
+			want:      []outcome{{StatusFailed, StageFetch}, {StatusCancelled, ""}, {StatusCancelled, ""}},
+			wantErr:   errFetch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ //nolint:exhaustruct
+				Repositories: []config.RepoConfig{
+					{Owner: "o", Repo: "a"}, //nolint:exhaustruct
+					{Owner: "o", Repo: "b"}, //nolint:exhaustruct
+					{Owner: "o", Repo: "c"}, //nolint:exhaustruct
+				},
+				RepoConcurrency: 1,
+				MaxConcurrency:  1,
+				Export:          config.ExportConfig{Aggregates: true}, //nolint:exhaustruct
+			}
+
+			exporter := &fakeExporter{failing: tt.failing, queued: tt.queued} //nolint:exhaustruct
+
+			gh := &fakeGitHub{errs: tt.fetchErrs, reviewErrs: tt.reviewErrs} //nolint:exhaustruct
+			m := NewMetricManager(exporter, gh)
+
+			report, err := m.ScrapeAndPush(context.Background(), cfg, RunOptions{FailFast: tt.failFast}) //nolint:exhaustruct
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("ScrapeAndPush: %v", err)
+			case tt.wantErr != nil && (!errors.Is(err, ErrRepositoriesFailed) || !errors.Is(err, tt.wantErr)):
+				t.Fatalf("got error %v, want %v and %v", err, ErrRepositoriesFailed, tt.wantErr)
+			}
+
+			for i, want := range tt.want {
+				got := report.Repositories[i]
+
+				if got.Status != want.status || got.Stage != want.stage {
+					t.Errorf("%s: got %s at %q, want %s at %q", got.Repository, got.Status, got.Stage, want.status, want.stage)
+				}
+
+				if got.Status == StatusOK && (got.PRsFound != 1 || got.PRsProcessed != 1 || got.Result == nil) {
+					t.Errorf("%s: found %d, processed %d PRs, result %v; want 1, 1 and a result",
+						got.Repository, got.PRsFound, got.PRsProcessed, got.Result)
+				}
+			}
+
+			if exporter.execPushed != tt.wantExec {
+				t.Errorf("execution timestamp pushed: %t, want %t", exporter.execPushed, tt.wantExec)
+			}
+		})
+	}
+}
+
+// vmStub stores imported samples and exports them deduplicated like
+// VictoriaMetrics with -dedup.minScrapeInterval set to window: of the samples
+// of a series within one window the one with the largest timestamp is kept,
+// and of samples with the same timestamp the largest value.
+type vmStub struct {
+	window int64
+
+	mu      sync.Mutex
+	samples map[string]map[int64][]float64
+}
+
+func newVMStub(t *testing.T, window time.Duration) (*vmStub, *httptest.Server) {
+	t.Helper()
+
+	stub := &vmStub{window: window.Milliseconds(), samples: make(map[string]map[int64][]float64)} //nolint:exhaustruct
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/import", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		metrics, err := vmdb.ParseJSON(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+
+		for _, m := range metrics.Data {
+			labels, _ := json.Marshal(maps.Collect(maps.All(m.Labels)))
+
+			series := stub.samples[string(labels)]
+			if series == nil {
+				series = make(map[int64][]float64)
+				stub.samples[string(labels)] = series
+			}
+
+			for i, value := range m.Values {
+				ts := int64(m.Timestamps[i])
+				series[ts] = append(series[ts], value.(float64))
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/api/v1/export", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+
+		for labels, samples := range stub.stored() {
+			var values []float64
+
+			var timestamps []int64
+
+			for ts, value := range samples {
+				if ts >= start*1000 && ts <= end*1000 {
+					timestamps = append(timestamps, ts)
+					values = append(values, value)
+				}
+			}
+
+			if len(values) > 0 {
+				line, _ := json.Marshal(map[string]any{"metric": json.RawMessage(labels), "values": values, "timestamps": timestamps})
+				fmt.Fprintf(w, "%s\n", line)
+			}
+		}
+	})
+
+	mux.HandleFunc("/api/v1/query", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return stub, server
+}
+
+// stored returns the deduplicated samples of every series by timestamp.
+func (s *vmStub) stored() map[string]map[int64]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := make(map[string]map[int64]float64, len(s.samples))
+
+	for labels, samples := range s.samples {
+		kept := make(map[int64]int64)
+
+		for ts := range samples {
+			if last, ok := kept[ts/s.window]; !ok || ts > last {
+				kept[ts/s.window] = ts
+			}
+		}
+
+		stored[labels] = make(map[int64]float64, len(kept))
+
+		for _, ts := range kept {
+			stored[labels][ts] = slices.Max(samples[ts])
+		}
+	}
+
+	return stored
+}
+
+func TestScrapeAndPushRerunReplacesSamples(t *testing.T) {
+	opened := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	closed := opened.Add(48 * time.Hour)
+
+	pr := func(number int, merged bool) github.PullRequest {
+		pr := github.PullRequest{ //nolint:exhaustruct
+			Number:    number,
+			State:     "closed",
+			CreatedAt: opened,
+			ClosedAt:  &closed,
+			User:      github.User{ID: 1, Login: "alice"},
+		}
+
+		if merged {
+			pr.MergedAt = &closed
+		}
+
+		return pr
+	}
+
+	// A window long enough for both runs to fall into it.
+	const alignMinutes = 1 << 20
+
+	stub, server := newVMStub(t, alignMinutes*time.Minute)
+
+	cfg := &config.Config{ //nolint:exhaustruct
+		Repositories:    []config.RepoConfig{{Owner: "o", Repo: "a"}}, //nolint:exhaustruct
+		RepoConcurrency: 1,
+		MaxConcurrency:  1,
+		Export:          config.ExportConfig{Aggregates: true, AlignMinutes: alignMinutes}, //nolint:exhaustruct
+		VictoriaMetrics: config.VictoriaMetricsConfig{Verify: true},                        //nolint:exhaustruct
+	}
+
+	exporter := vmdb.NewVMDBExporter(server.Client(), &vmdb.SingleNodeURL{VMURL: server.URL}, nil, "1y", vmdb.ImportOptions{}) //nolint:exhaustruct
+
+	gh := &fakeGitHub{prs: []github.PullRequest{pr(1, true), pr(2, true)}} //nolint:exhaustruct
+	m := NewMetricManager(exporter, gh)
+
+	// pushed returns the aggregates of the repository stored in VictoriaMetrics.
+	pushed := func() map[string]float64 {
+		values := make(map[string]float64)
+
+		for labels, samples := range stub.stored() {
+			var parsed map[string]string
+			if err := json.Unmarshal([]byte(labels), &parsed); err != nil || parsed["repo"] != "o/a" {
+				continue
+			}
+
+			if len(samples) != 1 {
+				t.Errorf("%s: got %d samples, want one per window", labels, len(samples))
+			}
+
+			for _, value := range samples {
+				values[labels] = value
+			}
+		}
+
+		return values
+	}
+
+	first, err := m.ScrapeAndPush(context.Background(), cfg, RunOptions{}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	before := pushed()
+
+	// Runs in the same millisecond share the sample timestamp.
+	time.Sleep(2 * time.Millisecond)
+
+	// One of the merges turned out to be a close, so the merge rate drops.
+	gh.prs = []github.PullRequest{pr(1, true), pr(2, false)}
+
+	second, err := m.ScrapeAndPush(context.Background(), cfg, RunOptions{}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+
+	if !second.Window.Equal(first.Window) || second.RunID != first.RunID {
+		t.Fatalf("rerun in window %v with ID %s, want window %v and ID %s", second.Window, second.RunID, first.Window, first.RunID)
+	}
+
+	after := pushed()
+
+	var lowered int
+
+	for series, value := range after {
+		if value < before[series] {
+			lowered++
+		}
+	}
+
+	if lowered == 0 {
+		t.Errorf("no value is lower after the rerun: before %v, after %v", before, after)
+	}
+
+	// Verification of the rerun reads back the values it pushed.
+	if failed := second.Failed(); len(failed) > 0 {
+		t.Errorf("rerun failed: %v", failed[0].Err())
+	}
+}
+
+func TestAlignTime(t *testing.T) {
+	at := func(hour, minute, second, ms int) time.Time {
+		return time.Date(2024, time.March, 10, hour, minute, second, ms*int(time.Millisecond), time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		t       time.Time
+		minutes int
+		want    time.Time
+	}{
+		{name: "zero keeps milliseconds", t: at(10, 7, 30, 123).Add(456), minutes: 0, want: at(10, 7, 30, 123)},
+		{name: "negative is like zero", t: at(10, 7, 30, 123), minutes: -5, want: at(10, 7, 30, 123)},
+		{name: "rounds down to the interval", t: at(10, 7, 30, 123), minutes: 5, want: at(10, 5, 0, 0)},
+		{name: "on the boundary", t: at(10, 5, 0, 0), minutes: 5, want: at(10, 5, 0, 0)},
+		{name: "last moment of the interval", t: at(10, 9, 59, 999), minutes: 5, want: at(10, 5, 0, 0)},
+		{name: "hourly", t: at(10, 59, 0, 0), minutes: 60, want: at(10, 0, 0, 0)},
+		{name: "daily since the epoch", t: at(23, 59, 0, 0), minutes: 24 * 60, want: at(0, 0, 0, 0)},
+		{
+			name:    "same instant in another zone",
+			t:       at(10, 7, 30, 0).In(time.FixedZone("UTC+5:30", 5*3600+1800)),
+			minutes: 60,
+			want:    at(10, 0, 0, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alignTime(tt.t, tt.minutes); !got.Equal(tt.want) {
+				t.Errorf("alignTime(%v, %d) = %v, want %v", tt.t, tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunID(t *testing.T) {
+	id := func(repos []string, parts ...string) string {
+		report := newRunReport(repos, false)
+		report.identify(parts...)
+
+		return report.RunID
+	}
+
+	base := id([]string{"o/a", "o/b"}, "1700000000000")
+
+	tests := []struct {
+		name string
+		id   string
+		same bool
+	}{
+		{name: "rerun", id: id([]string{"o/a", "o/b"}, "1700000000000"), same: true},
+		{name: "another sample time", id: id([]string{"o/a", "o/b"}, "1700000300000"), same: false},
+		{name: "another repository", id: id([]string{"o/a", "o/c"}, "1700000000000"), same: false},
+		{name: "fewer repositories", id: id([]string{"o/a"}, "1700000000000"), same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := tt.id == base; same != tt.same {
+				t.Errorf("run ID %s against %s: same %t, want %t", tt.id, base, same, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/github"
+	"metrics-scrapper/internal/model"
+	"time"
+)
+
+// Predict prints the predicted time to merge of PR number of repo. The model
+// saved for repo is used unless retrain is set; otherwise, or when there is
+// none yet, a model is trained on the full PR history and saved.
+func (m *MetricManager) Predict(ctx context.Context, cfg *config.Config, repo config.RepoConfig, number int, retrain bool) error {
+	path := model.Path(cfg.Model.Dir, repo.Owner, repo.Repo)
+
+	var mdl *model.Model
+
+	if !retrain {
+		loaded, err := model.Load(path)
+
+		switch {
+		case err == nil:
+			mdl = loaded
+			fmt.Printf("Loaded model trained %s on %d merged PRs\n", mdl.TrainedAt.Format(time.DateTime), mdl.Samples)
+		case errors.Is(err, fs.ErrNotExist):
+			fmt.Printf("No model for %s yet, training one\n", repo.Key())
+		case errors.Is(err, model.ErrIncompatibleModel):
+			fmt.Printf("Retraining: %v\n", err)
+		default:
+			return err
+		}
+	}
+
+	var (
+		pr      analyzer.PRMetrics
+		history *model.History
+		err     error
+	)
+
+	if mdl == nil {
+		mdl, pr, history, err = m.trainModel(ctx, cfg, repo, path, number)
+	} else {
+		pr, history, err = m.fetchPR(ctx, repo, number)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	printPrediction(pr, mdl.Predict(pr, history))
+
+	return nil
+}
+
+// trainModel trains a model of repo on its full history, saves it to path
+// and returns it along with PR number.
+func (m *MetricManager) trainModel(
+	ctx context.Context,
+	cfg *config.Config,
+	repo config.RepoConfig,
+	path string,
+	number int,
+) (*model.Model, analyzer.PRMetrics, *model.History, error) {
+	var pr analyzer.PRMetrics
+
+	metrics, err := m.fetchHistory(ctx, repo, analyzer.NewLimiter(cfg.MaxConcurrency))
+	if err != nil {
+		return nil, pr, nil, err
+	}
+
+	found := false
+
+	for _, m := range metrics {
+		if m.PRNumber == number {
+			pr, found = m, true
+		}
+	}
+
+	if !found {
+		return nil, pr, nil, prNotFound(repo, number)
+	}
+
+	history := model.NewHistory(metrics)
+
+	mdl, err := model.Train(repo.Key(), metrics, history, modelOptions(cfg))
+	if err != nil {
+		return nil, pr, nil, err
+	}
+
+	if err := mdl.Save(path); err != nil {
+		return nil, pr, nil, err
+	}
+
+	fmt.Printf("Trained on %d merged PRs, saved to %s\n", mdl.Samples, path)
+
+	return mdl, pr, history, nil
+}
+
+// fetchPR collects PR number of repo in full and the merge history of its
+// authors from the PR listing alone.
+func (m *MetricManager) fetchPR(ctx context.Context, repo config.RepoConfig, number int) (analyzer.PRMetrics, *model.History, error) {
+	var pr analyzer.PRMetrics
+
+	prs, err := m.GithubClient.GetAllPullRequests(ctx, repo.Owner, repo.Repo, time.Time{})
+	if err != nil {
+		return pr, nil, err
+	}
+
+	listed := make([]analyzer.PRMetrics, len(prs))
+
+	var target []github.PullRequest
+
+	for i, p := range prs {
+		listed[i] = analyzer.PullRequestMetrics(repo.Owner, repo.Repo, p)
+
+		if p.Number == number {
+			target = append(target, p)
+		}
+	}
+
+	if len(target) == 0 {
+		return pr, nil, prNotFound(repo, number)
+	}
+
+	metrics, prErrors, err := analyzer.CollectPRMetrics(ctx, m.GithubClient, repo.Owner, repo.Repo, target, analyzer.CollectOptions{
+		Concurrency: 1,
+		Limiter:     nil,
+		Sizes:       true,
+	})
+	if err != nil {
+		return pr, nil, err
+	}
+
+	if len(prErrors) > 0 {
+		return pr, nil, prErrors[0]
+	}
+
+	return metrics[0], model.NewHistory(listed), nil
+}
+
+// Backtest compares the model with the 70/30 heuristic on the newest holdout
+// share of the PRs of repo, see model.Backtest.
+func (m *MetricManager) Backtest(ctx context.Context, cfg *config.Config, repo config.RepoConfig, holdout float64) (*model.BacktestResult, error) {
+	metrics, err := m.fetchHistory(ctx, repo, analyzer.NewLimiter(cfg.MaxConcurrency))
+	if err != nil {
+		return nil, err
+	}
+
+	return model.Backtest(repo.Key(), metrics, holdout, modelOptions(cfg))
+}
+
+// fetchHistory collects the metrics of every PR of repo the page limits
+// reach. PRs whose details can't be fetched are left out.
+func (m *MetricManager) fetchHistory(ctx context.Context, repo config.RepoConfig, limiter analyzer.Limiter) ([]analyzer.PRMetrics, error) {
+	repoKey := repo.Key()
+
+	prs, err := m.GithubClient.GetAllPullRequests(ctx, repo.Owner, repo.Repo, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("%s: found %d pull requests\n", repoKey, len(prs))
+
+	metrics, prErrors, err := analyzer.CollectPRMetrics(ctx, m.GithubClient, repo.Owner, repo.Repo, prs, analyzer.CollectOptions{
+		Concurrency: repo.Concurrency,
+		Limiter:     limiter,
+		Sizes:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, prErr := range prErrors {
+		fmt.Printf("%s: skipping %v\n", repoKey, prErr)
+	}
+
+	return metrics, nil
+}
+
+func modelOptions(cfg *config.Config) model.Options {
+	return model.Options{
+		Lambda:    cfg.Model.Lambda,
+		MaxLabels: cfg.Model.MaxLabels,
+	}
+}
+
+func prNotFound(repo config.RepoConfig, number int) error {
+	return fmt.Errorf("%w: %s#%d is not within max_pages of the PR list", ErrPRNotFound, repo.Key(), number)
+}
+
+func printPrediction(pr analyzer.PRMetrics, prediction model.Prediction) {
+	fmt.Printf("\n=== %s#%d by %s, opened %s ===\n",
+		pr.Repository, pr.PRNumber, pr.Author, pr.CreatedAt.Format(time.DateTime))
+
+	fmt.Printf("Predicted time to merge: %v (80%% between %v and %v)\n",
+		prediction.Value.Round(time.Hour), prediction.Lower.Round(time.Hour), prediction.Upper.Round(time.Hour))
+
+	switch {
+	case pr.IsMerged:
+		fmt.Printf("Actually merged after %v\n", pr.TotalLifetime.Round(time.Hour))
+	case pr.State == "closed":
+		fmt.Printf("Closed without a merge after %v\n", pr.TotalLifetime.Round(time.Hour))
+	case pr.TotalLifetime > prediction.Value:
+		fmt.Printf("Open for %v, longer than predicted\n", pr.TotalLifetime.Round(time.Hour))
+	default:
+		fmt.Printf("Open for %v, expected merge around %s\n",
+			pr.TotalLifetime.Round(time.Hour), pr.CreatedAt.Add(prediction.Value).Format(time.DateTime))
+	}
+}
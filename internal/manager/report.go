@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"metrics-scrapper/internal/analyzer"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Stage is the step of repository processing a failure happened at.
+type Stage string
+
+const (
+	StageFetch   Stage = "fetch"
+	StageCollect Stage = "collect"
+	StageTotals  Stage = "totals"
+	StagePush    Stage = "push"
+	StageVerify  Stage = "verify"
+)
+
+// Status is the outcome of processing a single repository.
+type Status string
+
+// StatusQueued is a repository whose metrics were written to the spool but
+// not delivered yet. They are delivered before the next push or by `spool
+// flush`, so the run counts as successful, but they couldn't be verified.
+const (
+	StatusOK        Status = "ok"
+	StatusQueued    Status = "queued"
+	StatusSkipped   Status = "skipped"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// RepoReport describes how a single repository was processed.
+type RepoReport struct {
+	Repository string            `json:"repository"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Status     Status            `json:"status"`
+	Stage      Stage             `json:"stage,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Duration   time.Duration     `json:"duration_ns"`
+	PRsFound   int               `json:"prs_found"`
+	// PRsProcessed counts PRs whose metrics were collected, PRErrors lists
+	// the ones left out.
+	PRsProcessed int      `json:"prs_processed"`
+	PRErrors     []string `json:"pr_errors,omitempty"`
+
+	// Result is the analysis of a repository that was processed successfully
+	// and had PRs to analyze.
+	Result *analyzer.RepositoryResult `json:"-"`
+
+	err error
+}
+
+// Err returns the error the repository failed with, if any.
+func (r RepoReport) Err() error {
+	return r.err
+}
+
+func (r *RepoReport) fail(stage Stage, err error) {
+	r.Status = StatusFailed
+	r.Stage = stage
+	r.Error = err.Error()
+	r.err = err
+}
+
+// RunReport summarizes a ScrapeAndPush run. Repositories are listed in the
+// order of the configuration.
+//
+// Aggregates are timestamped at SampleTime, the start of the run. Window is
+// the start of the deduplication window the run falls into: VictoriaMetrics
+// keeps the samples of the latest run of a window, so a rerun replaces the
+// values of an earlier one. RunID is derived from the repositories and the
+// window, so such reruns share it.
+type RunReport struct {
+	RunID        string        `json:"run_id"`
+	SampleTime   time.Time     `json:"sample_time"`
+	Window       time.Time     `json:"window"`
+	StartedAt    time.Time     `json:"started_at"`
+	FinishedAt   time.Time     `json:"finished_at"`
+	Duration     time.Duration `json:"duration_ns"`
+	FailFast     bool          `json:"fail_fast"`
+	Repositories []RepoReport  `json:"repositories"`
+
+	mu sync.Mutex
+}
+
+func newRunReport(repos []string, failFast bool) *RunReport {
+	report := &RunReport{ //nolint:exhaustruct
+		StartedAt:    time.Now(),
+		FailFast:     failFast,
+		Repositories: make([]RepoReport, len(repos)),
+	}
+
+	// Repositories that are never reached (fail-fast) stay cancelled.
+	for i, repo := range repos {
+		report.Repositories[i] = RepoReport{ //nolint:exhaustruct
+			Repository: repo,
+			Status:     StatusCancelled,
+		}
+	}
+
+	return report
+}
+
+// identify sets RunID from the repositories of the report and the given
+// parts describing what the run pushes.
+func (r *RunReport) identify(parts ...string) {
+	hash := sha256.New()
+
+	for _, repo := range r.Repositories {
+		fmt.Fprintln(hash, repo.Repository)
+	}
+
+	for _, part := range parts {
+		fmt.Fprintln(hash, part)
+	}
+
+	r.RunID = hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func (r *RunReport) set(i int, repo RepoReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Repositories[i] = repo
+}
+
+func (r *RunReport) finish() {
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Failed returns the repositories that failed.
+func (r *RunReport) Failed() []RepoReport {
+	var failed []RepoReport
+
+	for _, repo := range r.Repositories {
+		if repo.Status == StatusFailed {
+			failed = append(failed, repo)
+		}
+	}
+
+	return failed
+}
+
+// Print writes a human readable summary of the run to w.
+func (r *RunReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n=== Run summary %s (%v) ===\n", r.RunID, r.Duration.Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "REPOSITORY\tSTATUS\tPRS\tDURATION\tERROR")
+
+	for _, repo := range r.Repositories {
+		errText := repo.Error
+		if repo.Stage != "" {
+			errText = fmt.Sprintf("%s: %s", repo.Stage, repo.Error)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%v\t%s\n",
+			repo.Repository, repo.Status, repo.PRsProcessed, repo.PRsFound,
+			repo.Duration.Round(time.Millisecond), errText)
+	}
+
+	tw.Flush()
+
+	for _, repo := range r.Repositories {
+		for _, prErr := range repo.PRErrors {
+			fmt.Fprintf(w, "%s: %s\n", repo.Repository, prErr)
+		}
+	}
+}
+
+// WriteJSON saves the report to path.
+func (r *RunReport) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingReport, err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingReport, err)
+	}
+
+	return nil
+}
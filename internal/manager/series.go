@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/vmdb"
+	"slices"
+	"strconv"
+)
+
+// Per-PR metric names. Durations are in seconds.
+const (
+	PRLifetimeSeconds          = "pr_lifetime_seconds"
+	PRTimeToFirstReviewSeconds = "pr_time_to_first_review_seconds"
+	PRComments                 = "pr_comments"
+	PRReviewers                = "pr_reviewers"
+
+	PRBusinessLifetimeSeconds          = "pr_business_lifetime_seconds"
+	PRBusinessTimeToFirstReviewSeconds = "pr_business_time_to_first_review_seconds"
+)
+
+// Values of the state label.
+const (
+	prStateMerged = "merged"
+	prStateClosed = "closed"
+)
+
+// addPRSamples adds the samples of every closed PR to vmMetrics, timestamped
+// at its merge or close time, so PromQL can aggregate any window after the
+// fact. Open PRs are skipped: their values are not final yet.
+func addPRSamples(vmMetrics *vmdb.Metrics, repo config.RepoConfig, prs []analyzer.PRMetrics, export config.ExportConfig) {
+	withLabel := func(name, value string) string {
+		if slices.Contains(export.PRLabels, name) {
+			return value
+		}
+
+		return ""
+	}
+
+	for _, pr := range prs {
+		closedAt, state := pr.ClosedAt, prStateClosed
+		if pr.IsMerged {
+			closedAt, state = pr.MergedAt, prStateMerged
+		}
+
+		if closedAt.IsZero() {
+			continue
+		}
+
+		add := func(name string, value float64) {
+			vmMetrics.AddPRSample(vmdb.PRSample{
+				Name:      name,
+				Repo:      repo.Key(),
+				PR:        withLabel(config.PRLabelNumber, strconv.Itoa(pr.PRNumber)),
+				Author:    withLabel(config.PRLabelAuthor, pr.Author),
+				State:     withLabel(config.PRLabelState, state),
+				Labels:    repo.Labels,
+				Value:     value,
+				Timestamp: uint64(closedAt.UnixMilli()),
+			})
+		}
+
+		add(PRLifetimeSeconds, seconds(pr.TotalLifetime))
+
+		if !pr.FirstReviewTime.IsZero() {
+			add(PRTimeToFirstReviewSeconds, seconds(pr.TimeToFirstReview))
+		}
+
+		if pr.Business != nil {
+			add(PRBusinessLifetimeSeconds, seconds(pr.Business.TotalLifetime))
+
+			if !pr.FirstReviewTime.IsZero() {
+				add(PRBusinessTimeToFirstReviewSeconds, seconds(pr.Business.TimeToFirstReview))
+			}
+		}
+
+		add(PRComments, float64(pr.CommentsCount))
+		add(PRReviewers, float64(len(pr.Reviewers)))
+	}
+}
@@ -0,0 +1,187 @@
+package manager
+
+import (
+	"maps"
+	"metrics-scrapper/internal/analyzer"
+	"strconv"
+	"time"
+)
+
+// SummaryMetric is a repository-level value derived from the analysis. The
+// same set is pushed by ScrapeAndPush and exposed by the serve command.
+// Labels tell apart the samples of one metric, e.g. its quantiles.
+type SummaryMetric struct {
+	Name   string
+	Help   string
+	Labels map[string]string
+	Value  float64
+}
+
+// SummaryMetrics returns the values of result. mergeWithinDays are the
+// horizons of the merge probabilities.
+func SummaryMetrics(result analyzer.AnalysisResult, mergeWithinDays []int) []SummaryMetric {
+	metrics := []SummaryMetric{
+		// 1. Общее время жизни PR
+		{
+			Name:  "PRLifetime",
+			Help:  "Median lifetime of pull requests, seconds.",
+			Value: seconds(result.MedianLifetime),
+		},
+		// 2. Время до первого ответа
+		{
+			Name:  "TimeToFirstReview",
+			Help:  "Average time from opening a pull request to its first review, seconds.",
+			Value: seconds(result.AverageTimeToFirstReview),
+		},
+		// 3. Процент успешных мержей
+		{
+			Name:  "MergeSuccessRate",
+			Help:  "Share of all pull requests, open ones included, that were merged, percent.",
+			Value: result.MergeRate,
+		},
+		// 4. Прогнозное время до мержа нового PR
+		{
+			Name:  "PredictedMergeTime",
+			Help:  "Legacy 70/30 estimate of the time to merge a new pull request, seconds. Deprecated, use SurvivalMergeTime.",
+			Value: seconds(result.HeuristicTimeToMerge),
+		},
+	}
+
+	// 5. Перцентили времени жизни и времени до первого ответа
+	metrics = appendQuantiles(metrics, "PRLifetimeQuantile",
+		"Quantiles of the lifetime of pull requests, seconds.", result.LifetimePercentiles)
+	metrics = appendQuantiles(metrics, "TimeToFirstReviewQuantile",
+		"Quantiles of the time from opening a pull request to its first review, seconds.",
+		result.TimeToFirstReviewPercentiles)
+
+	// 6. Прогноз мержа с учётом открытых PR: медиана с доверительным интервалом
+	// и вероятность мержа за N дней
+	metrics = appendEstimate(metrics, "SurvivalMergeTime",
+		"Median time to merge of pull requests that get merged, counting open ones as censored, seconds.",
+		result.MedianTimeToMerge)
+
+	for _, days := range mergeWithinDays {
+		p := result.MergeCurve.MergeProbability(time.Duration(days) * 24 * time.Hour)
+		labels := map[string]string{"days": strconv.Itoa(days)}
+
+		metrics = append(metrics,
+			SummaryMetric{
+				Name:   "MergeProbability",
+				Help:   "Probability that a pull request is merged within the given number of days.",
+				Labels: labels,
+				Value:  p.Value,
+			},
+			SummaryMetric{
+				Name:   "MergeProbabilityLower",
+				Help:   "Lower bound of the 95% confidence interval of MergeProbability.",
+				Labels: labels,
+				Value:  p.Lower,
+			},
+			SummaryMetric{
+				Name:   "MergeProbabilityUpper",
+				Help:   "Upper bound of the 95% confidence interval of MergeProbability.",
+				Labels: labels,
+				Value:  p.Upper,
+			},
+		)
+	}
+
+	// 7. Длительности в рабочем времени по календарю репозитория
+	if result.Business != nil {
+		metrics = append(metrics, businessMetrics(*result.Business)...)
+	}
+
+	return metrics
+}
+
+// businessMetrics returns the duration metrics of SummaryMetrics in working
+// time, under their own names.
+func businessMetrics(business analyzer.AnalysisResult) []SummaryMetric {
+	metrics := []SummaryMetric{
+		{
+			Name:  "BusinessPRLifetime",
+			Help:  "Median lifetime of pull requests in working hours, seconds.",
+			Value: seconds(business.MedianLifetime),
+		},
+		{
+			Name:  "BusinessTimeToFirstReview",
+			Help:  "Average time from opening a pull request to its first review in working hours, seconds.",
+			Value: seconds(business.AverageTimeToFirstReview),
+		},
+		{
+			Name:  "BusinessPredictedMergeTime",
+			Help:  "Legacy 70/30 estimate of the time to merge a new pull request in working hours, seconds. Deprecated, use BusinessSurvivalMergeTime.",
+			Value: seconds(business.HeuristicTimeToMerge),
+		},
+	}
+
+	metrics = appendEstimate(metrics, "BusinessSurvivalMergeTime",
+		"Median time to merge of pull requests that get merged in working hours, counting open ones as censored, seconds.",
+		business.MedianTimeToMerge)
+	metrics = appendQuantiles(metrics, "BusinessPRLifetimeQuantile",
+		"Quantiles of the lifetime of pull requests in working hours, seconds.", business.LifetimePercentiles)
+	metrics = appendQuantiles(metrics, "BusinessTimeToFirstReviewQuantile",
+		"Quantiles of the time from opening a pull request to its first review in working hours, seconds.",
+		business.TimeToFirstReviewPercentiles)
+
+	return metrics
+}
+
+func appendQuantiles(metrics []SummaryMetric, name, help string, percentiles analyzer.Percentiles) []SummaryMetric {
+	for i, value := range percentiles.Values() {
+		metrics = append(metrics, SummaryMetric{
+			Name:   name,
+			Help:   help,
+			Labels: map[string]string{"quantile": strconv.FormatFloat(analyzer.Quantiles[i], 'g', -1, 64)},
+			Value:  seconds(value),
+		})
+	}
+
+	return metrics
+}
+
+// appendEstimate appends the estimate and the bounds of its confidence interval
+// as name, nameLower and nameUpper. An unbounded upper end is left out.
+func appendEstimate(metrics []SummaryMetric, name, help string, estimate analyzer.DurationEstimate) []SummaryMetric {
+	metrics = append(metrics,
+		SummaryMetric{ //nolint:exhaustruct
+			Name:  name,
+			Help:  help,
+			Value: seconds(estimate.Value),
+		},
+		SummaryMetric{ //nolint:exhaustruct
+			Name:  name + "Lower",
+			Help:  "Lower bound of the 95% confidence interval of " + name + ", seconds.",
+			Value: seconds(estimate.Lower),
+		},
+	)
+
+	if estimate.Upper > 0 {
+		metrics = append(metrics, SummaryMetric{ //nolint:exhaustruct
+			Name:  name + "Upper",
+			Help:  "Upper bound of the 95% confidence interval of " + name + ", seconds.",
+			Value: seconds(estimate.Upper),
+		})
+	}
+
+	return metrics
+}
+
+// WithLabels returns the labels of a repository with those of a sample on
+// top. The result may share repoLabels and must not be modified.
+func WithLabels(repoLabels, sampleLabels map[string]string) map[string]string {
+	if len(sampleLabels) == 0 {
+		return repoLabels
+	}
+
+	labels := make(map[string]string, len(repoLabels)+len(sampleLabels))
+	maps.Copy(labels, repoLabels)
+	maps.Copy(labels, sampleLabels)
+
+	return labels
+}
+
+// seconds truncates to whole seconds as the metrics have always been pushed.
+func seconds(d time.Duration) float64 {
+	return float64(d / time.Second)
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"metrics-scrapper/internal/analyzer"
+	"metrics-scrapper/internal/config"
+	"os"
+	"path/filepath"
+)
+
+// totalsPath returns where the totals of repo are kept in dir.
+func totalsPath(dir string, repo config.RepoConfig) string {
+	return filepath.Join(dir, repo.Owner, repo.Repo+".json")
+}
+
+// loadTotals reads the totals of repo saved by a previous run. A repository
+// without saved totals starts from empty ones.
+func loadTotals(dir string, repo config.RepoConfig) (*analyzer.Totals, error) {
+	totals := &analyzer.Totals{} //nolint:exhaustruct
+
+	path := totalsPath(dir, repo)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return totals, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadingTotals, err)
+	}
+
+	if err := json.Unmarshal(data, totals); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoadingTotals, path, err)
+	}
+
+	return totals, nil
+}
+
+// saveTotals replaces the saved totals of repo atomically.
+func saveTotals(dir string, repo config.RepoConfig, totals *analyzer.Totals) error {
+	path := totalsPath(dir, repo)
+
+	data, err := json.Marshal(totals)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingTotals, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingTotals, err)
+	}
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingTotals, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingTotals, err)
+	}
+
+	return nil
+}
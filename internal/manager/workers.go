@@ -1,12 +1,14 @@
 package manager
 
 import (
+	"context"
 	"metrics-scrapper/internal/vmdb"
 	"time"
 )
 
 type VMDBExporter interface {
-	PushMetrics(collection *vmdb.Metrics) error
-	PushExecTimestamp(t time.Time) error
-	GetLastExecTimestamp() (time.Time, error)
+	PushMetrics(ctx context.Context, collection *vmdb.Metrics) error
+	PushExecTimestamp(ctx context.Context, t time.Time) error
+	GetLastExecTimestamp(ctx context.Context) (time.Time, error)
+	VerifyMetrics(ctx context.Context, collection *vmdb.Metrics) error
 }
@@ -0,0 +1,161 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"metrics-scrapper/internal/analyzer"
+)
+
+// minPercentageError is the least time to merge counted in MAPE: the relative
+// error of a PR merged in minutes says nothing about the predictor.
+const minPercentageError = time.Hour
+
+// Score is the error of a predictor over the merged PRs of the holdout. MAE is
+// the mean absolute error, MAPE the mean absolute percentage error over the
+// PRs that took at least minPercentageError.
+type Score struct {
+	Name string
+	MAE  time.Duration
+	MAPE float64
+}
+
+// BacktestResult compares the model with the repository-wide estimates on
+// the PRs opened after Cutoff.
+type BacktestResult struct {
+	Repository string
+	Cutoff     time.Time
+	// TrainPRs were opened before Cutoff; TrainMerged of them had been
+	// merged by then and made the training set.
+	TrainPRs    int
+	TrainMerged int
+	// TestMerged PRs opened after Cutoff have been merged and are scored,
+	// TestMAPE of them in MAPE. TestOpen are still open and not scored, so
+	// the longest waits of the holdout are missing from the scores.
+	TestMerged int
+	TestMAPE   int
+	TestOpen   int
+
+	Scores []Score
+}
+
+// Backtest trains a model on the oldest PRs of metrics as they looked when
+// the newest holdout share of PRs started to be opened, and scores it on the
+// merged PRs of that share. The 70/30 heuristic and the Kaplan–Meier median
+// are computed from the same training snapshot, so all predictors know the
+// same past.
+func Backtest(repository string, metrics []analyzer.PRMetrics, holdout float64, opts Options) (*BacktestResult, error) {
+	if holdout <= 0 || holdout >= 1 {
+		return nil, fmt.Errorf("%w: must be between 0 and 1, got %g", ErrInvalidHoldout, holdout)
+	}
+
+	sorted := slices.SortedFunc(slices.Values(metrics), func(a, b analyzer.PRMetrics) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	split := int(float64(len(sorted)) * (1 - holdout))
+	if split == 0 || split == len(sorted) {
+		return nil, fmt.Errorf("%w: have %d PRs", ErrNotEnoughData, len(sorted))
+	}
+
+	train, test := sorted[:split], sorted[split:]
+	cutoff := test[0].CreatedAt
+
+	// The window reaches the first PR, so nothing is left out but what
+	// happened after the cutoff.
+	snapshot := analyzer.AsOf(train, cutoff, cutoff.Sub(train[0].CreatedAt)+time.Nanosecond)
+	history := NewHistory(metrics)
+
+	model, err := Train(repository, snapshot, history, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := analyzer.AnalyzeData(snapshot)
+
+	result := &BacktestResult{ //nolint:exhaustruct
+		Repository:  repository,
+		Cutoff:      cutoff,
+		TrainPRs:    len(train),
+		TrainMerged: model.Samples,
+	}
+
+	predictors := []struct {
+		name    string
+		predict func(analyzer.PRMetrics) time.Duration
+	}{
+		{"model", func(pr analyzer.PRMetrics) time.Duration { return model.Predict(pr, history).Value }},
+		{"heuristic 70/30", func(analyzer.PRMetrics) time.Duration { return analysis.HeuristicTimeToMerge }},
+		{"kaplan-meier median", func(analyzer.PRMetrics) time.Duration { return analysis.MedianTimeToMerge.Value }},
+	}
+
+	var scored []analyzer.PRMetrics
+
+	for _, pr := range test {
+		if !pr.IsMerged {
+			if pr.State != "closed" {
+				result.TestOpen++
+			}
+
+			continue
+		}
+
+		scored = append(scored, pr)
+		result.TestMerged++
+
+		if pr.TotalLifetime >= minPercentageError {
+			result.TestMAPE++
+		}
+	}
+
+	if result.TestMerged == 0 {
+		return nil, fmt.Errorf("%w: no merged PRs opened since %s", ErrNotEnoughData, cutoff.Format(time.DateTime))
+	}
+
+	for _, predictor := range predictors {
+		var absolute, percentage float64
+
+		for _, pr := range scored {
+			actual := pr.TotalLifetime
+			errAbs := math.Abs(float64(predictor.predict(pr) - actual))
+
+			absolute += errAbs
+
+			if actual >= minPercentageError {
+				percentage += errAbs / float64(actual)
+			}
+		}
+
+		score := Score{Name: predictor.name, MAE: time.Duration(absolute / float64(result.TestMerged)), MAPE: 0}
+		if result.TestMAPE > 0 {
+			score.MAPE = percentage / float64(result.TestMAPE) * 100
+		}
+
+		result.Scores = append(result.Scores, score)
+	}
+
+	return result, nil
+}
+
+// Print writes the comparison to w.
+func (r *BacktestResult) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n=== Backtest %s ===\n", r.Repository)
+	fmt.Fprintf(w, "Trained on %d merged of %d PRs opened before %s\n",
+		r.TrainMerged, r.TrainPRs, r.Cutoff.Format(time.DateTime))
+	fmt.Fprintf(w, "Scored on %d merged PRs opened since, %d of them in MAPE\n", r.TestMerged, r.TestMAPE)
+	fmt.Fprintf(w, "%d PRs opened since are still open and not scored\n\n", r.TestOpen)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "PREDICTOR\tMAE (HOURS)\tMAPE")
+
+	for _, score := range r.Scores {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f%%\n", score.Name, score.MAE.Hours(), score.MAPE)
+	}
+
+	tw.Flush()
+}
@@ -0,0 +1,12 @@
+package model
+
+import "errors"
+
+var (
+	ErrNotEnoughData     = errors.New("not enough merged PRs to train on")
+	ErrSolvingModel      = errors.New("solving the regression")
+	ErrLoadingModel      = errors.New("loading model")
+	ErrSavingModel       = errors.New("saving model")
+	ErrIncompatibleModel = errors.New("incompatible model")
+	ErrInvalidHoldout    = errors.New("invalid holdout")
+)
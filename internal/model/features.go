@@ -0,0 +1,109 @@
+package model
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"metrics-scrapper/internal/analyzer"
+)
+
+// baseFeatures are the features every model has, in the order of its weights.
+// Counts with a long tail are taken as log(1+x). Indicators of the labels of
+// the model vocabulary follow them, named labelPrefix plus the label.
+//
+// Every feature is known when the PR is opened. Reviewers and comments are
+// not features: as of the creation they are zero, and counting them as of
+// the merge would leak the target, since PRs that wait longer collect more.
+var baseFeatures = []string{
+	"log_author_prior_merges",
+	"log_size",
+	"log_changed_files",
+	"created_sun",
+	"created_mon",
+	"created_tue",
+	"created_wed",
+	"created_thu",
+	"created_fri",
+	"created_sat",
+}
+
+const labelPrefix = "label:"
+
+// minLabelCount is how many training PRs must carry a label for it to become
+// a feature; rarer labels would only fit noise.
+const minLabelCount = 3
+
+// History tells how many PRs an author had merged before a moment, so every
+// PR is described by what was known when it was opened.
+type History struct {
+	merges map[string][]time.Time
+}
+
+// NewHistory indexes the merges of metrics.
+func NewHistory(metrics []analyzer.PRMetrics) *History {
+	h := &History{merges: make(map[string][]time.Time)}
+
+	for _, pr := range metrics {
+		if pr.IsMerged {
+			h.merges[pr.Author] = append(h.merges[pr.Author], pr.MergedAt)
+		}
+	}
+
+	for _, merges := range h.merges {
+		slices.SortFunc(merges, time.Time.Compare)
+	}
+
+	return h
+}
+
+// MergesBefore returns the number of PRs of author merged before at.
+func (h *History) MergesBefore(author string, at time.Time) int {
+	merges := h.merges[author]
+
+	return sort.Search(len(merges), func(i int) bool { return !merges[i].Before(at) })
+}
+
+// features returns the feature vector of m, unscaled.
+func (m *Model) features(pr analyzer.PRMetrics, history *History) []float64 {
+	x := make([]float64, len(baseFeatures)+len(m.Labels))
+
+	x[0] = math.Log1p(float64(history.MergesBefore(pr.Author, pr.CreatedAt)))
+	x[1] = math.Log1p(float64(pr.Additions + pr.Deletions))
+	x[2] = math.Log1p(float64(pr.ChangedFiles))
+	x[3+int(pr.CreatedAt.UTC().Weekday())] = 1
+
+	for _, label := range pr.Labels {
+		if i, found := slices.BinarySearch(m.Labels, label); found {
+			x[len(baseFeatures)+i] = 1
+		}
+	}
+
+	return x
+}
+
+// vocabulary returns up to limit labels carried by at least minLabelCount of
+// metrics, the most frequent first, sorted by name.
+func vocabulary(metrics []analyzer.PRMetrics, limit int) []string {
+	counts := make(map[string]int)
+
+	for _, pr := range metrics {
+		for _, label := range pr.Labels {
+			counts[label]++
+		}
+	}
+
+	labels := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	labels = slices.DeleteFunc(labels, func(label string) bool { return counts[label] < minLabelCount })
+	labels = labels[:min(len(labels), limit)]
+
+	slices.Sort(labels)
+
+	return labels
+}
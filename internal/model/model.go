@@ -0,0 +1,251 @@
+// Package model predicts the time to merge of a single PR from what is known
+// when it is opened: the merge history of its author, its size, the day it
+// was opened and its labels.
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"metrics-scrapper/internal/analyzer"
+)
+
+// version is bumped whenever the features change, so models saved by older
+// releases are retrained instead of being fed the wrong vector.
+const version = 2
+
+// minSamples is the least number of merged PRs a model is trained on.
+const minSamples = 10
+
+// Options controls training.
+type Options struct {
+	// Lambda is the ridge penalty on the standardised weights.
+	Lambda float64
+	// MaxLabels is the size of the label vocabulary.
+	MaxLabels int
+}
+
+// Model is a ridge regression of log(1 + hours to merge) on the standardised
+// features of merged PRs. Working on the log scale makes it predict the
+// typical time rather than the mean dragged up by the few PRs that wait for
+// months.
+type Model struct {
+	Version    int       `json:"version"`
+	Repository string    `json:"repository"`
+	TrainedAt  time.Time `json:"trained_at"`
+	Samples    int       `json:"samples"`
+	Lambda     float64   `json:"lambda"`
+
+	Features []string `json:"features"`
+	// Labels is the vocabulary, sorted.
+	Labels []string `json:"labels"`
+
+	Mean      []float64 `json:"mean"`
+	Scale     []float64 `json:"scale"`
+	Weights   []float64 `json:"weights"`
+	Intercept float64   `json:"intercept"`
+
+	// Residuals are the 10th and 90th percentiles of the training residuals
+	// on the log scale, which bound the 80% prediction interval.
+	Residuals [2]float64 `json:"residuals"`
+}
+
+// Prediction is a predicted time to merge, counted from the PR creation, with
+// its 80% interval.
+type Prediction struct {
+	Value time.Duration
+	Lower time.Duration
+	Upper time.Duration
+}
+
+// Train fits a model to the merged PRs of metrics. history describes the
+// authors as of each PR creation and may span more PRs than metrics.
+func Train(repository string, metrics []analyzer.PRMetrics, history *History, opts Options) (*Model, error) {
+	var merged []analyzer.PRMetrics
+
+	for _, pr := range metrics {
+		if pr.IsMerged {
+			merged = append(merged, pr)
+		}
+	}
+
+	if len(merged) < minSamples {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrNotEnoughData, len(merged), minSamples)
+	}
+
+	model := &Model{ //nolint:exhaustruct
+		Version:    version,
+		Repository: repository,
+		TrainedAt:  time.Now().UTC(),
+		Samples:    len(merged),
+		Lambda:     opts.Lambda,
+		Labels:     vocabulary(merged, opts.MaxLabels),
+	}
+
+	model.Features = slices.Clone(baseFeatures)
+	for _, label := range model.Labels {
+		model.Features = append(model.Features, labelPrefix+label)
+	}
+
+	k := len(model.Features)
+	n := float64(len(merged))
+
+	x := make([][]float64, len(merged))
+	y := make([]float64, len(merged))
+
+	model.Mean = make([]float64, k)
+	model.Scale = make([]float64, k)
+
+	for i, m := range merged {
+		x[i] = model.features(m, history)
+		y[i] = target(m.TotalLifetime)
+
+		for j, v := range x[i] {
+			model.Mean[j] += v / n
+		}
+
+		model.Intercept += y[i] / n
+	}
+
+	for _, xs := range x {
+		for j, v := range xs {
+			model.Scale[j] += (v - model.Mean[j]) * (v - model.Mean[j]) / n
+		}
+	}
+
+	for j, variance := range model.Scale {
+		// Constant features are centred to zero and get no weight.
+		model.Scale[j] = 1
+		if variance > 0 {
+			model.Scale[j] = math.Sqrt(variance)
+		}
+	}
+
+	centred := make([]float64, len(y))
+
+	for i := range x {
+		model.standardise(x[i])
+		centred[i] = y[i] - model.Intercept
+	}
+
+	weights, err := ridge(x, centred, opts.Lambda)
+	if err != nil {
+		return nil, err
+	}
+
+	model.Weights = weights
+
+	residuals := make([]float64, len(y))
+	for i := range x {
+		residuals[i] = y[i] - model.predict(x[i])
+	}
+
+	slices.Sort(residuals)
+	model.Residuals = [2]float64{percentile(residuals, 0.1), percentile(residuals, 0.9)}
+
+	return model, nil
+}
+
+// Predict returns the predicted time to merge of pr.
+func (m *Model) Predict(pr analyzer.PRMetrics, history *History) Prediction {
+	x := m.features(pr, history)
+	m.standardise(x)
+
+	y := m.predict(x)
+
+	return Prediction{
+		Value: duration(y),
+		Lower: duration(y + m.Residuals[0]),
+		Upper: duration(y + m.Residuals[1]),
+	}
+}
+
+func (m *Model) standardise(x []float64) {
+	for j := range x {
+		x[j] = (x[j] - m.Mean[j]) / m.Scale[j]
+	}
+}
+
+// predict returns the target predicted for the standardised features x.
+func (m *Model) predict(x []float64) float64 {
+	y := m.Intercept
+	for j, w := range m.Weights {
+		y += w * x[j]
+	}
+
+	return y
+}
+
+// target is the regression target of a time to merge.
+func target(d time.Duration) float64 {
+	return math.Log1p(d.Hours())
+}
+
+// duration is the inverse of target.
+func duration(y float64) time.Duration {
+	return time.Duration(max(math.Expm1(y), 0) * float64(time.Hour))
+}
+
+// percentile returns the q-th percentile of the sorted values.
+func percentile(sorted []float64, q float64) float64 {
+	return sorted[int(math.Round(q*float64(len(sorted)-1)))]
+}
+
+// Path returns where the model of owner/repo is kept in dir.
+func Path(dir, owner, repo string) string {
+	return filepath.Join(dir, owner, repo+".json")
+}
+
+// Save writes the model to path, replacing the previous one atomically.
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingModel, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingModel, err)
+	}
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingModel, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("%w: %w", ErrSavingModel, err)
+	}
+
+	return nil
+}
+
+// Load reads a model saved by Save. A missing file is reported as
+// fs.ErrNotExist.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadingModel, err)
+	}
+
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoadingModel, path, err)
+	}
+
+	if m.Version != version {
+		return nil, fmt.Errorf("%w: %s has version %d, want %d", ErrIncompatibleModel, path, m.Version, version)
+	}
+
+	k := len(baseFeatures) + len(m.Labels)
+	if len(m.Features) != k || len(m.Mean) != k || len(m.Scale) != k || len(m.Weights) != k {
+		return nil, fmt.Errorf("%w: %s: want %d features", ErrIncompatibleModel, path, k)
+	}
+
+	return &m, nil
+}
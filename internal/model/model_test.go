@@ -0,0 +1,85 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/analyzer"
+)
+
+// sizedPRs returns n merged PRs whose time to merge grows with their size.
+func sizedPRs(n int) []analyzer.PRMetrics {
+	created := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	metrics := make([]analyzer.PRMetrics, n)
+
+	for i := range metrics {
+		size := 10 << (i % 8)
+
+		metrics[i] = analyzer.PRMetrics{ //nolint:exhaustruct
+			PRNumber:      i + 1,
+			Author:        fmt.Sprintf("author%d", i%3),
+			IsMerged:      true,
+			CreatedAt:     created.Add(time.Duration(i) * 7 * time.Hour),
+			Additions:     size,
+			ChangedFiles:  1 + i%8,
+			TotalLifetime: time.Duration(size) * time.Minute,
+		}
+	}
+
+	return metrics
+}
+
+func TestTrain(t *testing.T) {
+	tests := []struct {
+		name    string
+		prs     int
+		wantErr error
+	}{
+		{name: "too few merged PRs", prs: minSamples - 1, wantErr: ErrNotEnoughData},
+		{name: "enough merged PRs", prs: 64, wantErr: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := sizedPRs(tt.prs)
+			history := NewHistory(metrics)
+
+			model, err := Train("o/r", metrics, history, Options{Lambda: 1, MaxLabels: 10})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			small := model.Predict(metrics[0], history)
+			large := model.Predict(metrics[7], history)
+
+			if small.Value >= large.Value {
+				t.Errorf("predicted %v for a small PR and %v for a large one", small.Value, large.Value)
+			}
+
+			if small.Lower > small.Value || small.Upper < small.Value {
+				t.Errorf("prediction %v outside its interval [%v, %v]", small.Value, small.Lower, small.Upper)
+			}
+
+			path := filepath.Join(t.TempDir(), "model.json")
+			if err := model.Save(path); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			if got := loaded.Predict(metrics[7], history); got != large {
+				t.Errorf("loaded model predicts %v, want %v", got, large)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+	"math"
+)
+
+// ridge returns the weights w minimising |Xw - y|² + lambda·|w|². The columns
+// of x and y must be centred, so the intercept is the mean of the target.
+func ridge(x [][]float64, y []float64, lambda float64) ([]float64, error) {
+	k := len(x[0])
+
+	// Normal equations (XᵀX + λI)w = Xᵀy. The matrix is symmetric positive
+	// definite for any positive lambda.
+	a := make([][]float64, k)
+	b := make([]float64, k)
+
+	for i := range k {
+		a[i] = make([]float64, k)
+		a[i][i] = lambda
+	}
+
+	for row, xs := range x {
+		for i := range k {
+			b[i] += xs[i] * y[row]
+
+			for j := range i + 1 {
+				a[i][j] += xs[i] * xs[j]
+			}
+		}
+	}
+
+	// Cholesky decomposition A = LLᵀ, stored in the lower triangle of a.
+	for i := range k {
+		for j := range i + 1 {
+			sum := a[i][j]
+			for p := range j {
+				sum -= a[i][p] * a[j][p]
+			}
+
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("%w: matrix is not positive definite", ErrSolvingModel)
+				}
+
+				a[i][i] = math.Sqrt(sum)
+			} else {
+				a[i][j] = sum / a[j][j]
+			}
+		}
+	}
+
+	// Forward substitution Lz = b, then back substitution Lᵀw = z.
+	w := make([]float64, k)
+
+	for i := range k {
+		sum := b[i]
+		for p := range i {
+			sum -= a[i][p] * w[p]
+		}
+
+		w[i] = sum / a[i][i]
+	}
+
+	for i := k - 1; i >= 0; i-- {
+		sum := w[i]
+		for p := i + 1; p < k; p++ {
+			sum -= a[p][i] * w[p]
+		}
+
+		w[i] = sum / a[i][i]
+	}
+
+	return w, nil
+}
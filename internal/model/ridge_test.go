@@ -0,0 +1,137 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestRidge(t *testing.T) {
+	tests := []struct {
+		name   string
+		x      [][]float64
+		y      []float64
+		lambda float64
+		want   []float64
+	}{
+		{
+			name:   "negligible penalty recovers exact weights",
+			x:      [][]float64{{1, 0}, {0, 1}, {-1, 0}, {0, -1}},
+			y:      []float64{2, -3, -2, 3},
+			lambda: 1e-9,
+			want:   []float64{2, -3},
+		},
+		{
+			// XᵀX = 2I and Xᵀy = (4, -6), so w = Xᵀy / (2 + λ).
+			name:   "penalty shrinks orthogonal weights",
+			x:      [][]float64{{1, 0}, {0, 1}, {-1, 0}, {0, -1}},
+			y:      []float64{2, -3, -2, 3},
+			lambda: 2,
+			want:   []float64{1, -1.5},
+		},
+		{
+			// XᵀX is singular; the penalty splits the weight evenly.
+			name:   "duplicate features",
+			x:      [][]float64{{1, 1}, {-1, -1}},
+			y:      []float64{2, -2},
+			lambda: 1,
+			want:   []float64{0.8, 0.8},
+		},
+		{
+			name:   "single feature",
+			x:      [][]float64{{1}, {2}, {-3}},
+			y:      []float64{2, 4, -6},
+			lambda: 1,
+			want:   []float64{28.0 / 15},
+		},
+		{
+			name:   "constant feature gets no weight",
+			x:      [][]float64{{0, 1}, {0, -1}},
+			y:      []float64{1, -1},
+			lambda: 1,
+			want:   []float64{0, 2.0 / 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ridge(tt.x, tt.y, tt.lambda)
+			if err != nil {
+				t.Fatalf("ridge: %v", err)
+			}
+
+			for i := range tt.want {
+				if math.Abs(w[i]-tt.want[i]) > 1e-6 {
+					t.Errorf("got weights %v, want %v", w, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// TestRidgeNormalEquations checks that the weights solve the normal equations
+// (XᵀX + λI)w = Xᵀy on correlated random data.
+func TestRidgeNormalEquations(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+
+	for _, k := range []int{1, 3, 10, 30} {
+		x := make([][]float64, 200)
+		y := make([]float64, len(x))
+
+		for i := range x {
+			x[i] = make([]float64, k)
+
+			common := rng.NormFloat64()
+			for j := range x[i] {
+				x[i][j] = common + 0.1*rng.NormFloat64()
+				y[i] += float64(j%3-1) * x[i][j]
+			}
+
+			y[i] += rng.NormFloat64()
+		}
+
+		const lambda = 0.5
+
+		w, err := ridge(x, y, lambda)
+		if err != nil {
+			t.Fatalf("k=%d: ridge: %v", k, err)
+		}
+
+		for i := range k {
+			residual := lambda * w[i]
+			for row, xs := range x {
+				for j := range k {
+					residual += xs[i] * xs[j] * w[j]
+				}
+
+				residual -= xs[i] * y[row]
+			}
+
+			if math.Abs(residual) > 1e-6 {
+				t.Errorf("k=%d: normal equation %d off by %g", k, i, residual)
+			}
+		}
+	}
+}
+
+func TestRidgeNotPositiveDefinite(t *testing.T) {
+	tests := []struct {
+		name   string
+		x      [][]float64
+		lambda float64
+	}{
+		{name: "constant feature without a penalty", x: [][]float64{{0, 1}, {0, -1}}, lambda: 0},
+		{name: "negative penalty", x: [][]float64{{1, 1}, {-1, -1}}, lambda: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ridge(tt.x, []float64{1, -1}, tt.lambda)
+			if !errors.Is(err, ErrSolvingModel) {
+				t.Errorf("got %v, want %v", err, ErrSolvingModel)
+			}
+		})
+	}
+}
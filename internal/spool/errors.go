@@ -0,0 +1,12 @@
+package spool
+
+import "errors"
+
+var (
+	ErrOpeningSpool    = errors.New("opening spool")
+	ErrSpoolFull       = errors.New("spool is full")
+	ErrWritingBatch    = errors.New("writing spool batch")
+	ErrReadingSpool    = errors.New("reading spool")
+	ErrDeliveringBatch = errors.New("delivering spool batch")
+	ErrQueued          = errors.New("queued, not delivered")
+)
@@ -0,0 +1,405 @@
+// Package spool is a write-ahead queue in front of a metrics exporter. Every
+// batch is written to disk before it is sent, so pushes that fail because
+// VictoriaMetrics is unavailable are not lost and are delivered, in order, by
+// a later push or `spool flush`, also after a restart.
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"metrics-scrapper/internal/config"
+	"metrics-scrapper/internal/vmdb"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchExt = ".jsonl"
+	// badExt marks batches that can't be parsed or were rejected by the
+	// target as invalid. They are set aside so they don't block the batches
+	// after them.
+	badExt = ".bad"
+	// tmpExt marks batches being written.
+	tmpExt = ".tmp"
+)
+
+// Exporter is the exporter batches are delivered to.
+type Exporter interface {
+	PushMetrics(ctx context.Context, metrics *vmdb.Metrics) error
+	PushExecTimestamp(ctx context.Context, t time.Time) error
+	GetLastExecTimestamp(ctx context.Context) (time.Time, error)
+	VerifyMetrics(ctx context.Context, metrics *vmdb.Metrics) error
+}
+
+// Spool implements Exporter on top of target. Only pushes are spooled;
+// queries go to target directly.
+//
+// Batches are delivered at least once: a batch may be sent again if the
+// process stops between delivering and removing it, or if two processes
+// flush the same spool. Samples have deterministic timestamps, so a repeated
+// delivery overwrites the same samples.
+type Spool struct {
+	Exporter
+
+	dir         string
+	maxSize     int64
+	chunkSize   int
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	// mu guards the batches being written and the size of the spool.
+	mu      sync.Mutex
+	nextSeq uint64
+	size    int64
+
+	// deliveryMu serializes deliveries, so batches go out in order. After a
+	// failed delivery pushes don't retry before retryAt, so a dead endpoint
+	// holds up one push rather than every push waiting through the retries.
+	deliveryMu sync.Mutex
+	retryAt    time.Time
+	lastErr    error
+}
+
+// Batch is a spooled push waiting for delivery.
+type Batch struct {
+	Seq       uint64
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// New opens the spool in dir, creating it if needed. Pushes are split into
+// batches of at most chunkSize bytes, which are retried maxAttempts times with
+// exponential backoff from baseDelay up to maxDelay. A maxSize of zero doesn't
+// limit the spool, a chunkSize of zero spools every push as one batch.
+func New(
+	target Exporter,
+	dir string,
+	maxSize int64,
+	chunkSize int,
+	maxAttempts int,
+	baseDelay time.Duration,
+	maxDelay time.Duration,
+) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningSpool, err)
+	}
+
+	s := &Spool{ //nolint:exhaustruct
+		Exporter:    target,
+		dir:         dir,
+		maxSize:     maxSize,
+		chunkSize:   chunkSize,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		nextSeq:     1,
+	}
+
+	// A crash between writing a batch and renaming it leaves a partial
+	// file behind that would never be picked up.
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*"+batchExt+tmpExt))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningSpool, err)
+	}
+
+	for _, path := range leftovers {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", ErrOpeningSpool, err)
+		}
+	}
+
+	batches, err := s.Batches()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpeningSpool, err)
+	}
+
+	for _, batch := range batches {
+		s.size += batch.Size
+		s.nextSeq = batch.Seq + 1
+	}
+
+	return s, nil
+}
+
+// FromConfig opens the spool described by cfg in front of target, splitting
+// pushes into batches of chunkSize bytes.
+func FromConfig(target Exporter, cfg config.SpoolConfig, chunkSize int) (*Spool, error) {
+	return New(
+		target,
+		cfg.Dir,
+		int64(cfg.MaxSizeMB)<<20,
+		chunkSize,
+		cfg.MaxAttempts,
+		time.Duration(cfg.BaseDelayMS)*time.Millisecond,
+		time.Duration(cfg.MaxDelayMS)*time.Millisecond,
+	)
+}
+
+// Dir returns the spool directory.
+func (s *Spool) Dir() string {
+	return s.dir
+}
+
+// MaxSize returns the size limit in bytes, zero for none.
+func (s *Spool) MaxSize() int64 {
+	return s.maxSize
+}
+
+// PushMetrics spools metrics and delivers every pending batch. The metrics
+// are encoded straight into batches of at most chunkSize bytes, so the whole
+// payload is never held in memory; when the spool can't take the next batch,
+// the pending ones are delivered first, so a push larger than the spool still
+// goes through.
+//
+// A batch that can't be delivered stays in the spool, together with the ones
+// after it, and the error is ErrQueued: the data is safe on disk and will be
+// delivered by a later push or Flush. Any other error means the metrics were
+// not spooled.
+func (s *Spool) PushMetrics(ctx context.Context, metrics *vmdb.Metrics) error {
+	if len(metrics.Data) == 0 {
+		return nil
+	}
+
+	for data, err := range metrics.JSONChunks(s.chunkSize) {
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrWritingBatch, err)
+		}
+
+		s.mu.Lock()
+		fits := s.fits(len(data))
+		s.mu.Unlock()
+
+		if !fits {
+			if _, _, err := s.flush(ctx, false); err != nil {
+				return fmt.Errorf("%w: %d more bytes needed: %w", ErrSpoolFull, len(data), err)
+			}
+		}
+
+		s.mu.Lock()
+		err := s.enqueue(data)
+		s.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, pending, err := s.flush(ctx, false); err != nil {
+		return fmt.Errorf("%w: %d batches kept in %s: %w", ErrQueued, pending, s.dir, err)
+	}
+
+	return nil
+}
+
+func (s *Spool) PushExecTimestamp(ctx context.Context, t time.Time) error {
+	metrics := vmdb.Metrics{} //nolint:exhaustruct
+
+	metrics.AddExecTimeMetric(
+		uint64(t.UnixMilli()),
+		uint64(t.UnixMilli()),
+	)
+
+	return s.PushMetrics(ctx, &metrics)
+}
+
+// Flush delivers every pending batch in order, also right after a failed
+// delivery. It stops at the first batch that can't be delivered and returns
+// the number of delivered and pending batches.
+func (s *Spool) Flush(ctx context.Context) (int, int, error) {
+	return s.flush(ctx, true)
+}
+
+// flush delivers the pending batches. Unless force is set, nothing is sent
+// before retryAt and the error of the failed delivery is returned instead.
+func (s *Spool) flush(ctx context.Context, force bool) (int, int, error) {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	batches, err := s.Batches()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !force && time.Now().Before(s.retryAt) {
+		return 0, len(batches), fmt.Errorf("%w, next attempt at %s", s.lastErr, s.retryAt.Format(time.DateTime))
+	}
+
+	for i, batch := range batches {
+		setAside, err := s.deliver(ctx, batch)
+		if err != nil {
+			err = fmt.Errorf("%w %d: %w", ErrDeliveringBatch, batch.Seq, err)
+			s.retryAt, s.lastErr = time.Now().Add(s.maxDelay), err
+
+			return i, len(batches) - i, err
+		}
+
+		if !setAside {
+			if err := os.Remove(batch.Path); err != nil {
+				return i, len(batches) - i, fmt.Errorf("%w: %w", ErrWritingBatch, err)
+			}
+		}
+
+		s.mu.Lock()
+		s.size -= batch.Size
+		s.mu.Unlock()
+	}
+
+	s.retryAt, s.lastErr = time.Time{}, nil
+
+	return len(batches), 0, nil
+}
+
+// deliver sends batch to the target, retrying with backoff. A corrupted batch
+// and a batch the target rejects as invalid (vmdb.ErrRejected) would never be
+// delivered and would hold up the ones after them; they are renamed with
+// badExt instead and reported as set aside, so they are no longer pending.
+func (s *Spool) deliver(ctx context.Context, batch Batch) (setAside bool, err error) {
+	data, err := os.ReadFile(batch.Path)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrReadingSpool, err)
+	}
+
+	metrics, err := vmdb.ParseJSON(data)
+	if err != nil {
+		return true, s.setAside(batch, "is corrupted", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = s.Exporter.PushMetrics(ctx, metrics)
+		if errors.Is(err, vmdb.ErrRejected) {
+			return true, s.setAside(batch, "was rejected", err)
+		}
+
+		if err == nil || attempt+1 >= s.maxAttempts {
+			return false, err
+		}
+
+		delay := s.maxDelay
+		if attempt < 32 {
+			delay = min(s.maxDelay, s.baseDelay<<attempt)
+		}
+
+		fmt.Printf("Spool batch %d: %v, retrying in %v\n", batch.Seq, err, delay)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// fits reports whether a batch of size bytes fits in the spool.
+func (s *Spool) fits(size int) bool {
+	return s.maxSize <= 0 || s.size+int64(size) <= s.maxSize
+}
+
+// setAside renames batch with badExt, so it is kept for inspection but not
+// delivered.
+func (s *Spool) setAside(batch Batch, reason string, cause error) error {
+	fmt.Printf("Spool batch %d %s, moving it aside: %v\n", batch.Seq, reason, cause)
+
+	if err := os.Rename(batch.Path, batch.Path+badExt); err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingBatch, err)
+	}
+
+	return nil
+}
+
+// enqueue writes data as the next batch. The file appears under its final
+// name only once it is fully written and synced.
+func (s *Spool) enqueue(data []byte) error {
+	if !s.fits(len(data)) {
+		return fmt.Errorf("%w: %d of %d bytes used, %d more needed", ErrSpoolFull, s.size, s.maxSize, len(data))
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.nextSeq, batchExt))
+	tmp := path + tmpExt
+
+	if err := writeSynced(tmp, data); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return fmt.Errorf("%w: %w", ErrWritingBatch, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("%w: %w", ErrWritingBatch, err)
+	}
+
+	s.nextSeq++
+	s.size += int64(len(data))
+
+	return nil
+}
+
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()  //nolint:errcheck
+		return err //nolint:wrapcheck
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()  //nolint:errcheck
+		return err //nolint:wrapcheck
+	}
+
+	return f.Close() //nolint:wrapcheck
+}
+
+// Batches lists the pending batches in delivery order.
+func (s *Spool) Batches() ([]Batch, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadingSpool, err)
+	}
+
+	var batches []Batch
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), batchExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReadingSpool, err)
+		}
+
+		batches = append(batches, Batch{
+			Seq:       seq,
+			Path:      filepath.Join(s.dir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+
+	sort.Slice(batches, func(i, j int) bool { return batches[i].Seq < batches[j].Seq })
+
+	return batches, nil
+}
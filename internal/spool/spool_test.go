@@ -0,0 +1,317 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics-scrapper/internal/vmdb"
+)
+
+var errDown = errors.New("endpoint down")
+
+// fakeTarget records the series names of every delivered batch, or fails
+// with err. Batches with a series listed in rejected are rejected as invalid.
+type fakeTarget struct {
+	mu        sync.Mutex
+	err       error
+	rejected  []string
+	attempts  int
+	delivered [][]string
+}
+
+func (f *fakeTarget) PushMetrics(_ context.Context, metrics *vmdb.Metrics) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.attempts++
+
+	if f.err != nil {
+		return f.err
+	}
+
+	names := make([]string, len(metrics.Data))
+	for i, m := range metrics.Data {
+		names[i] = m.Labels["__name__"]
+
+		if slices.Contains(f.rejected, names[i]) {
+			return fmt.Errorf("400, %w", vmdb.ErrRejected)
+		}
+	}
+
+	f.delivered = append(f.delivered, names)
+
+	return nil
+}
+
+func (f *fakeTarget) PushExecTimestamp(context.Context, time.Time) error {
+	return nil
+}
+
+func (f *fakeTarget) GetLastExecTimestamp(context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (f *fakeTarget) VerifyMetrics(context.Context, *vmdb.Metrics) error {
+	return nil
+}
+
+// series returns metrics with one sample of each named series.
+func series(names ...string) *vmdb.Metrics {
+	metrics := &vmdb.Metrics{} //nolint:exhaustruct
+	for i, name := range names {
+		metrics.AddPRMetric(name, "o/r", nil, float64(i), 1700000000000)
+	}
+
+	return metrics
+}
+
+// seriesSize is the encoded size of a series made by series.
+var seriesSize = func() int {
+	data, err := series("s0").ExportToJSON()
+	if err != nil {
+		panic(err)
+	}
+
+	return len(data)
+}()
+
+// newTestSpool opens a spool in dir, a new directory when empty, delivering
+// every batch once and pausing deliveries for a minute after a failure.
+func newTestSpool(t *testing.T, target Exporter, dir string, maxSize int64, chunkSize int) *Spool {
+	t.Helper()
+
+	if dir == "" {
+		dir = t.TempDir()
+	}
+
+	s, err := New(target, dir, maxSize, chunkSize, 1, time.Millisecond, time.Minute)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return s
+}
+
+func TestPushMetricsSplitsIntoBatches(t *testing.T) {
+	names := []string{"s0", "s1", "s2", "s3"}
+
+	tests := []struct {
+		name      string
+		maxSize   int64
+		chunkSize int
+		down      bool
+		wantErr   error
+		delivered [][]string
+		pending   int
+	}{
+		{
+			name:      "one batch without a chunk size",
+			delivered: [][]string{names},
+		},
+		{
+			name:      "batches of the chunk size",
+			chunkSize: 2 * seriesSize,
+			delivered: [][]string{{"s0", "s1"}, {"s2", "s3"}},
+		},
+		{
+			name:      "push larger than the spool is delivered while written",
+			maxSize:   int64(2 * seriesSize),
+			chunkSize: seriesSize,
+			delivered: [][]string{{"s0"}, {"s1"}, {"s2"}, {"s3"}},
+		},
+		{
+			name:    "batch larger than the spool",
+			maxSize: int64(seriesSize),
+			wantErr: ErrSpoolFull,
+		},
+		{
+			name:      "batches stay on disk while the endpoint is down",
+			chunkSize: 3 * seriesSize,
+			down:      true,
+			wantErr:   ErrQueued,
+			pending:   2,
+		},
+		{
+			name:      "spool full while the endpoint is down",
+			maxSize:   int64(2 * seriesSize),
+			chunkSize: seriesSize,
+			down:      true,
+			wantErr:   ErrSpoolFull,
+			pending:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &fakeTarget{} //nolint:exhaustruct
+			if tt.down {
+				target.err = errDown
+			}
+
+			s := newTestSpool(t, target, "", tt.maxSize, tt.chunkSize)
+
+			err := s.PushMetrics(context.Background(), series(names...))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PushMetrics: got error %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.EqualFunc(target.delivered, tt.delivered, slices.Equal) {
+				t.Errorf("delivered %v, want %v", target.delivered, tt.delivered)
+			}
+
+			batches, err := s.Batches()
+			if err != nil {
+				t.Fatalf("Batches: %v", err)
+			}
+
+			if len(batches) != tt.pending {
+				t.Errorf("got %d pending batches, want %d", len(batches), tt.pending)
+			}
+
+			for _, batch := range batches {
+				if tt.chunkSize > 0 && batch.Size > int64(tt.chunkSize) {
+					t.Errorf("batch %d: %d bytes, want at most %d", batch.Seq, batch.Size, tt.chunkSize)
+				}
+			}
+		})
+	}
+}
+
+func TestPushMetricsQueuedWhileDown(t *testing.T) {
+	target := &fakeTarget{err: errDown} //nolint:exhaustruct
+	s := newTestSpool(t, target, "", 0, 0)
+
+	ctx := context.Background()
+
+	for _, name := range []string{"s0", "s1"} {
+		if err := s.PushMetrics(ctx, series(name)); !errors.Is(err, ErrQueued) || !errors.Is(err, errDown) {
+			t.Fatalf("PushMetrics(%s): got %v, want %v caused by %v", name, err, ErrQueued, errDown)
+		}
+	}
+
+	// The second push doesn't wait through the retries again.
+	if target.attempts != 1 {
+		t.Errorf("got %d delivery attempts, want 1", target.attempts)
+	}
+
+	target.err = nil
+
+	delivered, pending, err := s.Flush(ctx)
+	if err != nil || delivered != 2 || pending != 0 {
+		t.Fatalf("Flush() = %d, %d, %v; want 2, 0, nil", delivered, pending, err)
+	}
+
+	if want := [][]string{{"s0"}, {"s1"}}; !slices.EqualFunc(target.delivered, want, slices.Equal) {
+		t.Errorf("delivered %v, want %v", target.delivered, want)
+	}
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	before := newTestSpool(t, &fakeTarget{err: errDown}, dir, 0, 0) //nolint:exhaustruct
+
+	for _, name := range []string{"s0", "s1"} {
+		if err := before.PushMetrics(ctx, series(name)); !errors.Is(err, ErrQueued) {
+			t.Fatalf("PushMetrics(%s): %v", name, err)
+		}
+	}
+
+	// A batch being written when the process stopped.
+	partial := filepath.Join(dir, "00000000000000000003"+batchExt+tmpExt)
+	if err := os.WriteFile(partial, []byte(`{"metric":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	target := &fakeTarget{} //nolint:exhaustruct
+	after := newTestSpool(t, target, dir, 0, 0)
+
+	if _, err := os.Stat(partial); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial batch left behind: %v", err)
+	}
+
+	if err := after.PushMetrics(ctx, series("s2")); err != nil {
+		t.Fatalf("PushMetrics: %v", err)
+	}
+
+	if want := [][]string{{"s0"}, {"s1"}, {"s2"}}; !slices.EqualFunc(target.delivered, want, slices.Equal) {
+		t.Errorf("delivered %v, want %v", target.delivered, want)
+	}
+}
+
+func TestSpoolSetsAsideUndeliverableBatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		rejected []string
+		pushes   []string
+		want     [][]string
+		bad      int
+	}{
+		{
+			name:     "corrupted batch",
+			existing: `{"metric": {"__name__": "s0"`,
+			pushes:   []string{"s1"},
+			want:     [][]string{{"s1"}},
+			bad:      1,
+		},
+		{
+			name:     "batch rejected as invalid",
+			rejected: []string{"s0"},
+			pushes:   []string{"s0", "s1"},
+			want:     [][]string{{"s1"}},
+			bad:      1,
+		},
+		{
+			name:   "nothing to set aside",
+			pushes: []string{"s0", "s1"},
+			want:   [][]string{{"s0"}, {"s1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			if tt.existing != "" {
+				if err := os.WriteFile(filepath.Join(dir, "00000000000000000001"+batchExt), []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			target := &fakeTarget{rejected: tt.rejected} //nolint:exhaustruct
+			s := newTestSpool(t, target, dir, 0, 0)
+
+			for _, name := range tt.pushes {
+				if err := s.PushMetrics(context.Background(), series(name)); err != nil {
+					t.Fatalf("PushMetrics(%s): %v", name, err)
+				}
+			}
+
+			if !slices.EqualFunc(target.delivered, tt.want, slices.Equal) {
+				t.Errorf("delivered %v, want %v", target.delivered, tt.want)
+			}
+
+			bad, err := filepath.Glob(filepath.Join(dir, "*"+badExt))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(bad) != tt.bad {
+				t.Errorf("got %d batches set aside, want %d", len(bad), tt.bad)
+			}
+
+			if batches, err := s.Batches(); err != nil || len(batches) != 0 {
+				t.Errorf("Batches() = %v, %v; want none pending", batches, err)
+			}
+		})
+	}
+}
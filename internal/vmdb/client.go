@@ -0,0 +1,86 @@
+package vmdb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"metrics-scrapper/internal/config"
+	"net/http"
+	"os"
+	"time"
+)
+
+// NewHTTPClient builds the client for VictoriaMetrics requests with the
+// configured timeout, TLS settings and credentials.
+func NewHTTPClient(cfg config.VictoriaMetricsConfig) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{ //nolint:exhaustruct
+		Timeout: time.Duration(cfg.TimeoutMS) * time.Millisecond,
+		Transport: &authTransport{
+			base:        transport,
+			username:    cfg.Username,
+			password:    cfg.Password,
+			bearerToken: cfg.BearerToken,
+		},
+	}, nil
+}
+
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ //nolint:exhaustruct
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLoadingTLSConfig, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates in %s", ErrLoadingTLSConfig, cfg.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLoadingTLSConfig, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// authTransport adds basic auth or bearer token credentials to every request.
+type authTransport struct {
+	base        http.RoundTripper
+	username    string
+	password    string
+	bearerToken string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case t.bearerToken != "":
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.bearerToken)
+	case t.username != "":
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,57 @@
+package vmdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// dryRunExporter writes the import payload to Out instead of sending it to
+// VictoriaMetrics. Together with replayed GitHub fixtures it allows fully
+// offline runs.
+type dryRunExporter struct {
+	Out io.Writer
+}
+
+func NewDryRunExporter(out io.Writer) *dryRunExporter {
+	return &dryRunExporter{
+		Out: out,
+	}
+}
+
+func (d *dryRunExporter) PushMetrics(_ context.Context, metrics *Metrics) error {
+	exported, err := metrics.ExportToJSON()
+	if err != nil {
+		return err
+	}
+
+	_, err = d.Out.Write(exported)
+	if err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+
+	return nil
+}
+
+func (d *dryRunExporter) PushExecTimestamp(ctx context.Context, t time.Time) error {
+	metrics := Metrics{} //nolint:exhaustruct
+
+	metrics.AddExecTimeMetric(
+		uint64(t.UnixMilli()),
+		uint64(t.UnixMilli()),
+	)
+
+	return d.PushMetrics(ctx, &metrics)
+}
+
+// GetLastExecTimestamp always reports no previous execution, so dry runs
+// scrape the full history unless --scrape-threshold is set.
+func (d *dryRunExporter) GetLastExecTimestamp(context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// VerifyMetrics has nothing to read back from.
+func (d *dryRunExporter) VerifyMetrics(context.Context, *Metrics) error {
+	return nil
+}
@@ -9,4 +9,9 @@ var (
 	ErrUnmarshalingRequestBody      = errors.New("unmarshaling request body")
 	ErrFailedConvertExecTimestamp   = errors.New("converting last exec timestamp to string failed")
 	ErrParsingVMURL                 = errors.New("parsing vm url")
+	ErrLoadingTLSConfig             = errors.New("loading tls config")
+	ErrUnsupportedValue             = errors.New("unsupported metric value type")
+	ErrVerificationFailed           = errors.New("pushed samples not found")
+	ErrChunksFailed                 = errors.New("import chunks failed")
+	ErrRejected                     = errors.New("rejected by the server")
 )
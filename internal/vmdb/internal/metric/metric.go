@@ -5,18 +5,35 @@ import (
 )
 
 type Metric struct {
-	Labels     any      `json:"metric"`
+	Labels     Labels   `json:"metric"`
 	Values     []any    `json:"values"`
 	Timestamps []uint64 `json:"timestamps"`
 }
 
-type PRMetricLabels struct {
-	Name string `json:"__name__"` //nolint:tagliatelle
-	Repo string `json:"repo"`
-}
+// Labels of a series, including its name under NameLabel.
+type Labels map[string]string
+
+const NameLabel = "__name__"
+
+// NewLabels returns extra with name and the given label name/value pairs set
+// on top of it. Pairs with an empty value are left out, which is how
+// cardinality controls drop labels.
+func NewLabels(name string, extra map[string]string, pairs ...string) Labels {
+	labels := make(Labels, len(extra)+1+len(pairs)/2)
+
+	for label, value := range extra {
+		labels[label] = value
+	}
+
+	labels[NameLabel] = name
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			labels[pairs[i]] = pairs[i+1]
+		}
+	}
 
-type ExecTimeMetricLabels struct {
-	Name string `json:"__name__"` //nolint:tagliatelle
+	return labels
 }
 
 func (m Metric) ToJSON() ([]byte, error) {
@@ -0,0 +1,54 @@
+package metric
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestNewLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra map[string]string
+		pairs []string
+		want  Labels
+	}{
+		{name: "name only", want: Labels{NameLabel: "pr_total"}},
+		{
+			name:  "extra labels and pairs",
+			extra: map[string]string{"team": "core"},
+			pairs: []string{"repo", "o/r", "pr", "42"},
+			want:  Labels{NameLabel: "pr_total", "team": "core", "repo": "o/r", "pr": "42"},
+		},
+		{
+			name:  "empty pair values are left out",
+			pairs: []string{"repo", "o/r", "author", ""},
+			want:  Labels{NameLabel: "pr_total", "repo": "o/r"},
+		},
+		{
+			name:  "pairs and the name override extra labels",
+			extra: map[string]string{NameLabel: "other", "repo": "x/y"},
+			pairs: []string{"repo", "o/r"},
+			want:  Labels{NameLabel: "pr_total", "repo": "o/r"},
+		},
+		{
+			name:  "dangling name is ignored",
+			pairs: []string{"repo", "o/r", "state"},
+			want:  Labels{NameLabel: "pr_total", "repo": "o/r"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extra := maps.Clone(tt.extra)
+
+			got := NewLabels("pr_total", tt.extra, tt.pairs...)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("NewLabels() = %v, want %v", got, tt.want)
+			}
+
+			if !maps.Equal(tt.extra, extra) {
+				t.Errorf("extra labels changed to %v", tt.extra)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package vmdb
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"iter"
 	"metrics-scrapper/internal/vmdb/internal/metric"
 )
 
@@ -10,25 +12,62 @@ type Metrics struct {
 	Data []metric.Metric
 }
 
+// AddPRMetric adds a repository-level sample. labels are extra labels of the
+// repository.
 func (m *Metrics) AddPRMetric(
 	name string,
 	repo string,
+	labels map[string]string,
 	value any,
 	timestamp uint64,
 ) {
 	m.Data = append(
 		m.Data,
 		metric.Metric{
-			Labels: metric.PRMetricLabels{
-				Name: name,
-				Repo: repo,
-			},
+			Labels:     metric.NewLabels(name, labels, repoLabel, repo),
 			Values:     []any{value},
 			Timestamps: []uint64{timestamp},
 		},
 	)
 }
 
+// PRSample is a single per-PR value. Empty PR, Author and State labels are
+// not exported. Labels are extra labels of the repository.
+type PRSample struct {
+	Name      string
+	Repo      string
+	PR        string
+	Author    string
+	State     string
+	Labels    map[string]string
+	Value     any
+	Timestamp uint64
+}
+
+func (m *Metrics) AddPRSample(sample PRSample) {
+	m.Data = append(
+		m.Data,
+		metric.Metric{
+			Labels: metric.NewLabels(sample.Name, sample.Labels,
+				repoLabel, sample.Repo,
+				prLabel, sample.PR,
+				authorLabel, sample.Author,
+				stateLabel, sample.State,
+			),
+			Values:     []any{sample.Value},
+			Timestamps: []uint64{sample.Timestamp},
+		},
+	)
+}
+
+// Labels set by the scraper. Extra labels never override them.
+const (
+	repoLabel   = "repo"
+	prLabel     = "pr"
+	authorLabel = "author"
+	stateLabel  = "state"
+)
+
 const execTimeMetricName = "scraper_exec_timestamp"
 
 func (m *Metrics) AddExecTimeMetric(
@@ -38,9 +77,7 @@ func (m *Metrics) AddExecTimeMetric(
 	m.Data = append(
 		m.Data,
 		metric.Metric{
-			Labels: metric.ExecTimeMetricLabels{
-				Name: execTimeMetricName,
-			},
+			Labels:     metric.NewLabels(execTimeMetricName, nil),
 			Values:     []any{value},
 			Timestamps: []uint64{timestamp},
 		},
@@ -69,3 +106,71 @@ func (m *Metrics) ExportToJSON() ([]byte, error) {
 
 	return exported.Bytes(), nil
 }
+
+// JSONChunks encodes the metrics like ExportToJSON, but yields them in chunks
+// of at most maxBytes, split between lines, so the whole payload is never
+// held in memory. A single line larger than maxBytes makes a chunk of its
+// own. A maxBytes of zero yields everything as one chunk.
+func (m *Metrics) JSONChunks(maxBytes int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		var chunk []byte
+
+		for _, metric := range m.Data {
+			formatted, err := metric.ToJSON()
+			if err != nil {
+				yield(nil, fmt.Errorf("marshalling metric: %w", err))
+				return
+			}
+
+			if maxBytes > 0 && len(chunk) > 0 && len(chunk)+len(formatted)+1 > maxBytes {
+				if !yield(chunk, nil) {
+					return
+				}
+
+				chunk = nil
+			}
+
+			chunk = append(chunk, formatted...)
+			chunk = append(chunk, '\n')
+		}
+
+		if len(chunk) > 0 {
+			yield(chunk, nil)
+		}
+	}
+}
+
+// ParseJSON reads metrics in the import format produced by ExportToJSON.
+func ParseJSON(data []byte) (*Metrics, error) {
+	metrics := &Metrics{} //nolint:exhaustruct
+
+	for line := range bytes.Lines(data) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var parsed struct {
+			Labels     metric.Labels `json:"metric"`
+			Values     []float64     `json:"values"`
+			Timestamps []uint64      `json:"timestamps"`
+		}
+
+		if err := json.Unmarshal(line, &parsed); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnmarshalingRequestBody, err)
+		}
+
+		values := make([]any, len(parsed.Values))
+		for i, value := range parsed.Values {
+			values[i] = value
+		}
+
+		metrics.Data = append(metrics.Data, metric.Metric{
+			Labels:     parsed.Labels,
+			Values:     values,
+			Timestamps: parsed.Timestamps,
+		})
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,171 @@
+package vmdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"metrics-scrapper/internal/vmdb/internal/metric"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"golang.org/x/exp/slog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteExporter pushes metrics with the Prometheus remote-write protocol
+// (snappy-compressed protobuf WriteRequest), accepted by Prometheus, Mimir,
+// Thanos receive, vmagent and VictoriaMetrics itself. The last execution
+// timestamp is read through the Prometheus query API of the embedded
+// exporter.
+type remoteWriteExporter struct {
+	*vmdbExporter
+
+	WriteURL string
+}
+
+func NewRemoteWriteExporter(
+	client Client,
+	urlProvider URLProvider,
+	writeURL string,
+	logger *slog.Logger,
+	lastExecTimestampSearchRange string,
+) *remoteWriteExporter {
+	return &remoteWriteExporter{
+		vmdbExporter: NewVMDBExporter(client, urlProvider, logger, lastExecTimestampSearchRange, ImportOptions{}),
+		WriteURL:     writeURL,
+	}
+}
+
+func (e *remoteWriteExporter) PushMetrics(ctx context.Context, metrics *Metrics) error {
+	payload, err := metrics.ExportToRemoteWrite()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.WriteURL, bytes.NewReader(snappy.Encode(nil, payload)))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSendingRequest, err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+		return pushStatusError(resp.StatusCode, " "+string(bytes.TrimSpace(body)))
+	}
+
+	return nil
+}
+
+func (e *remoteWriteExporter) PushExecTimestamp(ctx context.Context, t time.Time) error {
+	metrics := Metrics{} //nolint:exhaustruct
+
+	metrics.AddExecTimeMetric(
+		uint64(t.UnixMilli()),
+		uint64(t.UnixMilli()),
+	)
+
+	return e.PushMetrics(ctx, &metrics)
+}
+
+// Field numbers of the prometheus.WriteRequest message family.
+const (
+	writeRequestTimeseries = 1
+
+	timeSeriesLabels  = 1
+	timeSeriesSamples = 2
+
+	labelName  = 1
+	labelValue = 2
+
+	sampleValue     = 1
+	sampleTimestamp = 2
+)
+
+// ExportToRemoteWrite encodes the metrics as an uncompressed remote-write
+// WriteRequest. Labels are sorted by name as the protocol requires.
+func (m *Metrics) ExportToRemoteWrite() ([]byte, error) {
+	var request []byte
+
+	for _, metric := range m.Data {
+		labels := labelPairs(metric.Labels)
+
+		var series []byte
+
+		for _, label := range labels {
+			var pair []byte
+			pair = protowire.AppendTag(pair, labelName, protowire.BytesType)
+			pair = protowire.AppendString(pair, label[0])
+			pair = protowire.AppendTag(pair, labelValue, protowire.BytesType)
+			pair = protowire.AppendString(pair, label[1])
+
+			series = protowire.AppendTag(series, timeSeriesLabels, protowire.BytesType)
+			series = protowire.AppendBytes(series, pair)
+		}
+
+		for i, value := range metric.Values {
+			v, err := toFloat(value)
+			if err != nil {
+				return nil, err
+			}
+
+			var sample []byte
+			sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(v))
+			sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, metric.Timestamps[i])
+
+			series = protowire.AppendTag(series, timeSeriesSamples, protowire.BytesType)
+			series = protowire.AppendBytes(series, sample)
+		}
+
+		request = protowire.AppendTag(request, writeRequestTimeseries, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+
+	return request, nil
+}
+
+// labelPairs returns the non-empty labels sorted by name.
+func labelPairs(labels metric.Labels) [][2]string {
+	pairs := make([][2]string, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			pairs = append(pairs, [2]string{name, value})
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	return pairs
+}
+
+func toFloat(value any) (float64, error) {
+	v := reflect.ValueOf(value)
+
+	switch {
+	case v.CanFloat():
+		return v.Float(), nil
+	case v.CanInt():
+		return float64(v.Int()), nil
+	case v.CanUint():
+		return float64(v.Uint()), nil
+	}
+
+	return 0, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+}
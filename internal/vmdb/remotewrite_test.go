@@ -0,0 +1,279 @@
+package vmdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// series is a decoded remote-write TimeSeries.
+type series struct {
+	labels  [][2]string
+	samples []sample
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+// remoteWriteReceiver is a remote-write endpoint keeping the series of the
+// last request.
+type remoteWriteReceiver struct {
+	t      *testing.T
+	series []series
+}
+
+func (rw *remoteWriteReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for header, want := range map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	} {
+		if got := r.Header.Get(header); got != want {
+			rw.t.Errorf("%s: got %q, want %q", header, got, want)
+		}
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rw.series, err = decodeWriteRequest(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeWriteRequest decodes the fields of a prometheus.WriteRequest the
+// exporter sets.
+func decodeWriteRequest(b []byte) ([]series, error) {
+	var result []series
+
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != writeRequestTimeseries || typ != protowire.BytesType {
+			return fmt.Errorf("unexpected WriteRequest field %d", num)
+		}
+
+		var s series
+
+		err := eachField(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+			switch num {
+			case timeSeriesLabels:
+				var label [2]string
+
+				err := eachField(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+					label[num-labelName] = string(v)
+					return nil
+				})
+				s.labels = append(s.labels, label)
+
+				return err
+			case timeSeriesSamples:
+				var smp sample
+
+				err := eachField(v, func(num protowire.Number, _ protowire.Type, _ []byte, n uint64) error {
+					switch num {
+					case sampleValue:
+						smp.value = math.Float64frombits(n)
+					case sampleTimestamp:
+						smp.timestamp = int64(n)
+					}
+
+					return nil
+				})
+				s.samples = append(s.samples, smp)
+
+				return err
+			}
+
+			return fmt.Errorf("unexpected TimeSeries field %d", num)
+		})
+		result = append(result, s)
+
+		return err
+	})
+
+	return result, err
+}
+
+// eachField calls fn with every field of the message b: v for length-delimited
+// fields, n for varint and fixed64 ones.
+func eachField(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, length := protowire.ConsumeTag(b)
+		if length < 0 {
+			return protowire.ParseError(length)
+		}
+
+		b = b[length:]
+
+		var (
+			v []byte
+			n uint64
+		)
+
+		switch typ {
+		case protowire.BytesType:
+			v, length = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			n, length = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			n, length = protowire.ConsumeFixed64(b)
+		default:
+			return fmt.Errorf("unexpected wire type %d", typ)
+		}
+
+		if length < 0 {
+			return protowire.ParseError(length)
+		}
+
+		b = b[length:]
+
+		if err := fn(num, typ, v, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestRemoteWritePushMetrics(t *testing.T) {
+	tests := []struct {
+		name string
+		add  func(m *Metrics)
+		want []series
+	}{
+		{
+			name: "repository metric with sorted extra labels",
+			add: func(m *Metrics) {
+				m.AddPRMetric("MergeSuccessRate", "o/r", map[string]string{"team": "core", "env": "prod"}, 0.75, 1700000000000)
+			},
+			want: []series{{
+				labels:  [][2]string{{"__name__", "MergeSuccessRate"}, {"env", "prod"}, {"repo", "o/r"}, {"team", "core"}},
+				samples: []sample{{0.75, 1700000000000}},
+			}},
+		},
+		{
+			name: "per-PR sample drops empty labels and converts integers",
+			add: func(m *Metrics) {
+				m.AddPRSample(PRSample{
+					Name: "pr_comments", Repo: "o/r", PR: "42", Author: "alice", State: "",
+					Labels: nil, Value: 3, Timestamp: 1700000001000,
+				})
+			},
+			want: []series{{
+				labels:  [][2]string{{"__name__", "pr_comments"}, {"author", "alice"}, {"pr", "42"}, {"repo", "o/r"}},
+				samples: []sample{{3, 1700000001000}},
+			}},
+		},
+		{
+			name: "execution timestamp and several series in order",
+			add: func(m *Metrics) {
+				m.AddPRMetric("TotalPRs", "o/r", nil, uint64(8), 1700000002000)
+				m.AddExecTimeMetric(uint64(1700000003000), uint64(1700000003000))
+			},
+			want: []series{
+				{
+					labels:  [][2]string{{"__name__", "TotalPRs"}, {"repo", "o/r"}},
+					samples: []sample{{8, 1700000002000}},
+				},
+				{
+					labels:  [][2]string{{"__name__", "scraper_exec_timestamp"}},
+					samples: []sample{{1700000003000, 1700000003000}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &remoteWriteReceiver{t: t} //nolint:exhaustruct
+
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			exporter := NewRemoteWriteExporter(server.Client(), nil, server.URL+"/api/v1/write", nil, "1y")
+
+			metrics := &Metrics{} //nolint:exhaustruct
+			tt.add(metrics)
+
+			if err := exporter.PushMetrics(context.Background(), metrics); err != nil {
+				t.Fatalf("PushMetrics: %v", err)
+			}
+
+			if len(receiver.series) != len(tt.want) {
+				t.Fatalf("got %d series, want %d", len(receiver.series), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got := receiver.series[i]
+
+				if !slices.Equal(got.labels, want.labels) {
+					t.Errorf("series %d: got labels %v, want %v", i, got.labels, want.labels)
+				}
+
+				if !slices.Equal(got.samples, want.samples) {
+					t.Errorf("series %d: got samples %v, want %v", i, got.samples, want.samples)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoteWriteRejectedPush(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		rejected bool
+	}{
+		{name: "invalid data", status: http.StatusBadRequest, rejected: true},
+		{name: "payload too large", status: http.StatusRequestEntityTooLarge, rejected: true},
+		{name: "bad credentials", status: http.StatusUnauthorized, rejected: false},
+		{name: "rate limited", status: http.StatusTooManyRequests, rejected: false},
+		{name: "server error", status: http.StatusServiceUnavailable, rejected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "out of order sample", tt.status)
+			}))
+			defer server.Close()
+
+			exporter := NewRemoteWriteExporter(server.Client(), nil, server.URL, nil, "1y")
+
+			metrics := &Metrics{} //nolint:exhaustruct
+			metrics.AddPRMetric("TotalPRs", "o/r", nil, 1, 1700000000000)
+
+			err := exporter.PushMetrics(context.Background(), metrics)
+			if !errors.Is(err, ErrUnexpectedResponseStatusCode) {
+				t.Fatalf("got %v, want %v", err, ErrUnexpectedResponseStatusCode)
+			}
+
+			if rejected := errors.Is(err, ErrRejected); rejected != tt.rejected {
+				t.Errorf("%v: rejected %t, want %t", err, rejected, tt.rejected)
+			}
+		})
+	}
+}
@@ -28,9 +28,7 @@ type result struct {
 	Value  []interface{} `json:"value"`
 }
 
-func (m *vmdbExporter) GetLastExecTimestamp() (time.Time, error) {
-	ctx := context.TODO()
-
+func (m *vmdbExporter) GetLastExecTimestamp(ctx context.Context) (time.Time, error) {
 	urlGetLastExec, err := m.getLastExecTimestampURL()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("creating url: %w", err)
@@ -67,7 +65,7 @@ func (m *vmdbExporter) GetLastExecTimestamp() (time.Time, error) {
 	return extractLastExecTimestamp(response.Data.Result)
 }
 
-func (m *vmdbExporter) PushExecTimestamp(t time.Time) error {
+func (m *vmdbExporter) PushExecTimestamp(ctx context.Context, t time.Time) error {
 	metrics := Metrics{} //nolint:exhaustruct
 
 	metrics.AddExecTimeMetric(
@@ -75,8 +73,7 @@ func (m *vmdbExporter) PushExecTimestamp(t time.Time) error {
 		uint64(t.UnixMilli()),
 	)
 
-	return nil
-	//return m.PushMetrics(&metrics)
+	return m.PushMetrics(ctx, &metrics)
 }
 
 func (m *vmdbExporter) getLastExecTimestampURL() (string, error) {
@@ -1,13 +1,26 @@
 package vmdb
 
+import (
+	"fmt"
+	"metrics-scrapper/internal/config"
+)
+
 const (
-	clusterQueryPath    = "/select/0/prometheus/api/v1/query"
-	singleNodeQueryPath = "/api/v1/query"
+	singleNodeQueryPath       = "/api/v1/query"
+	singleNodeImportPath      = "/api/v1/import"
+	singleNodeRemoteWritePath = "/api/v1/write"
+	singleNodeExportPath      = "/api/v1/export"
 )
 
 type URLProvider interface {
+	// Get returns the instant query URL.
 	Get() string
+	// Post returns the JSON lines import URL.
 	Post() string
+	// RemoteWrite returns the Prometheus remote-write URL.
+	RemoteWrite() string
+	// Export returns the raw samples export URL.
+	Export() string
 }
 
 type SingleNodeURL struct {
@@ -19,18 +32,52 @@ func (s *SingleNodeURL) Get() string {
 }
 
 func (s *SingleNodeURL) Post() string {
-	return s.VMURL
+	return s.VMURL + singleNodeImportPath
+}
+
+func (s *SingleNodeURL) RemoteWrite() string {
+	return s.VMURL + singleNodeRemoteWritePath
 }
 
+func (s *SingleNodeURL) Export() string {
+	return s.VMURL + singleNodeExportPath
+}
+
+// ClusterURL addresses a VictoriaMetrics cluster. Tenant is "accountID" or
+// "accountID:projectID".
 type ClusterURL struct {
-	VMAgentURL  string
+	VMInsertURL string
 	VMSelectURL string
+	Tenant      string
 }
 
 func (s *ClusterURL) Get() string {
-	return s.VMSelectURL + clusterQueryPath
+	return fmt.Sprintf("%s/select/%s/prometheus/api/v1/query", s.VMSelectURL, s.Tenant)
 }
 
 func (s *ClusterURL) Post() string {
-	return s.VMAgentURL
+	return fmt.Sprintf("%s/insert/%s/prometheus/api/v1/import", s.VMInsertURL, s.Tenant)
+}
+
+func (s *ClusterURL) RemoteWrite() string {
+	return fmt.Sprintf("%s/insert/%s/prometheus/api/v1/write", s.VMInsertURL, s.Tenant)
+}
+
+func (s *ClusterURL) Export() string {
+	return fmt.Sprintf("%s/select/%s/prometheus/api/v1/export", s.VMSelectURL, s.Tenant)
+}
+
+// NewURLProvider returns the URL provider for the configured mode.
+func NewURLProvider(cfg config.VictoriaMetricsConfig) URLProvider {
+	if cfg.Mode == config.VMModeCluster {
+		return &ClusterURL{
+			VMInsertURL: cfg.InsertURL,
+			VMSelectURL: cfg.SelectURL,
+			Tenant:      cfg.Tenant,
+		}
+	}
+
+	return &SingleNodeURL{
+		VMURL: cfg.URL,
+	}
 }
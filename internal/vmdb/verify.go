@@ -0,0 +1,227 @@
+package vmdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"metrics-scrapper/internal/vmdb/internal/metric"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freshly pushed samples become visible to the query side after a short
+// delay, so verification is retried before it fails.
+const (
+	verifyAttempts = 5
+	verifyDelay    = time.Second
+)
+
+// verifyExamples is how many mismatching samples are listed in the error.
+const verifyExamples = 3
+
+// exportedSeries is a line of the /api/v1/export response.
+type exportedSeries struct {
+	Metric     metric.Labels `json:"metric"`
+	Values     []float64     `json:"values"`
+	Timestamps []int64       `json:"timestamps"`
+}
+
+// VerifyMetrics reads metrics back through the export API and checks that
+// every sample is stored with the pushed value.
+func (m *vmdbExporter) VerifyMetrics(ctx context.Context, metrics *Metrics) error {
+	expected, err := expectedSamples(metrics)
+	if err != nil {
+		return err
+	}
+
+	if len(expected) == 0 {
+		return nil
+	}
+
+	var mismatches []string
+
+	for attempt := range verifyAttempts {
+		if attempt > 0 {
+			if err := sleep(ctx, verifyDelay); err != nil {
+				return err
+			}
+		}
+
+		stored, err := m.exportSamples(ctx, metrics)
+		if err != nil {
+			return err
+		}
+
+		mismatches = mismatches[:0]
+
+		for key, value := range expected {
+			if got, ok := stored[key]; !ok || got != value {
+				mismatches = append(mismatches, key)
+			}
+		}
+
+		if len(mismatches) == 0 {
+			return nil
+		}
+	}
+
+	sort.Strings(mismatches)
+
+	return fmt.Errorf("%w: %d of %d samples missing or different, e.g. %s",
+		ErrVerificationFailed, len(mismatches), len(expected),
+		strings.Join(mismatches[:min(len(mismatches), verifyExamples)], ", "))
+}
+
+// exportSamples fetches every sample of the series in metrics within their
+// time range.
+func (m *vmdbExporter) exportSamples(ctx context.Context, metrics *Metrics) (map[string]float64, error) {
+	exportURL, err := url.Parse(m.URLProvider.Export())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParsingVMURL, err)
+	}
+
+	selectors, start, end := exportRange(metrics)
+
+	query := exportURL.Query()
+	for _, selector := range selectors {
+		query.Add("match[]", selector)
+	}
+
+	query.Set("start", strconv.FormatInt(start/1000, 10))
+	query.Set("end", strconv.FormatInt(end/1000+1, 10))
+
+	exportURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, exportURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSendingRequest, err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%d, %w", resp.StatusCode, ErrUnexpectedResponseStatusCode)
+	}
+
+	stored := make(map[string]float64)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 64<<20)
+
+	for scanner.Scan() {
+		var series exportedSeries
+		if err := json.Unmarshal(scanner.Bytes(), &series); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnmarshalingRequestBody, err)
+		}
+
+		pairs := labelPairs(series.Metric)
+
+		for i, timestamp := range series.Timestamps {
+			stored[sampleKey(pairs, timestamp)] = series.Values[i]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadingRequestBody, err)
+	}
+
+	return stored, nil
+}
+
+// expectedSamples indexes the samples of metrics by series and timestamp.
+func expectedSamples(metrics *Metrics) (map[string]float64, error) {
+	expected := make(map[string]float64)
+
+	for _, metric := range metrics.Data {
+		labels := labelPairs(metric.Labels)
+
+		for i, value := range metric.Values {
+			v, err := toFloat(value)
+			if err != nil {
+				return nil, err
+			}
+
+			expected[sampleKey(labels, int64(metric.Timestamps[i]))] = v
+		}
+	}
+
+	return expected, nil
+}
+
+// exportRange returns one selector per metric name and repository in
+// metrics and the time range covering all their samples, in milliseconds.
+func exportRange(metrics *Metrics) ([]string, int64, int64) {
+	var (
+		selectors  []string
+		seen       = make(map[string]bool)
+		start, end int64
+	)
+
+	for _, metric := range metrics.Data {
+		labels := labelPairs(metric.Labels)
+
+		var matchers []string
+
+		for _, pair := range labels {
+			if pair[0] == "__name__" || pair[0] == "repo" {
+				matchers = append(matchers, pair[0]+"="+strconv.Quote(pair[1]))
+			}
+		}
+
+		if selector := "{" + strings.Join(matchers, ",") + "}"; !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+
+		for _, timestamp := range metric.Timestamps {
+			if ts := int64(timestamp); start == 0 || ts < start {
+				start = ts
+			}
+
+			end = max(end, int64(timestamp))
+		}
+	}
+
+	return selectors, start, end
+}
+
+func sampleKey(labels [][2]string, timestamp int64) string {
+	var key strings.Builder
+
+	key.WriteByte('{')
+
+	for i, pair := range labels {
+		if i > 0 {
+			key.WriteByte(',')
+		}
+
+		key.WriteString(pair[0] + "=" + strconv.Quote(pair[1]))
+	}
+
+	key.WriteString("}@" + strconv.FormatInt(timestamp, 10))
+
+	return key.String()
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package vmdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestExportRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		add       func(m *Metrics)
+		selectors []string
+		start     int64
+		end       int64
+	}{
+		{
+			name:      "no samples",
+			add:       func(*Metrics) {},
+			selectors: nil,
+		},
+		{
+			name: "one selector per name and repository",
+			add: func(m *Metrics) {
+				m.AddPRMetric("pr_total", "o/a", map[string]string{"team": "core"}, 1, 2000)
+				m.AddPRMetric("pr_total", "o/a", map[string]string{"team": "web"}, 1, 2000)
+				m.AddPRMetric("pr_total", "o/b", nil, 1, 2000)
+				m.AddPRMetric("pr_merged_total", "o/a", nil, 1, 2000)
+			},
+			selectors: []string{
+				`{__name__="pr_total",repo="o/a"}`,
+				`{__name__="pr_total",repo="o/b"}`,
+				`{__name__="pr_merged_total",repo="o/a"}`,
+			},
+			start: 2000,
+			end:   2000,
+		},
+		{
+			name: "range covers every sample",
+			add: func(m *Metrics) {
+				m.AddPRMetric("pr_total", "o/a", nil, 1, 5000)
+				m.AddPRMetric("pr_total", "o/b", nil, 1, 3000)
+				m.AddPRMetric("pr_total", "o/c", nil, 1, 9000)
+			},
+			selectors: []string{
+				`{__name__="pr_total",repo="o/a"}`,
+				`{__name__="pr_total",repo="o/b"}`,
+				`{__name__="pr_total",repo="o/c"}`,
+			},
+			start: 3000,
+			end:   9000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &Metrics{} //nolint:exhaustruct
+			tt.add(metrics)
+
+			selectors, start, end := exportRange(metrics)
+
+			if !slices.Equal(selectors, tt.selectors) {
+				t.Errorf("got selectors %v, want %v", selectors, tt.selectors)
+			}
+
+			if start != tt.start || end != tt.end {
+				t.Errorf("got range [%d, %d], want [%d, %d]", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestVerifyMetrics(t *testing.T) {
+	var query map[string][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/export" {
+			http.NotFound(w, r)
+			return
+		}
+
+		query = r.URL.Query()
+
+		// Series come back with the labels in any order, values as floats
+		// and samples outside the pushed ones.
+		fmt.Fprintln(w, `{"metric":{"repo":"o/a","__name__":"pr_total"},"values":[7,8],"timestamps":[1000,2000]}`)
+		fmt.Fprintln(w, `{"metric":{"__name__":"MergeSuccessRate","repo":"o/a"},"values":[0.5],"timestamps":[2000]}`)
+	}))
+	defer server.Close()
+
+	exporter := NewVMDBExporter(server.Client(), &SingleNodeURL{VMURL: server.URL}, nil, "1y", ImportOptions{}) //nolint:exhaustruct
+
+	metrics := &Metrics{} //nolint:exhaustruct
+	metrics.AddPRMetric("pr_total", "o/a", nil, uint64(8), 2000)
+	metrics.AddPRMetric("MergeSuccessRate", "o/a", nil, 0.5, 2000)
+
+	if err := exporter.VerifyMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("VerifyMetrics: %v", err)
+	}
+
+	wantSelectors := []string{`{__name__="pr_total",repo="o/a"}`, `{__name__="MergeSuccessRate",repo="o/a"}`}
+	if got := query["match[]"]; !slices.Equal(got, wantSelectors) {
+		t.Errorf("got match[] %v, want %v", got, wantSelectors)
+	}
+
+	if start, end := query["start"], query["end"]; !slices.Equal(start, []string{"2"}) || !slices.Equal(end, []string{"3"}) {
+		t.Errorf("got range [%v, %v], want [2, 3]", start, end)
+	}
+}
@@ -2,25 +2,36 @@ package vmdb
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"sync"
 
 	"golang.org/x/exp/slog"
 )
 
-const vmImportPath = "/api/v1/import"
-
 type Client interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// ImportOptions controls how PushMetrics splits and sends a payload. A zero
+// ChunkSize sends everything in one request.
+type ImportOptions struct {
+	ChunkSize   int
+	Gzip        bool
+	Concurrency int
+}
+
 type vmdbExporter struct {
 	Client                       Client
 	URLProvider                  URLProvider
 	Logger                       *slog.Logger
 	LastExecTimestampSearchRange string
+	Import                       ImportOptions
 }
 
 func NewVMDBExporter(
@@ -28,40 +39,139 @@ func NewVMDBExporter(
 	urlProvider URLProvider,
 	logger *slog.Logger,
 	lastExecTimestampSearchRange string,
+	importOptions ImportOptions,
 ) *vmdbExporter {
 	return &vmdbExporter{
 		Client:                       client,
 		URLProvider:                  urlProvider,
 		Logger:                       logger,
 		LastExecTimestampSearchRange: lastExecTimestampSearchRange,
+		Import:                       importOptions,
 	}
 }
 
-func (m *vmdbExporter) PushMetrics(metrics *Metrics) error {
+// ChunkError is a chunk of a push that was not imported.
+type ChunkError struct {
+	Chunk  int
+	Series int
+	Bytes  int
+	Err    error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (%d series, %d bytes): %v", e.Chunk, e.Series, e.Bytes, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// PushMetrics encodes metrics chunk by chunk while earlier chunks are being
+// uploaded, at most Import.Concurrency at a time. Every chunk is sent even if
+// some fail; the error then lists the failed chunks as ChunkErrors.
+func (m *vmdbExporter) PushMetrics(ctx context.Context, metrics *Metrics) error {
+	vmImportEndpoint, err := url.Parse(m.URLProvider.Post())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrParsingVMURL, err)
+	}
+
+	type chunk struct {
+		index int
+		data  []byte
+	}
+
 	var (
-		vmImportEndpoint *url.URL
-		err              error
+		chunks = make(chan chunk)
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []*ChunkError
 	)
 
-	exported, err := metrics.ExportToJSON()
-	if err != nil {
-		return err
+	for range max(1, m.Import.Concurrency) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for c := range chunks {
+				if err := m.postChunk(ctx, vmImportEndpoint, c.data); err != nil {
+					mu.Lock()
+					failed = append(failed, &ChunkError{
+						Chunk:  c.index,
+						Series: bytes.Count(c.data, []byte{'\n'}),
+						Bytes:  len(c.data),
+						Err:    err,
+					})
+					mu.Unlock()
+				}
+			}
+		}()
 	}
 
-	ctx := context.TODO()
+	var (
+		total     int
+		encodeErr error
+	)
 
-	vmImportEndpoint, err = url.Parse(m.URLProvider.Post())
-	if err != nil {
-		return fmt.Errorf("%w: %w", ErrParsingVMURL, err)
+	for data, err := range metrics.JSONChunks(m.Import.ChunkSize) {
+		if err != nil {
+			encodeErr = err
+			break
+		}
+
+		chunks <- chunk{index: total, data: data}
+		total++
 	}
 
-	vmImportEndpoint.Path = vmImportPath
+	close(chunks)
+	wg.Wait()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, vmImportEndpoint.String(), bytes.NewReader(exported))
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Chunk < failed[j].Chunk })
+
+		errs := make([]error, len(failed))
+		for i, chunkErr := range failed {
+			errs[i] = chunkErr
+		}
+
+		return fmt.Errorf("%w: %d of %d: %w", ErrChunksFailed, len(failed), total, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (m *vmdbExporter) postChunk(ctx context.Context, endpoint *url.URL, data []byte) error {
+	body := data
+
+	if m.Import.Gzip {
+		var compressed bytes.Buffer
+
+		zw := gzip.NewWriter(&compressed)
+
+		if _, err := zw.Write(data); err != nil {
+			return fmt.Errorf("compressing chunk: %w", err)
+		}
+
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("compressing chunk: %w", err)
+		}
+
+		body = compressed.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
 
+	if m.Import.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
 	resp, err := m.Client.Do(req)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrSendingRequest, err)
@@ -70,8 +180,22 @@ func (m *vmdbExporter) PushMetrics(metrics *Metrics) error {
 	defer resp.Body.Close() //nolint:errcheck
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("%d, %w", resp.StatusCode, ErrUnexpectedResponseStatusCode)
+		return pushStatusError(resp.StatusCode, "")
 	}
 
 	return nil
 }
+
+// pushStatusError reports an unexpected response status of a push. A client
+// error about the data itself is also ErrRejected, as the same data would be
+// rejected again. Other client errors, such as bad credentials, a wrong URL or
+// rate limiting, are about the request and may pass later.
+func pushStatusError(code int, detail string) error {
+	switch code {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return fmt.Errorf("%d%s, %w: %w", code, detail, ErrUnexpectedResponseStatusCode, ErrRejected)
+	default:
+		return fmt.Errorf("%d%s, %w", code, detail, ErrUnexpectedResponseStatusCode)
+	}
+}